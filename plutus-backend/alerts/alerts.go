@@ -0,0 +1,257 @@
+// Package alerts lets signed-in users ask to be told when a product gets
+// cheaper or comes back in stock. Alerts are stored against the product's
+// link, like price history, so they survive a re-seed renumbering the
+// products. An Evaluator checks them after each catalog change and sends a
+// notification for every alert whose condition is met; each alert fires
+// once.
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"plutus-backend/catalog"
+	"plutus-backend/currency"
+	"plutus-backend/graph/model"
+)
+
+// Kind is what an alert waits for. The alerts table (migration 0009_alerts)
+// checks for the kinds below.
+type Kind string
+
+const (
+	// PriceDrop fires when the price falls to TargetPrice, or without a
+	// target, below the price when the alert was created.
+	PriceDrop Kind = "price_drop"
+	// BackInStock fires when a sold-out product, or size, can be bought
+	// again.
+	BackInStock Kind = "back_in_stock"
+)
+
+var (
+	// ErrNotFound is returned for a product or alert that doesn't exist.
+	ErrNotFound = errors.New("not found")
+	// ErrNoStockStatus is returned for a back-in-stock alert on a category
+	// whose products have no stock status.
+	ErrNoStockStatus = errors.New("stock isn't tracked for this product")
+	// ErrAlreadyMet is returned for an alert that would fire straight away.
+	ErrAlreadyMet = errors.New("the alert's condition is already met")
+)
+
+// Alert is one user's alert on one product.
+type Alert struct {
+	ID          int
+	UserID      int
+	Kind        Kind
+	Category    string
+	ProductLink string
+	// Size is empty for an alert on the product as a whole.
+	Size string
+	// TargetPrice is in Currency, which is also the currency prices are
+	// shown in when the alert fires.
+	TargetPrice *float64
+	Currency    string
+	// ReferencePrice is the price when the alert was created, in the
+	// category's currency.
+	ReferencePrice *float64
+	CreatedAt      time.Time
+	TriggeredAt    *time.Time
+
+	// ProductID and Product are the product currently listed under
+	// ProductLink; they are empty while there is none.
+	ProductID string
+	Product   model.Product
+}
+
+// Params describes a new alert.
+type Params struct {
+	Kind      Kind
+	Category  string
+	ProductID string
+	Size      string
+	// TargetPrice, in Currency, is optional for PriceDrop alerts and not
+	// allowed on BackInStock ones.
+	TargetPrice *float64
+	Currency    string
+}
+
+const columns = "a.id, a.user_id, a.kind, a.category, a.product_link, a.size, a.target_price, a.currency, a.reference_price, a.created_at, a.triggered_at"
+
+func scanAlert(s interface{ Scan(...interface{}) error }, extra ...interface{}) (*Alert, error) {
+	var a Alert
+	var targetPrice, referencePrice sql.NullFloat64
+	var triggeredAt sql.NullTime
+	dest := append([]interface{}{&a.ID, &a.UserID, &a.Kind, &a.Category, &a.ProductLink, &a.Size,
+		&targetPrice, &a.Currency, &referencePrice, &a.CreatedAt, &triggeredAt}, extra...)
+	if err := s.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if targetPrice.Valid {
+		a.TargetPrice = &targetPrice.Float64
+	}
+	if referencePrice.Valid {
+		a.ReferencePrice = &referencePrice.Float64
+	}
+	if triggeredAt.Valid {
+		a.TriggeredAt = &triggeredAt.Time
+	}
+	return &a, nil
+}
+
+// Create stores an alert for userID, or updates the user's pending alert of
+// the same kind on the same product and size. It refuses alerts that would
+// fire straight away.
+func Create(ctx context.Context, cat *catalog.Catalog, rates *currency.Rates, userID int, p Params) (*Alert, error) {
+	c, ok := catalog.Find(p.Category)
+	if !ok {
+		return nil, fmt.Errorf("unknown category %q", p.Category)
+	}
+	if p.Kind != PriceDrop && p.Kind != BackInStock {
+		return nil, fmt.Errorf("unknown alert kind %q", p.Kind)
+	}
+	code, err := currency.Normalize(p.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := strconv.Atoi(p.ProductID); err != nil {
+		return nil, fmt.Errorf("product %q: %w", p.ProductID, ErrNotFound)
+	}
+	found, err := cat.Products(ctx, c.Key, []string{p.ProductID})
+	if err != nil {
+		return nil, err
+	}
+	product := found[p.ProductID]
+	if product == nil {
+		return nil, fmt.Errorf("product %q: %w", p.ProductID, ErrNotFound)
+	}
+	productLink := catalog.ProductLink(product)
+	if productLink == "" {
+		return nil, fmt.Errorf("product %q has no link to follow it by", p.ProductID)
+	}
+	size := strings.TrimSpace(p.Size)
+
+	var reference *float64
+	switch p.Kind {
+	case PriceDrop:
+		current, ok := price(product, size)
+		if !ok {
+			if size != "" {
+				return nil, fmt.Errorf("product %q has no price for size %q", p.ProductID, size)
+			}
+			return nil, fmt.Errorf("product %q has no price", p.ProductID)
+		}
+		reference = &current
+		if p.TargetPrice != nil {
+			if !(*p.TargetPrice > 0) {
+				return nil, fmt.Errorf("target price must be positive")
+			}
+			converted, err := rates.Convert(current, c.PriceCurrency(), code)
+			if err != nil {
+				return nil, err
+			}
+			if converted <= *p.TargetPrice {
+				return nil, fmt.Errorf("%w: the price is already %s", ErrAlreadyMet, formatMoney(converted, code))
+			}
+		}
+	case BackInStock:
+		if p.TargetPrice != nil {
+			return nil, fmt.Errorf("back-in-stock alerts don't take a target price")
+		}
+		available, tracked := inStock(product, size)
+		if !tracked {
+			return nil, ErrNoStockStatus
+		}
+		if available {
+			return nil, fmt.Errorf("%w: the product is in stock", ErrAlreadyMet)
+		}
+	}
+
+	a, err := scanAlert(cat.DB.QueryRowContext(ctx,
+		"INSERT INTO alerts AS a (user_id, kind, category, product_link, size, target_price, currency, reference_price)"+
+			" VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"+
+			" ON CONFLICT (user_id, kind, category, product_link, size) WHERE triggered_at IS NULL"+
+			" DO UPDATE SET target_price = EXCLUDED.target_price, currency = EXCLUDED.currency, reference_price = EXCLUDED.reference_price"+
+			" RETURNING "+columns,
+		userID, string(p.Kind), c.Key, productLink, size, p.TargetPrice, code, reference))
+	if err != nil {
+		return nil, err
+	}
+	a.ProductID, a.Product = p.ProductID, product
+	return a, nil
+}
+
+// List returns a user's alerts, newest first, with their products.
+func List(ctx context.Context, cat *catalog.Catalog, userID int) ([]*Alert, error) {
+	rows, err := cat.DB.QueryContext(ctx, "SELECT "+columns+" FROM alerts a WHERE a.user_id = $1 ORDER BY a.created_at DESC, a.id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	alerts := []*Alert{}
+	for rows.Next() {
+		a, err := scanAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return alerts, attachProducts(ctx, cat, alerts)
+}
+
+// Delete removes one of a user's alerts. It returns ErrNotFound when the
+// user has no alert with that id.
+func Delete(ctx context.Context, db *sql.DB, userID int, id string) error {
+	if _, err := strconv.Atoi(id); err != nil {
+		return fmt.Errorf("alert %q: %w", id, ErrNotFound)
+	}
+	res, err := db.ExecContext(ctx, "DELETE FROM alerts WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("alert %q: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// attachProducts looks up the product currently listed under each alert's
+// link.
+func attachProducts(ctx context.Context, cat *catalog.Catalog, alerts []*Alert) error {
+	links := map[string][]string{}
+	for _, a := range alerts {
+		links[a.Category] = append(links[a.Category], a.ProductLink)
+	}
+	for key, categoryLinks := range links {
+		if _, ok := catalog.Lookup(key); !ok {
+			continue
+		}
+		found, err := cat.ProductsByLink(ctx, key, categoryLinks)
+		if err != nil {
+			return err
+		}
+		for _, a := range alerts {
+			if p := found[a.ProductLink]; a.Category == key && p != nil {
+				a.ProductID, a.Product = catalog.ProductID(p), p
+			}
+		}
+	}
+	return nil
+}
+
+// formatMoney renders an amount for messages, such as "AED 1250.00".
+func formatMoney(amount float64, code string) string {
+	return fmt.Sprintf("%s %.2f", code, amount)
+}
@@ -0,0 +1,156 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"plutus-backend/catalog"
+	"plutus-backend/currency"
+	"plutus-backend/notify"
+)
+
+// DefaultStorefrontURL is used when Evaluator.StorefrontURL is empty.
+const DefaultStorefrontURL = "https://houseofplutus.com"
+
+// Evaluator checks pending alerts against the catalog and notifies their
+// owners.
+type Evaluator struct {
+	Catalog  *catalog.Catalog
+	Rates    *currency.Rates
+	Notifier notify.Notifier
+	// StorefrontURL is the storefront's base URL that product page paths
+	// (see catalog.Category.PagePath) are appended to in messages.
+	StorefrontURL string
+}
+
+// recipient is who a pending alert notifies.
+type recipient struct {
+	email    string
+	fullName string
+}
+
+// Run notifies the owner of every pending alert whose condition is met and
+// marks the alert triggered. An alert is claimed before its notification is
+// sent, so concurrent runs don't notify twice, and released again if sending
+// fails so the next run retries it. Run returns the number of notifications
+// sent; alerts it couldn't deliver are reported in the error.
+func (e *Evaluator) Run(ctx context.Context) (int, error) {
+	rows, err := e.Catalog.DB.QueryContext(ctx, "SELECT "+columns+", u.email, u.full_name"+
+		" FROM alerts a JOIN users u ON u.id = a.user_id WHERE a.triggered_at IS NULL ORDER BY a.id")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var pending []*Alert
+	recipients := map[int]recipient{}
+	for rows.Next() {
+		var to recipient
+		a, err := scanAlert(rows, &to.email, &to.fullName)
+		if err != nil {
+			return 0, err
+		}
+		pending = append(pending, a)
+		recipients[a.ID] = to
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+	if err := attachProducts(ctx, e.Catalog, pending); err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var errs []error
+	for _, a := range pending {
+		m, due, err := e.message(a, recipients[a.ID])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("alert %d: %w", a.ID, err))
+			continue
+		}
+		if !due {
+			continue
+		}
+		res, err := e.Catalog.DB.ExecContext(ctx, "UPDATE alerts SET triggered_at = now() WHERE id = $1 AND triggered_at IS NULL", a.ID)
+		if err != nil {
+			return sent, errors.Join(append(errs, err)...)
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			continue
+		}
+		if err := e.Notifier.Notify(ctx, m); err != nil {
+			errs = append(errs, fmt.Errorf("alert %d: %w", a.ID, err))
+			if _, err := e.Catalog.DB.ExecContext(ctx, "UPDATE alerts SET triggered_at = NULL WHERE id = $1", a.ID); err != nil {
+				errs = append(errs, fmt.Errorf("alert %d: %w", a.ID, err))
+			}
+			continue
+		}
+		sent++
+	}
+	return sent, errors.Join(errs...)
+}
+
+// message checks whether a's condition is met and if so, composes its
+// notification.
+func (e *Evaluator) message(a *Alert, to recipient) (notify.Message, bool, error) {
+	c, ok := catalog.Lookup(a.Category)
+	if !ok || a.Product == nil {
+		return notify.Message{}, false, nil
+	}
+	name := title(a.Product)
+	if a.Size != "" {
+		name += " (size " + a.Size + ")"
+	}
+	var subject, news string
+	switch a.Kind {
+	case PriceDrop:
+		current, ok := price(a.Product, a.Size)
+		if !ok {
+			return notify.Message{}, false, nil
+		}
+		shown, err := e.Rates.Convert(current, c.PriceCurrency(), a.Currency)
+		if err != nil {
+			return notify.Message{}, false, err
+		}
+		if a.TargetPrice != nil {
+			if shown > *a.TargetPrice {
+				return notify.Message{}, false, nil
+			}
+			news = fmt.Sprintf("%s is now %s, at or below your target of %s.", name, formatMoney(shown, a.Currency), formatMoney(*a.TargetPrice, a.Currency))
+		} else {
+			if a.ReferencePrice == nil || current >= *a.ReferencePrice {
+				return notify.Message{}, false, nil
+			}
+			was, err := e.Rates.Convert(*a.ReferencePrice, c.PriceCurrency(), a.Currency)
+			if err != nil {
+				return notify.Message{}, false, err
+			}
+			news = fmt.Sprintf("%s is now %s, down from %s.", name, formatMoney(shown, a.Currency), formatMoney(was, a.Currency))
+		}
+		subject = "Price drop: " + title(a.Product)
+	case BackInStock:
+		if available, _ := inStock(a.Product, a.Size); !available {
+			return notify.Message{}, false, nil
+		}
+		subject = "Back in stock: " + title(a.Product)
+		news = name + " is back in stock."
+	default:
+		return notify.Message{}, false, nil
+	}
+
+	greeting := "Hi,"
+	if first := strings.Fields(to.fullName); len(first) > 0 {
+		greeting = "Hi " + first[0] + ","
+	}
+	storefront := e.StorefrontURL
+	if storefront == "" {
+		storefront = DefaultStorefrontURL
+	}
+	body := greeting + "\n\n" + news + "\n\n" +
+		strings.TrimRight(storefront, "/") + c.PagePath + a.ProductID + "\n\n" +
+		"This alert is now switched off. You can set a new one from the product page.\n\n" +
+		"House of Plutus"
+	return notify.Message{To: to.email, Subject: subject, Body: body}, true, nil
+}
@@ -0,0 +1,90 @@
+package alerts
+
+import (
+	"strings"
+
+	"plutus-backend/graph/model"
+)
+
+// title returns the product's brand and name, for messages.
+func title(p model.Product) string {
+	var brand, name string
+	switch p := p.(type) {
+	case *model.Sneaker:
+		brand, name = p.Brand, p.ProductName
+	case *model.Watch:
+		brand, name = p.Brand, p.Name
+	case *model.Perfume:
+		brand, name = p.Brand, p.Title
+	case *model.Accessory:
+		brand, name = p.Brand, p.ProductName
+	case *model.Apparel:
+		brand, name = p.Brand, p.ProductName
+	}
+	if brand == "" || strings.HasPrefix(strings.ToLower(name), strings.ToLower(brand)) {
+		return name
+	}
+	return brand + " " + name
+}
+
+// price returns the product's price for size, or its lowest price when size
+// is empty, in its category's currency. It reports false when the product
+// has no such price.
+func price(p model.Product, size string) (float64, bool) {
+	var sizePrices []*model.SizePrice
+	switch p := p.(type) {
+	case *model.Sneaker:
+		sizePrices = p.SizePrices
+	case *model.Accessory:
+		sizePrices = p.SizePrices
+	case *model.Apparel:
+		sizePrices = p.SizePrices
+	case *model.Watch:
+		return p.SalePrice, size == "" && p.SalePrice > 0
+	case *model.Perfume:
+		lowest, ok := 0.0, false
+		for _, v := range p.Variants {
+			if v.Price == nil || size != "" && (v.Size == nil || !sameSize(*v.Size, size)) {
+				continue
+			}
+			if !ok || *v.Price < lowest {
+				lowest, ok = *v.Price, true
+			}
+		}
+		return lowest, ok
+	}
+	lowest, ok := 0.0, false
+	for _, sp := range sizePrices {
+		if size != "" && !sameSize(sp.Size, size) {
+			continue
+		}
+		if !ok || sp.Price < lowest {
+			lowest, ok = sp.Price, true
+		}
+	}
+	return lowest, ok
+}
+
+// inStock reports whether the product, and size when given, can be bought.
+// tracked is false for products without a stock status.
+func inStock(p model.Product, size string) (available, tracked bool) {
+	switch p := p.(type) {
+	case *model.Sneaker:
+		available = !p.SoldOut
+	case *model.Accessory:
+		available = p.InStock
+	case *model.Apparel:
+		available = p.InStock
+	default:
+		return false, false
+	}
+	if available && size != "" {
+		// A size is available while it is listed with a price.
+		_, available = price(p, size)
+	}
+	return available, true
+}
+
+func sameSize(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
@@ -0,0 +1,44 @@
+// Package auth identifies the signed-in user behind a request. Sessions
+// issues signed access tokens and rotating refresh tokens, and its
+// Middleware puts the user of a valid access token into the request
+// context, where handlers and resolvers read it with FromContext.
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnauthenticated is returned by Require when no user is signed in.
+var ErrUnauthenticated = errors.New("you need to sign in")
+
+// User is a row of the users table. ID is the numeric primary key other
+// tables refer to; UserID is the public "user_..." identifier.
+type User struct {
+	ID       int
+	UserID   string
+	Email    string
+	FullName string
+	Role     Role
+}
+
+type contextKey struct{}
+
+// WithUser returns a context whose signed-in user is u.
+func WithUser(ctx context.Context, u *User) context.Context {
+	return context.WithValue(ctx, contextKey{}, u)
+}
+
+// FromContext returns the user set by WithUser, or nil.
+func FromContext(ctx context.Context) *User {
+	u, _ := ctx.Value(contextKey{}).(*User)
+	return u
+}
+
+// Require returns the signed-in user, or ErrUnauthenticated.
+func Require(ctx context.Context) (*User, error) {
+	if u := FromContext(ctx); u != nil {
+		return u, nil
+	}
+	return nil, ErrUnauthenticated
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func bridgeSignature(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyBridge(t *testing.T) {
+	body := []byte(`{"provider":"google","subject":"123","email":"a@example.com"}`)
+	ts := strconv.FormatInt(testNow.Unix(), 10)
+	sig := bridgeSignature(testSecret, ts, body)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		body      []byte
+		now       time.Time
+		ok        bool
+	}{
+		{"valid", ts, sig, body, testNow, true},
+		{"within skew", ts, sig, body, testNow.Add(BridgeMaxSkew), true},
+		{"body changed", ts, sig, []byte(`{"provider":"google","subject":"124","email":"a@example.com"}`), testNow, false},
+		{"signed with another secret", ts, bridgeSignature([]byte("other-secret"), ts, body), body, testNow, false},
+		{"signature truncated", ts, sig[:len(sig)-2], body, testNow, false},
+		{"signature altered", ts, "A" + sig[1:], body, testNow, false},
+		{"signature missing", ts, "", body, testNow, false},
+		{"timestamp changed", strconv.FormatInt(testNow.Unix()+1, 10), sig, body, testNow, false},
+		{"too old", ts, sig, body, testNow.Add(BridgeMaxSkew + time.Second), false},
+		{"from the future", ts, sig, body, testNow.Add(-BridgeMaxSkew - time.Second), false},
+		{"timestamp not a number", "soon", bridgeSignature(testSecret, "soon", body), body, testNow, false},
+	}
+	for _, tt := range tests {
+		err := VerifyBridge(testSecret, tt.timestamp, tt.signature, tt.body, tt.now)
+		if tt.ok && err != nil {
+			t.Errorf("%s: err = %v, want nil", tt.name, err)
+		}
+		if !tt.ok && err != ErrInvalidToken {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Purpose is what a one-time token is for. A token only works for the
+// purpose it was issued for.
+type Purpose string
+
+const (
+	PasswordReset     Purpose = "password_reset"
+	EmailVerification Purpose = "email_verification"
+)
+
+// TTL is how long a token of the purpose stays usable.
+func (p Purpose) TTL() time.Duration {
+	if p == PasswordReset {
+		return time.Hour
+	}
+	return 48 * time.Hour
+}
+
+// IssueToken returns a new token for userID. Unused tokens issued earlier
+// for the same purpose stop working, so only the latest email's link does.
+func IssueToken(ctx context.Context, db *sql.DB, userID int, p Purpose) (string, error) {
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "UPDATE user_tokens SET used_at = now() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL",
+		userID, string(p)); err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+		userID, string(p), hash, time.Now().Add(p.TTL())); err != nil {
+		return "", err
+	}
+	return token, tx.Commit()
+}
+
+// ConsumeToken uses up a token within tx and returns the user it was
+// issued to. It returns ErrInvalidToken for a token that is unknown, used,
+// expired or issued for another purpose.
+func ConsumeToken(ctx context.Context, tx *sql.Tx, token string, p Purpose) (int, error) {
+	var userID int
+	err := tx.QueryRowContext(ctx, "UPDATE user_tokens SET used_at = now()"+
+		" WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now() RETURNING user_id",
+		hashToken(token), string(p)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidToken
+	}
+	return userID, err
+}
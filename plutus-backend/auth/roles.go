@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Role is what a user may do. Each role can do everything the roles before
+// it in Roles can: staff manage enquiries, admins also manage the catalog
+// and users.
+type Role string
+
+const (
+	Customer Role = "customer"
+	Staff    Role = "staff"
+	Admin    Role = "admin"
+)
+
+// Roles lists the roles from least to most privileged. The users_role_check
+// constraint (migration 0010_roles) allows exactly these.
+var Roles = []Role{Customer, Staff, Admin}
+
+// ErrForbidden is returned when the signed-in user's role isn't enough.
+var ErrForbidden = errors.New("you don't have permission to do that")
+
+// ErrUserNotFound is returned by SetRole for an email without an account.
+var ErrUserNotFound = errors.New("no account has that email")
+
+// ParseRole returns the role named s, in either case.
+func ParseRole(s string) (Role, bool) {
+	for _, r := range Roles {
+		if strings.EqualFold(s, string(r)) {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+func (r Role) rank() int {
+	for i, role := range Roles {
+		if role == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// Includes reports whether r may do what other may.
+func (r Role) Includes(other Role) bool {
+	return r.rank() >= other.rank() && other.rank() >= 0
+}
+
+// MarshalGQL writes r as a GraphQL Role enum value.
+func (r Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(strings.ToUpper(string(r))))
+}
+
+// UnmarshalGQL reads a GraphQL Role enum value.
+func (r *Role) UnmarshalGQL(v any) error {
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("role must be a string")
+	}
+	role, ok := ParseRole(s)
+	if !ok {
+		return fmt.Errorf("%q is not a valid Role", s)
+	}
+	*r = role
+	return nil
+}
+
+// SetRole gives the account registered under email a role and returns it.
+// Access tokens carry the role, so the change reaches the user's sessions as
+// their tokens are refreshed, within DefaultAccessTTL.
+func SetRole(ctx context.Context, db *sql.DB, email string, role Role) (*User, error) {
+	if role.rank() < 0 {
+		return nil, fmt.Errorf("unknown role %q", role)
+	}
+	var u User
+	err := db.QueryRowContext(ctx, "UPDATE users SET role = $2, updated_at = CURRENT_TIMESTAMP WHERE lower(email) = lower($1)"+
+		" RETURNING id, user_id, email, full_name, role", email, string(role)).Scan(&u.ID, &u.UserID, &u.Email, &u.FullName, &u.Role)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// RequireRole returns the signed-in user if their role includes role,
+// ErrUnauthenticated when nobody is signed in and ErrForbidden otherwise.
+func RequireRole(ctx context.Context, role Role) (*User, error) {
+	u, err := Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !u.Role.Includes(role) {
+		return nil, ErrForbidden
+	}
+	return u, nil
+}
+
+// RequireRoleHTTP returns middleware that serves only requests whose user,
+// as put in the context by Sessions.Middleware, has role. It answers 401
+// when nobody is signed in and 403 when their role isn't enough; it is the
+// REST counterpart of the GraphQL @hasRole directive.
+func RequireRoleHTTP(role Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := RequireRole(r.Context(), role); err != nil {
+				status := http.StatusForbidden
+				if errors.Is(err, ErrUnauthenticated) {
+					status = http.StatusUnauthorized
+				}
+				writeError(w, status, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"message": err.Error(),
+	})
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireRoleHTTP(t *testing.T) {
+	s := NewSessions(nil, string(testSecret))
+	tokenFor := func(role Role) string {
+		tokens, err := s.tokens(&User{ID: 42, UserID: "user_abc", Email: "a@example.com", Role: role}, 1, "refresh", testNow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tokens.AccessToken
+	}
+	var served *User
+	h := s.Middleware(RequireRoleHTTP(Staff)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = FromContext(r.Context())
+	})))
+
+	tests := []struct {
+		name   string
+		auth   string
+		status int
+	}{
+		{"signed out", "", http.StatusUnauthorized},
+		{"invalid token", "Bearer not-a-token", http.StatusUnauthorized},
+		{"customer", "Bearer " + tokenFor(Customer), http.StatusForbidden},
+		{"staff", "Bearer " + tokenFor(Staff), http.StatusOK},
+		{"admin", "Bearer " + tokenFor(Admin), http.StatusOK},
+	}
+	for _, tt := range tests {
+		served = nil
+		req := httptest.NewRequest("GET", "/api/admin", nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if (served != nil) != (tt.status == http.StatusOK) {
+			t.Errorf("%s: handler served = %v, want %v", tt.name, served != nil, tt.status == http.StatusOK)
+		}
+	}
+}
+
+func TestRoleIncludes(t *testing.T) {
+	tests := []struct {
+		role, other Role
+		want        bool
+	}{
+		{Customer, Customer, true},
+		{Customer, Staff, false},
+		{Staff, Customer, true},
+		{Staff, Admin, false},
+		{Admin, Staff, true},
+		{Admin, "owner", false},
+		{"owner", Customer, false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Includes(tt.other); got != tt.want {
+			t.Errorf("%q.Includes(%q) = %v, want %v", tt.role, tt.other, got, tt.want)
+		}
+	}
+}
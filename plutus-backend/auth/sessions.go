@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAccessTTL is how long an access token is accepted. Access
+	// tokens can't be revoked, so it is kept short.
+	DefaultAccessTTL = 15 * time.Minute
+	// DefaultRefreshTTL is how long a session lasts without being
+	// refreshed.
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+// Tokens are what a client holds for one session: a short-lived access
+// token sent as "Authorization: Bearer ..." and a refresh token exchanged
+// for new Tokens when it expires.
+type Tokens struct {
+	AccessToken      string    `json:"accessToken"`
+	AccessExpiresAt  time.Time `json:"accessExpiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+// Sessions issues and rotates tokens. Each refresh replaces the session's
+// refresh token; presenting a replaced one again means it was copied, so
+// the session is revoked.
+type Sessions struct {
+	DB         *sql.DB
+	Secret     []byte
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// NewSessions returns Sessions over db signing with secret and using the
+// default lifetimes.
+func NewSessions(db *sql.DB, secret string) *Sessions {
+	return &Sessions{DB: db, Secret: []byte(secret), AccessTTL: DefaultAccessTTL, RefreshTTL: DefaultRefreshTTL}
+}
+
+// Start opens a session for u, who has just proven who they are. u.Role
+// goes into the access tokens.
+func (s *Sessions) Start(ctx context.Context, u *User, userAgent, ip string) (*Tokens, error) {
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	expires := time.Now().Add(s.RefreshTTL)
+	var id int64
+	err = s.DB.QueryRowContext(ctx, "INSERT INTO sessions (user_id, refresh_hash, user_agent, ip, expires_at)"+
+		" VALUES ($1, $2, $3, $4, $5) RETURNING id", u.ID, hash, truncate(userAgent, 255), ip, expires).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokens(u, id, refresh, expires)
+}
+
+// Refresh exchanges a refresh token for new Tokens and returns the
+// session's user.
+func (s *Sessions) Refresh(ctx context.Context, refreshToken string) (*Tokens, *User, error) {
+	old := hashToken(refreshToken)
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	expires := time.Now().Add(s.RefreshTTL)
+	var id int64
+	var u User
+	err = s.DB.QueryRowContext(ctx, "UPDATE sessions s SET refresh_hash = $2, previous_hash = s.refresh_hash, last_used_at = now(), expires_at = $3"+
+		" FROM users u WHERE u.id = s.user_id AND s.refresh_hash = $1 AND s.revoked_at IS NULL AND s.expires_at > now()"+
+		" RETURNING s.id, u.id, u.user_id, u.email, u.full_name, u.role", old, hash, expires).Scan(&id, &u.ID, &u.UserID, &u.Email, &u.FullName, &u.Role)
+	if err == sql.ErrNoRows {
+		res, err := s.DB.ExecContext(ctx, "UPDATE sessions SET revoked_at = now() WHERE previous_hash = $1 AND revoked_at IS NULL", old)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			log.Printf("⚠️ Warning: A replaced refresh token was reused; session revoked")
+		}
+		return nil, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens, err := s.tokens(&u, id, refresh, expires)
+	return tokens, &u, err
+}
+
+// Revoke ends the session a refresh token belongs to. Unknown tokens are
+// ignored.
+func (s *Sessions) Revoke(ctx context.Context, refreshToken string) error {
+	_, err := s.DB.ExecContext(ctx, "UPDATE sessions SET revoked_at = now() WHERE refresh_hash = $1 AND revoked_at IS NULL", hashToken(refreshToken))
+	return err
+}
+
+// RevokeAll ends every session of a user, signing them out everywhere once
+// their access tokens expire.
+func (s *Sessions) RevokeAll(ctx context.Context, userID int) error {
+	_, err := s.DB.ExecContext(ctx, "UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
+
+// Authenticate returns the user an access token was issued to.
+func (s *Sessions) Authenticate(accessToken string) (*User, error) {
+	c, err := Verify(s.Secret, accessToken, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return c.User(), nil
+}
+
+// Middleware puts the user of a request's bearer token into its context
+// (see FromContext). Requests without a valid token are served signed out,
+// so an expired token doesn't break public pages; a token that doesn't
+// verify is flagged with a WWW-Authenticate header so the client knows to
+// refresh it.
+func (s *Sessions) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := BearerToken(r); ok {
+			if u, err := s.Authenticate(token); err == nil {
+				r = r.WithContext(WithUser(r.Context(), u))
+			} else {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// BearerToken returns the token of a request's "Authorization: Bearer"
+// header.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func (s *Sessions) tokens(u *User, session int64, refresh string, refreshExpires time.Time) (*Tokens, error) {
+	now := time.Now()
+	expires := now.Add(s.AccessTTL)
+	access, err := Sign(s.Secret, Claims{
+		Issuer:    Issuer,
+		Subject:   u.UserID,
+		UserID:    u.ID,
+		Email:     u.Email,
+		Name:      u.FullName,
+		Role:      u.Role,
+		Session:   session,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expires.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{AccessToken: access, AccessExpiresAt: expires, RefreshToken: refresh, RefreshExpiresAt: refreshExpires}, nil
+}
+
+// newRefreshToken returns a random refresh token and the hash it is stored
+// as.
+func newRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return strings.ToValidUTF8(s[:n], "")
+	}
+	return s
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSessions is an in-memory stand-in for the sessions table, served
+// through database/sql by answering the statements Sessions runs.
+type fakeSessions struct {
+	mu       sync.Mutex
+	user     User
+	sessions []*fakeSession
+}
+
+type fakeSession struct {
+	refreshHash  string
+	previousHash string
+	expiresAt    time.Time
+	revoked      bool
+}
+
+var (
+	fakeDBsMu sync.Mutex
+	fakeDBs   = map[string]*fakeSessions{}
+)
+
+func init() {
+	sql.Register("fakesessions", fakeDriver{})
+}
+
+func newFakeSessions(t *testing.T, u User) (*Sessions, *fakeSessions) {
+	t.Helper()
+	fake := &fakeSessions{user: u}
+	fakeDBsMu.Lock()
+	fakeDBs[t.Name()] = fake
+	fakeDBsMu.Unlock()
+	db, err := sql.Open("fakesessions", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewSessions(db, string(testSecret)), fake
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	fake, ok := fakeDBs[name]
+	if !ok {
+		return nil, fmt.Errorf("no fake database %q", name)
+	}
+	return fake, nil
+}
+
+func (f *fakeSessions) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{f, query}, nil
+}
+func (f *fakeSessions) Close() error { return nil }
+func (f *fakeSessions) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("transactions aren't faked")
+}
+
+type fakeStmt struct {
+	f     *fakeSessions
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	var n int64
+	switch {
+	case strings.HasPrefix(s.query, "UPDATE sessions SET revoked_at = now() WHERE previous_hash = $1"):
+		for _, sess := range s.f.sessions {
+			if sess.previousHash == args[0] && !sess.revoked {
+				sess.revoked = true
+				n++
+			}
+		}
+	case strings.HasPrefix(s.query, "UPDATE sessions SET revoked_at = now() WHERE refresh_hash = $1"):
+		for _, sess := range s.f.sessions {
+			if sess.refreshHash == args[0] && !sess.revoked {
+				sess.revoked = true
+				n++
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unexpected exec: %s", s.query)
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	u := s.f.user
+	switch {
+	case strings.HasPrefix(s.query, "INSERT INTO sessions"):
+		s.f.sessions = append(s.f.sessions, &fakeSession{refreshHash: args[1].(string), expiresAt: args[4].(time.Time)})
+		return &fakeRows{columns: []string{"id"}, rows: [][]driver.Value{{int64(len(s.f.sessions))}}}, nil
+	case strings.HasPrefix(s.query, "UPDATE sessions s SET refresh_hash = $2"):
+		rows := &fakeRows{columns: []string{"id", "uid", "user_id", "email", "full_name", "role"}}
+		for i, sess := range s.f.sessions {
+			if sess.refreshHash == args[0] && !sess.revoked && sess.expiresAt.After(time.Now()) {
+				sess.previousHash, sess.refreshHash, sess.expiresAt = sess.refreshHash, args[1].(string), args[2].(time.Time)
+				rows.rows = append(rows.rows, []driver.Value{int64(i + 1), int64(u.ID), u.UserID, u.Email, u.FullName, string(u.Role)})
+			}
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", s.query)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	ctx := context.Background()
+	u := User{ID: 42, UserID: "user_abc", Email: "a@example.com", Role: Staff}
+	s, _ := newFakeSessions(t, u)
+
+	first, err := s.Start(ctx, &u, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, got, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("Refresh returned the same refresh token")
+	}
+	if *got != u {
+		t.Errorf("user = %+v, want %+v", *got, u)
+	}
+	claims, err := Verify(s.Secret, second.AccessToken, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Session != 1 || claims.Role != Staff {
+		t.Errorf("claims = %+v, want session 1 and role staff", claims)
+	}
+	if _, _, err := s.Refresh(ctx, second.RefreshToken); err != nil {
+		t.Errorf("refreshing with the new token: err = %v, want nil", err)
+	}
+}
+
+func TestRefreshReusedTokenRevokesSession(t *testing.T) {
+	ctx := context.Background()
+	u := User{ID: 42, UserID: "user_abc", Email: "a@example.com", Role: Customer}
+	s, fake := newFakeSessions(t, u)
+
+	first, err := s.Start(ctx, &u, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Someone replays the token the first refresh replaced.
+	if _, _, err := s.Refresh(ctx, first.RefreshToken); err != ErrInvalidToken {
+		t.Fatalf("reused token: err = %v, want ErrInvalidToken", err)
+	}
+	if !fake.sessions[0].revoked {
+		t.Fatal("reusing a replaced token didn't revoke the session")
+	}
+	// The legitimate holder's current token dies with the session.
+	if _, _, err := s.Refresh(ctx, second.RefreshToken); err != ErrInvalidToken {
+		t.Errorf("current token after reuse: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestRefreshUnknownTokenRevokesNothing(t *testing.T) {
+	ctx := context.Background()
+	u := User{ID: 42, UserID: "user_abc", Email: "a@example.com", Role: Customer}
+	s, fake := newFakeSessions(t, u)
+
+	if _, err := s.Start(ctx, &u, "test", "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Refresh(ctx, "not-a-token"); err != ErrInvalidToken {
+		t.Errorf("err = %v, want ErrInvalidToken", err)
+	}
+	if fake.sessions[0].revoked {
+		t.Error("an unknown token revoked a session")
+	}
+}
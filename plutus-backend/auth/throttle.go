@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Throttle counts failed sign-ins per key, such as an email address or an
+// IP address, and locks a key out once it has failed Threshold times. Each
+// further failure doubles the lockout, from Base up to Max. Counts start
+// over after Window without failures.
+type Throttle struct {
+	DB        *sql.DB
+	Scope     string
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Window    time.Duration
+}
+
+// NewAccountThrottle returns the Throttle for sign-ins to one account,
+// keyed by email address.
+func NewAccountThrottle(db *sql.DB) *Throttle {
+	return &Throttle{DB: db, Scope: "account", Threshold: 5, Base: 30 * time.Second, Max: time.Hour, Window: 24 * time.Hour}
+}
+
+// NewIPThrottle returns the Throttle for sign-ins from one IP address. It
+// allows more failures than an account, since many people can share an
+// address.
+func NewIPThrottle(db *sql.DB) *Throttle {
+	return &Throttle{DB: db, Scope: "ip", Threshold: 20, Base: 30 * time.Second, Max: time.Hour, Window: 24 * time.Hour}
+}
+
+// Locked returns how much longer key is locked out, or 0.
+func (t *Throttle) Locked(ctx context.Context, key string) (time.Duration, error) {
+	var until time.Time
+	err := t.DB.QueryRowContext(ctx, "SELECT locked_until FROM login_failures WHERE scope = $1 AND key = $2 AND locked_until > now()",
+		t.Scope, key).Scan(&until)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return time.Until(until), nil
+}
+
+// Fail records a failed sign-in for key and returns how long key is now
+// locked out, or 0.
+func (t *Throttle) Fail(ctx context.Context, key string) (time.Duration, error) {
+	var failures int
+	err := t.DB.QueryRowContext(ctx, "INSERT INTO login_failures (scope, key, failures) VALUES ($1, $2, 1)"+
+		" ON CONFLICT (scope, key) DO UPDATE SET last_failed_at = now(), failures = CASE"+
+		" WHEN login_failures.last_failed_at < now() - make_interval(secs => $3) THEN 1 ELSE login_failures.failures + 1 END"+
+		" RETURNING failures", t.Scope, key, t.Window.Seconds()).Scan(&failures)
+	if err != nil {
+		return 0, err
+	}
+	lockout := t.lockout(failures)
+	if lockout == 0 {
+		return 0, nil
+	}
+	_, err = t.DB.ExecContext(ctx, "UPDATE login_failures SET locked_until = now() + make_interval(secs => $3) WHERE scope = $1 AND key = $2",
+		t.Scope, key, lockout.Seconds())
+	return lockout, err
+}
+
+// Reset forgets the failures of key, after it signed in successfully.
+func (t *Throttle) Reset(ctx context.Context, key string) error {
+	_, err := t.DB.ExecContext(ctx, "DELETE FROM login_failures WHERE scope = $1 AND key = $2", t.Scope, key)
+	return err
+}
+
+// Prune deletes the counts that have started over.
+func (t *Throttle) Prune(ctx context.Context) error {
+	_, err := t.DB.ExecContext(ctx, "DELETE FROM login_failures WHERE scope = $1 AND last_failed_at < now() - make_interval(secs => $2)"+
+		" AND (locked_until IS NULL OR locked_until < now())", t.Scope, t.Window.Seconds())
+	return err
+}
+
+// lockout is the lockout after the given number of consecutive failures.
+func (t *Throttle) lockout(failures int) time.Duration {
+	if failures < t.Threshold {
+		return 0
+	}
+	d := t.Base
+	for i := t.Threshold; i < failures && d < t.Max; i++ {
+		d *= 2
+	}
+	return min(d, t.Max)
+}
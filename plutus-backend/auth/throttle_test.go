@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleLockout(t *testing.T) {
+	account := NewAccountThrottle(nil)
+	ip := NewIPThrottle(nil)
+	tests := []struct {
+		throttle *Throttle
+		failures int
+		want     time.Duration
+	}{
+		{account, 0, 0},
+		{account, 1, 0},
+		{account, 4, 0},
+		{account, 5, 30 * time.Second},
+		{account, 6, time.Minute},
+		{account, 7, 2 * time.Minute},
+		{account, 10, 16 * time.Minute},
+		{account, 11, 32 * time.Minute},
+		// Doubling again would pass Max, so it stops there.
+		{account, 12, time.Hour},
+		{account, 13, time.Hour},
+		{account, 1000, time.Hour},
+		{ip, 19, 0},
+		{ip, 20, 30 * time.Second},
+		{ip, 21, time.Minute},
+		{ip, 27, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := tt.throttle.lockout(tt.failures); got != tt.want {
+			t.Errorf("%s lockout(%d) = %v, want %v", tt.throttle.Scope, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestThrottleLockoutCapsBaseAboveMax(t *testing.T) {
+	th := &Throttle{Threshold: 1, Base: 2 * time.Hour, Max: time.Hour}
+	for _, failures := range []int{1, 2, 5} {
+		if got := th.lockout(failures); got != time.Hour {
+			t.Errorf("lockout(%d) = %v, want %v", failures, got, time.Hour)
+		}
+	}
+}
+
+func TestThrottleLockoutNeverShrinks(t *testing.T) {
+	th := NewAccountThrottle(nil)
+	var previous time.Duration
+	for failures := 0; failures <= 64; failures++ {
+		got := th.lockout(failures)
+		if got < previous {
+			t.Fatalf("lockout(%d) = %v, less than lockout(%d) = %v", failures, got, failures-1, previous)
+		}
+		if got > th.Max {
+			t.Fatalf("lockout(%d) = %v, more than Max %v", failures, got, th.Max)
+		}
+		previous = got
+	}
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned for a token that is malformed, badly signed,
+// expired or revoked.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Issuer is the iss claim of every access token.
+const Issuer = "plutus-backend"
+
+// Claims are the claims of an access token. The user's details travel in
+// the token so authenticating a request needs no database lookup.
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"` // users.user_id
+	UserID    int    `json:"uid"` // users.id
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	Role      Role   `json:"role,omitempty"`
+	Session   int64  `json:"sid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// User returns the user the claims describe. Tokens issued before roles
+// existed are customers'.
+func (c *Claims) User() *User {
+	role := c.Role
+	if role == "" {
+		role = Customer
+	}
+	return &User{ID: c.UserID, UserID: c.Subject, Email: c.Email, FullName: c.Name, Role: role}
+}
+
+// jwtHeader is the only header Sign writes and Verify accepts.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Sign encodes c as a JWT signed with HMAC-SHA256.
+func Sign(secret []byte, c Claims) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + signature(secret, unsigned), nil
+}
+
+// Verify checks a token's signature, issuer and expiry at now and returns
+// its claims.
+func Verify(secret []byte, token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(signature(secret, parts[0]+"."+parts[1]))) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var c Claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if c.Issuer != Issuer || c.UserID == 0 || now.Unix() >= c.ExpiresAt {
+		return nil, ErrInvalidToken
+	}
+	return &c, nil
+}
+
+func signature(secret []byte, unsigned string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testSecret = []byte("test-secret")
+	testNow    = time.Unix(1_700_000_000, 0)
+)
+
+func testClaims() Claims {
+	return Claims{
+		Issuer:    Issuer,
+		Subject:   "user_abc",
+		UserID:    42,
+		Email:     "a@example.com",
+		Role:      Customer,
+		Session:   7,
+		IssuedAt:  testNow.Unix(),
+		ExpiresAt: testNow.Add(DefaultAccessTTL).Unix(),
+	}
+}
+
+func mustSign(t *testing.T, secret []byte, c Claims) string {
+	t.Helper()
+	token, err := Sign(secret, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestVerifyAcceptsSignedToken(t *testing.T) {
+	c := testClaims()
+	got, err := Verify(testSecret, mustSign(t, testSecret, c), testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != c {
+		t.Errorf("claims = %+v, want %+v", *got, c)
+	}
+}
+
+func TestVerifyRejectsTamperedToken(t *testing.T) {
+	token := mustSign(t, testSecret, testClaims())
+	parts := strings.Split(token, ".")
+
+	escalated := testClaims()
+	escalated.Role = Admin
+	flipped := []byte(parts[2])
+	if flipped[0] == 'A' {
+		flipped[0] = 'B'
+	} else {
+		flipped[0] = 'A'
+	}
+
+	tests := map[string]string{
+		"payload swapped":    parts[0] + "." + encodeSegment(t, escalated) + "." + parts[2],
+		"signature altered":  parts[0] + "." + parts[1] + "." + string(flipped),
+		"signature dropped":  parts[0] + "." + parts[1] + ".",
+		"other secret":       mustSign(t, []byte("other-secret"), testClaims()),
+		"extra segment":      token + "." + parts[2],
+		"missing segment":    parts[0] + "." + parts[1],
+		"payload not base64": parts[0] + ".!!!." + signature(testSecret, parts[0]+".!!!"),
+		"payload not JSON":   parts[0] + ".bm90IGpzb24." + signature(testSecret, parts[0]+".bm90IGpzb24"),
+	}
+	for name, token := range tests {
+		if _, err := Verify(testSecret, token, testNow); err != ErrInvalidToken {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+func TestVerifyRejectsOtherAlgorithms(t *testing.T) {
+	payload := encodeSegment(t, testClaims())
+	tests := map[string]string{
+		// The classic confusion: an unsigned token claiming it needs no
+		// signature.
+		"none": encodeSegment(t, map[string]string{"alg": "none", "typ": "JWT"}) + "." + payload + ".",
+		// A header naming another algorithm, even with a valid HS256
+		// signature over it, must not be accepted.
+		"HS512": func() string {
+			unsigned := encodeSegment(t, map[string]string{"alg": "HS512", "typ": "JWT"}) + "." + payload
+			return unsigned + "." + signature(testSecret, unsigned)
+		}(),
+		"RS256": func() string {
+			unsigned := encodeSegment(t, map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + payload
+			return unsigned + "." + signature(testSecret, unsigned)
+		}(),
+		// The same header with its fields reordered encodes differently;
+		// only the exact header Sign writes is accepted.
+		"reordered header": func() string {
+			unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"HS256"}`)) + "." + payload
+			return unsigned + "." + signature(testSecret, unsigned)
+		}(),
+	}
+	for name, token := range tests {
+		if _, err := Verify(testSecret, token, testNow); err != ErrInvalidToken {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+func TestVerifyRejectsExpiredToken(t *testing.T) {
+	c := testClaims()
+	token := mustSign(t, testSecret, c)
+	expiry := time.Unix(c.ExpiresAt, 0)
+
+	if _, err := Verify(testSecret, token, expiry.Add(-time.Second)); err != nil {
+		t.Errorf("a second before expiry: err = %v, want nil", err)
+	}
+	for _, now := range []time.Time{expiry, expiry.Add(time.Second), expiry.Add(24 * time.Hour)} {
+		if _, err := Verify(testSecret, token, now); err != ErrInvalidToken {
+			t.Errorf("%s after expiry: err = %v, want ErrInvalidToken", now.Sub(expiry), err)
+		}
+	}
+}
+
+func TestVerifyRejectsForeignClaims(t *testing.T) {
+	wrongIssuer := testClaims()
+	wrongIssuer.Issuer = "someone-else"
+	noUser := testClaims()
+	noUser.UserID = 0
+
+	for name, c := range map[string]Claims{"issuer": wrongIssuer, "user id": noUser} {
+		if _, err := Verify(testSecret, mustSign(t, testSecret, c), testNow); err != ErrInvalidToken {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Package brands keeps the canonical brand list. Each brand has a display
+// name, a URL slug and any number of aliases; products are tied to a brand
+// by the key of their brand column, so "Hermès", "HERMES" and "Hermes" all
+// belong to one brand in every category.
+package brands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"plutus-backend/catalog"
+)
+
+// Brand is a canonical brand.
+type Brand struct {
+	ID   int
+	Slug string
+	Name string
+	// Logo, Description and Country are editorial details; empty when
+	// nobody has filled them in.
+	Logo        string
+	Description string
+	Country     string
+	// Aliases are the spellings that resolve to the brand, keyed as by Key.
+	Aliases []string
+	// Counts is the number of products per category key.
+	Counts map[string]int
+}
+
+// ProductCount is the number of products the brand has in all categories.
+func (b *Brand) ProductCount() int {
+	n := 0
+	for _, c := range b.Counts {
+		n += c
+	}
+	return n
+}
+
+// Details are a brand's editorial fields. Nil fields are left unchanged by
+// SetDetails.
+type Details struct {
+	Name        *string
+	Logo        *string
+	Description *string
+	Country     *string
+}
+
+// accentsFrom and accentsTo fold accented letters to ASCII for Key. The
+// brand_key SQL function (see migration 0007_brands) folds the same
+// letters, and the two must agree: changing them takes a migration that
+// redefines brand_key and rebuilds its indexes.
+const (
+	accentsFrom = "ÀÁÂÃÄÅàáâãäåÇçÈÉÊËèéêëÌÍÎÏìíîïÑñÒÓÔÕÖØòóôõöøÙÚÛÜùúûüÝýÿ"
+	accentsTo   = "AAAAAAaaaaaaCcEEEEeeeeIIIIiiiiNnOOOOOOooooooUUUUuuuuYyy"
+)
+
+var accentFolder = func() *strings.Replacer {
+	from, to := []rune(accentsFrom), []rune(accentsTo)
+	pairs := make([]string, 0, 2*len(from))
+	for i := range from {
+		pairs = append(pairs, string(from[i]), string(to[i]))
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// Key normalizes a brand spelling for matching: accents folded, lower case,
+// "&" spelled "and", and everything but letters and digits collapsed to
+// single spaces. "A. Lange & Söhne" becomes "a lange and sohne".
+func Key(name string) string {
+	s := strings.ToLower(accentFolder.Replace(name))
+	s = strings.ReplaceAll(s, "&", " and ")
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+	}), " ")
+}
+
+// Slug is the URL form of a brand key.
+func Slug(name string) string {
+	return strings.ReplaceAll(Key(name), " ", "-")
+}
+
+// Sync creates a brand for every brand key used by products that no alias
+// covers yet. The brand is named after its most common spelling. It returns
+// the number of brand keys it registered.
+func Sync(ctx context.Context, db *sql.DB) (int, error) {
+	cats := catalog.Categories()
+	selects := make([]string, len(cats))
+	for i, c := range cats {
+		selects[i] = "SELECT brand FROM " + c.Table
+	}
+	rows, err := db.QueryContext(ctx, "SELECT TRIM(brand), COUNT(*) FROM ("+strings.Join(selects, " UNION ALL ")+") p"+
+		" WHERE brand_key(brand) <> '' AND NOT EXISTS (SELECT 1 FROM brand_aliases a WHERE a.alias_key = brand_key(p.brand))"+
+		" GROUP BY 1")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	spellings := map[string][]spelling{}
+	for rows.Next() {
+		var s spelling
+		if err := rows.Scan(&s.name, &s.count); err != nil {
+			return 0, err
+		}
+		key := Key(s.name)
+		spellings[key] = append(spellings[key], s)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+	if len(spellings) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, 0, len(spellings))
+	for key := range spellings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for _, key := range keys {
+		name := preferredSpelling(spellings[key])
+		var id int
+		err := tx.QueryRowContext(ctx,
+			"INSERT INTO brands (slug, name) VALUES ($1, $2) ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug RETURNING id",
+			strings.ReplaceAll(key, " ", "-"), name).Scan(&id)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO brand_aliases (alias_key, alias, brand_id) VALUES ($1, $2, $3) ON CONFLICT (alias_key) DO NOTHING",
+			key, name, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), tx.Commit()
+}
+
+type spelling struct {
+	name  string
+	count int
+}
+
+// preferredSpelling picks a brand's display name: the most common spelling,
+// then one that keeps its accents, then one that isn't shouted in capitals.
+func preferredSpelling(spellings []spelling) string {
+	sort.Slice(spellings, func(i, j int) bool {
+		a, b := spellings[i], spellings[j]
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		if accented(a.name) != accented(b.name) {
+			return accented(a.name)
+		}
+		if shouted(a.name) != shouted(b.name) {
+			return !shouted(a.name)
+		}
+		return a.name < b.name
+	})
+	return spellings[0].name
+}
+
+func accented(s string) bool {
+	return strings.ContainsAny(s, accentsFrom)
+}
+
+func shouted(s string) bool {
+	return strings.ToUpper(s) == s && strings.IndexFunc(s, unicode.IsLetter) >= 0
+}
+
+// AddAlias makes alias resolve to the brand with the given slug. Products
+// spelled like the alias move to that brand; a brand that only existed for
+// the alias is left without products and is removed.
+func AddAlias(ctx context.Context, db *sql.DB, slug, alias string) error {
+	key := Key(alias)
+	if key == "" {
+		return fmt.Errorf("alias %q has no letters or digits", alias)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var id int
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM brands WHERE slug = $1", slug).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("no brand with slug %q", slug)
+		}
+		return err
+	}
+	var previous sql.NullInt64
+	if err := tx.QueryRowContext(ctx, "SELECT brand_id FROM brand_aliases WHERE alias_key = $1", key).Scan(&previous); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO brand_aliases (alias_key, alias, brand_id) VALUES ($1, $2, $3)"+
+			" ON CONFLICT (alias_key) DO UPDATE SET alias = EXCLUDED.alias, brand_id = EXCLUDED.brand_id",
+		key, alias, id); err != nil {
+		return err
+	}
+	if previous.Valid && int(previous.Int64) != id {
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM brands b WHERE b.id = $1 AND NOT EXISTS (SELECT 1 FROM brand_aliases a WHERE a.brand_id = b.id)",
+			previous.Int64); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetDetails updates the editorial fields of the brand with the given slug.
+func SetDetails(ctx context.Context, db *sql.DB, slug string, d Details) error {
+	res, err := db.ExecContext(ctx,
+		"UPDATE brands SET name = COALESCE($2, name), logo_url = COALESCE($3, logo_url),"+
+			" description = COALESCE($4, description), country = COALESCE($5, country) WHERE slug = $1",
+		slug, d.Name, d.Logo, d.Description, d.Country)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no brand with slug %q", slug)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package brands
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"plutus-backend/catalog"
+)
+
+// Resolver maps brand spellings to canonical brands from an in-memory copy of
+// the brands tables. Load replaces the copy wholesale, so lookups never wait
+// on a reload.
+type Resolver struct {
+	DB    *sql.DB
+	state atomic.Pointer[resolverState]
+}
+
+type resolverState struct {
+	byKey  map[string]*Brand
+	bySlug map[string]*Brand
+	all    []*Brand
+}
+
+// NewResolver returns a Resolver over db that knows no brands until Load.
+func NewResolver(db *sql.DB) *Resolver {
+	r := &Resolver{DB: db}
+	r.state.Store(&resolverState{byKey: map[string]*Brand{}, bySlug: map[string]*Brand{}})
+	return r
+}
+
+// Load reads every brand, its aliases and its product counts.
+func (r *Resolver) Load(ctx context.Context) error {
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT b.id, b.slug, b.name, COALESCE(b.logo_url, ''), COALESCE(b.description, ''), COALESCE(b.country, ''), a.alias_key"+
+			" FROM brands b LEFT JOIN brand_aliases a ON a.brand_id = b.id ORDER BY b.id, a.alias_key")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	st := &resolverState{byKey: map[string]*Brand{}, bySlug: map[string]*Brand{}}
+	byID := map[int]*Brand{}
+	for rows.Next() {
+		b := Brand{Counts: map[string]int{}}
+		var alias sql.NullString
+		if err := rows.Scan(&b.ID, &b.Slug, &b.Name, &b.Logo, &b.Description, &b.Country, &alias); err != nil {
+			return err
+		}
+		brand, ok := byID[b.ID]
+		if !ok {
+			brand = &b
+			byID[b.ID] = brand
+			st.bySlug[b.Slug] = brand
+			st.all = append(st.all, brand)
+		}
+		if alias.Valid {
+			brand.Aliases = append(brand.Aliases, alias.String)
+			st.byKey[alias.String] = brand
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	cats := catalog.Categories()
+	selects := make([]string, len(cats))
+	for i, c := range cats {
+		selects[i] = "SELECT '" + c.Key + "' AS category, brand FROM " + c.Table + " WHERE " + catalog.Listed
+	}
+	rows, err = r.DB.QueryContext(ctx, "SELECT a.brand_id, p.category, COUNT(*) FROM ("+strings.Join(selects, " UNION ALL ")+") p"+
+		" JOIN brand_aliases a ON a.alias_key = brand_key(p.brand) GROUP BY 1, 2")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, n int
+		var category string
+		if err := rows.Scan(&id, &category, &n); err != nil {
+			return err
+		}
+		if b := byID[id]; b != nil {
+			b.Counts[category] = n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	sort.SliceStable(st.all, func(i, j int) bool { return Key(st.all[i].Name) < Key(st.all[j].Name) })
+	r.state.Store(st)
+	return nil
+}
+
+// Resolve returns the brand that name is an alias of, or nil.
+func (r *Resolver) Resolve(name string) *Brand {
+	return r.state.Load().byKey[Key(name)]
+}
+
+// BySlug returns the brand with the given slug, or nil.
+func (r *Resolver) BySlug(slug string) *Brand {
+	return r.state.Load().bySlug[slug]
+}
+
+// All returns every brand ordered by name.
+func (r *Resolver) All() []*Brand {
+	return r.state.Load().all
+}
+
+// InCategory returns the brands with products in the category with the
+// given key, ordered by name. An empty key means any category.
+func (r *Resolver) InCategory(key string) []*Brand {
+	var out []*Brand
+	for _, b := range r.All() {
+		n := b.Counts[key]
+		if key == "" {
+			n = b.ProductCount()
+		}
+		if n > 0 {
+			out = append(out, b)
+		}
+	}
+	return out
+}
@@ -0,0 +1,31 @@
+package catalog
+
+import (
+	"strings"
+
+	"plutus-backend/querybuilder"
+)
+
+// Brands are matched through the brands and brand_aliases tables (see
+// package brands) on brand_key(brand), so every spelling of a brand, in any
+// category, resolves to the same canonical brand.
+const (
+	// CanonicalBrandExpr yields a product's canonical brand name, or its
+	// trimmed brand when the spelling isn't known yet.
+	CanonicalBrandExpr = "COALESCE((SELECT cb.name FROM brand_aliases ca JOIN brands cb ON cb.id = ca.brand_id" +
+		" WHERE ca.alias_key = brand_key(brand)), TRIM(brand))"
+
+	// brandMatch matches products of the brand that $ resolves to, or with
+	// the same brand key when $ isn't a known alias. $ stands for the bound
+	// value; see whereBrand.
+	brandMatch = "(brand_key(brand) IN (SELECT a.alias_key FROM brand_aliases a JOIN brand_aliases q ON q.brand_id = a.brand_id" +
+		" WHERE q.alias_key = brand_key($)) OR brand_key(brand) = brand_key($))"
+
+	// brandSlugMatch matches products of the brand with slug $.
+	brandSlugMatch = "brand_key(brand) IN (SELECT a.alias_key FROM brand_aliases a JOIN brands b ON b.id = a.brand_id WHERE b.slug = $)"
+)
+
+// whereBrand adds the condition for a "brand" or "brandSlug" filter.
+func whereBrand(qb *querybuilder.Builder, match, value string) {
+	qb.Where(strings.ReplaceAll(match, "$", qb.Arg(value)))
+}
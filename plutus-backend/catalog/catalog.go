@@ -0,0 +1,230 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"plutus-backend/graph/model"
+	"plutus-backend/querybuilder"
+)
+
+// Catalog bundles the repositories for every product category over one
+// database handle.
+type Catalog struct {
+	DB          *sql.DB
+	Sneakers    *Repository[model.Sneaker]
+	Watches     *Repository[model.Watch]
+	Perfumes    *Repository[model.Perfume]
+	Accessories *Repository[model.Accessory]
+	Apparel     *Repository[model.Apparel]
+}
+
+// New returns a Catalog reading from db.
+func New(db *sql.DB) *Catalog {
+	return &Catalog{
+		DB:          db,
+		Sneakers:    &Repository[model.Sneaker]{DB: db, Category: Sneakers, Scan: scanSneaker},
+		Watches:     &Repository[model.Watch]{DB: db, Category: Watches, Scan: scanWatch},
+		Perfumes:    &Repository[model.Perfume]{DB: db, Category: Perfumes, Scan: scanPerfume},
+		Accessories: &Repository[model.Accessory]{DB: db, Category: Accessories, Scan: scanAccessory},
+		Apparel:     &Repository[model.Apparel]{DB: db, Category: Apparel, Scan: scanApparel},
+	}
+}
+
+// Products loads products of one category by id, keyed by id, as the
+// category's model type.
+func (c *Catalog) Products(ctx context.Context, category string, ids []string) (map[string]model.Product, error) {
+	switch category {
+	case Sneakers.Key:
+		return products(ctx, c.Sneakers, ids)
+	case Watches.Key:
+		return products(ctx, c.Watches, ids)
+	case Perfumes.Key:
+		return products(ctx, c.Perfumes, ids)
+	case Accessories.Key:
+		return products(ctx, c.Accessories, ids)
+	case Apparel.Key:
+		return products(ctx, c.Apparel, ids)
+	}
+	return nil, fmt.Errorf("unknown category %q", category)
+}
+
+func products[T any, P interface {
+	*T
+	model.Product
+}](ctx context.Context, r *Repository[T], ids []string) (map[string]model.Product, error) {
+	items, err := r.ByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]model.Product, len(items))
+	for id, item := range items {
+		out[id] = P(item)
+	}
+	return out, nil
+}
+
+// ProductsByLink loads products of one category by their source page link,
+// keyed by link. Links, unlike ids, survive a re-seed, so tables that refer
+// to products store the link. When two products share a link the first one
+// listed wins.
+func (c *Catalog) ProductsByLink(ctx context.Context, category string, links []string) (map[string]model.Product, error) {
+	cat, ok := Lookup(category)
+	if !ok {
+		return nil, fmt.Errorf("unknown category %q", category)
+	}
+	link := "NULLIF(TRIM(" + cat.LinkColumn + "), '')"
+	rows, err := c.DB.QueryContext(ctx, "SELECT DISTINCT ON ("+link+") id::text, "+link+" FROM "+cat.Table+
+		" WHERE "+Listed+" AND "+link+" = ANY($1) ORDER BY "+link+", id", pq.Array(links))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	linkOf := map[string]string{}
+	for rows.Next() {
+		var id, l string
+		if err := rows.Scan(&id, &l); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		linkOf[id] = l
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	found, err := c.Products(ctx, category, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]model.Product, len(found))
+	for id, p := range found {
+		out[linkOf[id]] = p
+	}
+	return out, nil
+}
+
+// ProductID returns a product's id.
+func ProductID(p model.Product) string {
+	switch p := p.(type) {
+	case *model.Sneaker:
+		return p.ID
+	case *model.Watch:
+		return p.ID
+	case *model.Perfume:
+		return p.ID
+	case *model.Accessory:
+		return p.ID
+	case *model.Apparel:
+		return p.ID
+	}
+	return ""
+}
+
+// ProductLink returns a product's source page URL, which ProductsByLink
+// finds it by.
+func ProductLink(p model.Product) string {
+	switch p := p.(type) {
+	case *model.Sneaker:
+		return strings.TrimSpace(p.ProductLink)
+	case *model.Watch:
+		return strings.TrimSpace(p.Link)
+	case *model.Perfume:
+		return strings.TrimSpace(p.URL)
+	case *model.Accessory:
+		return strings.TrimSpace(p.ProductLink)
+	case *model.Apparel:
+		return strings.TrimSpace(p.ProductLink)
+	}
+	return ""
+}
+
+// Brands returns the canonical names of the brands in a category, sorted,
+// with each brand listed once however many spellings it has.
+func (c *Catalog) Brands(ctx context.Context, cat *Category) ([]string, error) {
+	rows, err := c.DB.QueryContext(ctx, "SELECT DISTINCT "+CanonicalBrandExpr+" AS name FROM "+cat.Table+
+		" WHERE "+Listed+" AND NULLIF(TRIM(brand), '') IS NOT NULL ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// Distinct returns the distinct non-null values of column among a
+// category's listed products. Categories without the column (sneakers have
+// no gender, for one) have no values rather than an error, so the all*
+// queries can be asked of every category alike.
+func (c *Catalog) Distinct(ctx context.Context, cat *Category, column string) ([]string, error) {
+	if !slices.Contains(cat.Columns, column) {
+		return []string{}, nil
+	}
+	rows, err := c.DB.QueryContext(ctx, "SELECT DISTINCT "+column+" FROM "+cat.Table+" WHERE "+Listed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value sql.NullString
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		if value.Valid {
+			values = append(values, value.String)
+		}
+	}
+	return values, rows.Err()
+}
+
+// Sizes returns the sorted distinct sizes found in a category's size_prices,
+// optionally restricted to one brand. Rows with malformed JSON are skipped.
+func (c *Catalog) Sizes(ctx context.Context, cat *Category, brand *string) ([]string, error) {
+	qb := querybuilder.New("SELECT size_prices FROM " + cat.Table).Where(Listed)
+	if brand != nil && *brand != "" {
+		whereBrand(qb, brandMatch, *brand)
+	}
+	query, args := qb.Build()
+	rows, err := c.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sizeSet := make(map[string]struct{})
+	for rows.Next() {
+		var sizePricesRaw []byte
+		if err := rows.Scan(&sizePricesRaw); err != nil {
+			return nil, err
+		}
+		sizePrices, err := decodeSizePrices(sizePricesRaw)
+		if err != nil {
+			continue // skip bad rows
+		}
+		for _, sp := range sizePrices {
+			if sp != nil && sp.Size != "" {
+				sizeSet[sp.Size] = struct{}{}
+			}
+		}
+	}
+	sizes := make([]string, 0, len(sizeSet))
+	for s := range sizeSet {
+		sizes = append(sizes, s)
+	}
+	sort.Strings(sizes)
+	return sizes, rows.Err()
+}
@@ -0,0 +1,225 @@
+package catalog
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"plutus-backend/graph/model"
+)
+
+// The registered categories. Adding one (handbags, jewellery, ...) means
+// registering it here, writing a scanner for its model type, wiring a
+// Repository into New and adding a migration that creates its table with
+// the price, search, brand and change triggers and indexes the others have
+// (see package migrations).
+var (
+	Sneakers = Register(&Category{
+		Key:          "sneakers",
+		Table:        "sneakers",
+		Columns:      []string{"id", "brand", "product_name", "size_prices", "images", "sold_out", "product_link", "seller_name", "seller_url"},
+		NameColumn:   "product_name",
+		LinkColumn:   "product_link",
+		PagePath:     "/sneaker/",
+		PriceExpr:    "min_price",
+		MaxPriceExpr: "max_price",
+		PricesColumn: "size_prices",
+		Filters: map[string]Filter{
+			"size": {Column: "size_prices", Match: MatchSize},
+		},
+		Facets: []Facet{brandFacet, sizeFacet},
+	})
+
+	Watches = Register(&Category{
+		Key:        "watches",
+		Table:      "watches",
+		Columns:    []string{"id", "brand", "name", "color", "sale_price", "market_price", "images", "link", "seller_name", "seller_url", "gender"},
+		NameColumn: "name",
+		LinkColumn: "link",
+		PagePath:   "/watch/",
+		PriceExpr:  "sale_price",
+		// Watches are listed by a Dubai seller and priced in dirhams.
+		Currency: "AED",
+		// market_price is free text such as "AED6,500.00" or "N/A".
+		DiscountExpr: "(SELECT 1 - sale_price / NULLIF(m::float8, 0) FROM (SELECT regexp_replace(market_price, '[^0-9.]', '', 'g') AS m) mp" +
+			" WHERE m ~ '^[0-9]+(\\.[0-9]+)?$')",
+		Filters: map[string]Filter{
+			"color":  {Column: "color", Match: MatchContains},
+			"gender": {Column: "gender", Match: MatchContains},
+		},
+		Facets: []Facet{
+			brandFacet,
+			{Filter: "color", Column: "color"},
+			{Filter: "gender", Column: "gender"},
+		},
+	})
+
+	Perfumes = Register(&Category{
+		Key:          "perfumes",
+		Table:        "perfumes",
+		Columns:      []string{"id", "brand", "title", "fragrance_family", "concentration", "subcategory", "variants", "images", "url", "seller_name", "seller_url"},
+		NameColumn:   "title",
+		LinkColumn:   "url",
+		PagePath:     "/perfume/",
+		PriceExpr:    "min_price",
+		MaxPriceExpr: "max_price",
+		PricesColumn: "variants",
+		Filters: map[string]Filter{
+			"fragranceFamily": {Column: "fragrance_family", Match: MatchContains},
+			"subcategory":     {Column: "subcategory", Match: MatchContains},
+			"concentration":   {Column: "concentration", Match: MatchContains},
+			"size":            {Column: "variants", Match: MatchSize},
+		},
+		Facets: []Facet{
+			brandFacet,
+			{Filter: "fragranceFamily", Column: "fragrance_family"},
+			{Filter: "concentration", Column: "concentration"},
+			{Filter: "subcategory", Column: "subcategory"},
+			{Filter: "size", Column: "fv->>'size'", Elements: "jsonb_array_elements(variants)"},
+		},
+	})
+
+	Accessories = Register(&Category{
+		Key:          "accessories",
+		Table:        "accessories",
+		Columns:      sizedColumns,
+		NameColumn:   "product_name",
+		LinkColumn:   "product_link",
+		PagePath:     "/accessories/",
+		PriceExpr:    "min_price",
+		MaxPriceExpr: "max_price",
+		PricesColumn: "size_prices",
+		Filters:      sizedFilters,
+		Facets:       sizedFacets,
+	})
+
+	Apparel = Register(&Category{
+		Key:          "apparel",
+		Table:        "apparel",
+		Columns:      sizedColumns,
+		NameColumn:   "product_name",
+		LinkColumn:   "product_link",
+		PagePath:     "/apparel/",
+		PriceExpr:    "min_price",
+		MaxPriceExpr: "max_price",
+		PricesColumn: "size_prices",
+		Filters:      sizedFilters,
+		Facets:       sizedFacets,
+	})
+)
+
+// sizedColumns, sizedFilters and sizedFacets are shared by the
+// accessories and apparel tables, which have identical layouts.
+var (
+	sizedColumns = []string{"id", "brand", "product_name", "subcategory", "gender", "size_prices", "images", "in_stock", "product_link", "seller_name", "seller_url"}
+	sizedFilters = map[string]Filter{
+		"subcategory": {Column: "subcategory", Match: MatchContains},
+		"gender":      {Column: "gender", Match: MatchContains},
+		"size":        {Column: "size_prices", Match: MatchSize},
+	}
+	sizedFacets = []Facet{
+		brandFacet,
+		{Filter: "subcategory", Column: "subcategory"},
+		{Filter: "gender", Column: "gender"},
+		sizeFacet,
+	}
+)
+
+var (
+	brandFacet = Facet{Filter: "brand", Column: CanonicalBrandExpr}
+	sizeFacet  = Facet{Filter: "size", Column: "fv->>'size'", Elements: "jsonb_array_elements(size_prices)"}
+)
+
+func scanSneaker(s Scanner) (*model.Sneaker, error) {
+	var p model.Sneaker
+	var sizePricesRaw []byte
+	if err := s.Scan(&p.ID, &p.Brand, &p.ProductName, &sizePricesRaw, pq.Array(&p.Images), &p.SoldOut, &p.ProductLink, &p.SellerName, &p.SellerURL); err != nil {
+		return nil, err
+	}
+	sizePrices, err := decodeSizePrices(sizePricesRaw)
+	if err != nil {
+		return nil, err
+	}
+	p.SizePrices = sizePrices
+	return &p, nil
+}
+
+func scanWatch(s Scanner) (*model.Watch, error) {
+	var w model.Watch
+	if err := s.Scan(&w.ID, &w.Brand, &w.Name, &w.Color, &w.SalePrice, &w.MarketPrice, pq.Array(&w.Images), &w.Link, &w.SellerName, &w.SellerURL, &w.Gender); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func scanPerfume(s Scanner) (*model.Perfume, error) {
+	var p model.Perfume
+	var variantsRaw []byte
+	if err := s.Scan(&p.ID, &p.Brand, &p.Title, &p.FragranceFamily, &p.Concentration, &p.Subcategory, &variantsRaw, pq.Array(&p.Images), &p.URL, &p.SellerName, &p.SellerURL); err != nil {
+		return nil, err
+	}
+	if p.Concentration == nil || *p.Concentration == "" {
+		concentration := inferConcentration(p.Title)
+		p.Concentration = &concentration
+	}
+	if err := json.Unmarshal(variantsRaw, &p.Variants); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func scanAccessory(s Scanner) (*model.Accessory, error) {
+	var a model.Accessory
+	var sizePricesRaw []byte
+	if err := s.Scan(&a.ID, &a.Brand, &a.ProductName, &a.Subcategory, &a.Gender, &sizePricesRaw, pq.Array(&a.Images), &a.InStock, &a.ProductLink, &a.SellerName, &a.SellerURL); err != nil {
+		return nil, err
+	}
+	sizePrices, err := decodeSizePrices(sizePricesRaw)
+	if err != nil {
+		return nil, err
+	}
+	a.SizePrices = sizePrices
+	return &a, nil
+}
+
+func scanApparel(s Scanner) (*model.Apparel, error) {
+	var a model.Apparel
+	var sizePricesRaw []byte
+	if err := s.Scan(&a.ID, &a.Brand, &a.ProductName, &a.Subcategory, &a.Gender, &sizePricesRaw, pq.Array(&a.Images), &a.InStock, &a.ProductLink, &a.SellerName, &a.SellerURL); err != nil {
+		return nil, err
+	}
+	sizePrices, err := decodeSizePrices(sizePricesRaw)
+	if err != nil {
+		return nil, err
+	}
+	a.SizePrices = sizePrices
+	return &a, nil
+}
+
+// decodeSizePrices unmarshals a size_prices JSONB value.
+func decodeSizePrices(raw []byte) ([]*model.SizePrice, error) {
+	var sizePrices []*model.SizePrice
+	if err := json.Unmarshal(raw, &sizePrices); err != nil {
+		return nil, err
+	}
+	return sizePrices, nil
+}
+
+// inferConcentration guesses a perfume's concentration from its title when
+// the column is empty.
+func inferConcentration(title string) string {
+	title = strings.ToLower(title)
+	switch {
+	case strings.Contains(title, "eau de parfum") || strings.Contains(title, "edp"):
+		return "EDP"
+	case strings.Contains(title, "eau de toilette") || strings.Contains(title, "edt"):
+		return "EDT"
+	case strings.Contains(title, "parfum"):
+		return "Parfum"
+	case strings.Contains(title, "cologne"):
+		return "Cologne"
+	default:
+		return "EDT" // Default fallback
+	}
+}
@@ -0,0 +1,127 @@
+// Package catalog maps the product tables onto the GraphQL models. Each
+// product table is described once by a Category in the registry, and a
+// generic Repository handles filtering, scanning and pagination for it.
+package catalog
+
+import (
+	"fmt"
+	"strings"
+
+	"plutus-backend/currency"
+)
+
+// MatchKind controls how a filter value is compared against its column.
+type MatchKind int
+
+const (
+	// MatchContains is a case-insensitive substring match (col ILIKE %v%).
+	MatchContains MatchKind = iota
+	// MatchSize is an exact match against the "size" of any element of a
+	// JSONB array column such as size_prices or variants, the values the
+	// size facet counts.
+	MatchSize
+)
+
+// Filter is one filterable field of a category.
+type Filter struct {
+	Column string
+	Match  MatchKind
+}
+
+// Category describes a product table: where it lives, which columns are
+// read, and which fields can be filtered and sorted.
+type Category struct {
+	// Key is the stable category name used by the REST API ("sneakers").
+	Key string
+	// Table is the Postgres table holding the products.
+	Table string
+	// Columns are selected in this order and handed to the scanner.
+	Columns []string
+	// NameColumn holds the product's display name.
+	NameColumn string
+	// LinkColumn holds the product's source page URL.
+	LinkColumn string
+	// PagePath is the storefront route of the category's product pages; a
+	// product's page is PagePath followed by its id.
+	PagePath string
+	// PriceExpr is a SQL expression yielding the product's starting price,
+	// used for price sorting, maxPrice and the price facet. It must be
+	// indexable, so it is a plain column rather than a JSONB subquery.
+	PriceExpr string
+	// MaxPriceExpr yields the product's highest price. minPrice is matched
+	// against it, so a product is kept when its price range overlaps the
+	// requested one. It defaults to PriceExpr.
+	MaxPriceExpr string
+	// PricesColumn is the JSONB array of priced sizes or variants that the
+	// min_price/max_price columns are derived from. Categories priced by a
+	// plain column leave it empty.
+	PricesColumn string
+	// Currency is the ISO 4217 code prices are stored in. It defaults to
+	// currency.Base.
+	Currency string
+	// DiscountExpr yields the fractional discount off a reference price.
+	// Categories without one can't be sorted by discount.
+	DiscountExpr string
+	// Filters are keyed by the GraphQL argument name.
+	Filters map[string]Filter
+	// Facets are counted for the filter sidebars.
+	Facets []Facet
+}
+
+var (
+	registry = map[string]*Category{}
+	order    []*Category
+)
+
+// Register adds a category to the registry. It panics on duplicate keys, as
+// categories are registered from package init.
+func Register(c *Category) *Category {
+	if _, exists := registry[c.Key]; exists {
+		panic(fmt.Sprintf("catalog: category %q registered twice", c.Key))
+	}
+	registry[c.Key] = c
+	order = append(order, c)
+	return c
+}
+
+// PriceCurrency is the currency the category's prices are stored in.
+func (c *Category) PriceCurrency() string {
+	if c.Currency != "" {
+		return c.Currency
+	}
+	return currency.Base
+}
+
+func (c *Category) maxPriceExpr() string {
+	if c.MaxPriceExpr != "" {
+		return c.MaxPriceExpr
+	}
+	return c.PriceExpr
+}
+
+// Lookup returns the category registered under key.
+func Lookup(key string) (*Category, bool) {
+	c, ok := registry[key]
+	return c, ok
+}
+
+// Find looks a category up by key or by the storefront's name for its
+// products, the path segment of its product pages, ignoring case: both
+// "watches" and "watch" find Watches.
+func Find(name string) (*Category, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if c, ok := registry[name]; ok {
+		return c, true
+	}
+	for _, c := range order {
+		if c.PagePath != "" && strings.Trim(c.PagePath, "/") == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Categories returns every registered category in registration order.
+func Categories() []*Category {
+	return append([]*Category(nil), order...)
+}
@@ -0,0 +1,157 @@
+package catalog
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"plutus-backend/graph/model"
+	"plutus-backend/querybuilder"
+)
+
+// Facet is a filterable field whose values are counted for the filter
+// sidebars.
+type Facet struct {
+	// Filter is the filter argument this facet narrows. Its own value is
+	// ignored while counting, so every option stays selectable.
+	Filter string
+	// Column is the SQL expression yielding the facet value.
+	Column string
+	// Elements, when set, is a set-returning function expanding each row
+	// into several values, aliased "fv" for Column to read from.
+	Elements string
+}
+
+// PriceBucketBounds are the lower bounds of the price facet's buckets in
+// the base currency; the last bucket is open-ended.
+var PriceBucketBounds = []float64{5000, 10000, 25000, 50000, 100000}
+
+// Facets counts each of the category's facet values, and its price buckets,
+// among the products matching every other filter in p. bounds are the
+// buckets' lower bounds in the category's currency.
+func (r *Repository[T]) Facets(ctx context.Context, p ListParams, bounds []float64) (*model.Facets, error) {
+	facets := &model.Facets{
+		Brands:            []*model.FacetValue{},
+		Subcategories:     []*model.FacetValue{},
+		Genders:           []*model.FacetValue{},
+		Sizes:             []*model.FacetValue{},
+		Colors:            []*model.FacetValue{},
+		Concentrations:    []*model.FacetValue{},
+		FragranceFamilies: []*model.FacetValue{},
+		PriceBuckets:      []*model.PriceBucket{},
+	}
+	for _, f := range r.Category.Facets {
+		values, err := r.facetValues(ctx, f, p)
+		if err != nil {
+			return nil, err
+		}
+		switch f.Filter {
+		case "brand":
+			facets.Brands = values
+		case "subcategory":
+			facets.Subcategories = values
+		case "gender":
+			facets.Genders = values
+		case "size":
+			facets.Sizes = values
+		case "color":
+			facets.Colors = values
+		case "concentration":
+			facets.Concentrations = values
+		case "fragranceFamily":
+			facets.FragranceFamilies = values
+		}
+	}
+	if r.Category.PriceExpr != "" {
+		buckets, err := r.priceBuckets(ctx, p, bounds)
+		if err != nil {
+			return nil, err
+		}
+		facets.PriceBuckets = buckets
+	}
+	return facets, nil
+}
+
+func (r *Repository[T]) facetValues(ctx context.Context, f Facet, p ListParams) ([]*model.FacetValue, error) {
+	from := r.Category.Table
+	if f.Elements != "" {
+		from += " CROSS JOIN LATERAL " + f.Elements + " fv"
+	}
+	qb := querybuilder.New("SELECT " + f.Column + ", COUNT(DISTINCT id) FROM " + from)
+	if err := r.Category.applyFilters(qb, p.without(f.Filter)); err != nil {
+		return nil, err
+	}
+	qb.Where("NULLIF(TRIM(" + f.Column + "), '') IS NOT NULL")
+	qb.GroupBy("1").OrderBy("2 DESC").OrderBy("1")
+	query, args := qb.Build()
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := []*model.FacetValue{}
+	for rows.Next() {
+		var v model.FacetValue
+		if err := rows.Scan(&v.Value, &v.Count); err != nil {
+			return nil, err
+		}
+		values = append(values, &v)
+	}
+	return values, rows.Err()
+}
+
+func (r *Repository[T]) priceBuckets(ctx context.Context, p ListParams, bounds []float64) ([]*model.PriceBucket, error) {
+	p.MinPrice, p.MaxPrice = nil, nil
+	price := "(" + r.Category.PriceExpr + ")::float8"
+	literals := make([]string, len(bounds))
+	for i, b := range bounds {
+		literals[i] = strconv.FormatFloat(b, 'f', -1, 64)
+	}
+	qb := querybuilder.New("SELECT width_bucket(" + price + ", ARRAY[" + strings.Join(literals, ", ") + "]::float8[]), COUNT(*) FROM " + r.Category.Table)
+	qb.Where(price + " IS NOT NULL")
+	if err := r.Category.applyFilters(qb, p); err != nil {
+		return nil, err
+	}
+	qb.GroupBy("1").OrderBy("1")
+	query, args := qb.Build()
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	buckets := []*model.PriceBucket{}
+	for rows.Next() {
+		var bucket int
+		var b model.PriceBucket
+		if err := rows.Scan(&bucket, &b.Count); err != nil {
+			return nil, err
+		}
+		// width_bucket numbers buckets from 0 (below the first bound) to
+		// len(bounds) (at or above the last).
+		if bucket > 0 {
+			min := bounds[bucket-1]
+			b.Min = &min
+		}
+		if bucket < len(bounds) {
+			max := bounds[bucket]
+			b.Max = &max
+		}
+		buckets = append(buckets, &b)
+	}
+	return buckets, rows.Err()
+}
+
+// without returns a copy of p that ignores the named filter. brand and
+// brandSlug narrow the same field, so dropping brand drops both.
+func (p ListParams) without(filter string) ListParams {
+	filters := make(Filters, len(p.Filters))
+	for name, value := range p.Filters {
+		if name != filter && !(filter == "brand" && name == "brandSlug") {
+			filters[name] = value
+		}
+	}
+	p.Filters = filters
+	return p
+}
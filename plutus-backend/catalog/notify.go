@@ -0,0 +1,38 @@
+package catalog
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WatchChanges listens on ChangesChannel and calls onChange once writes to
+// the product tables have been quiet for the given duration, so a bulk load
+// causes a single call rather than one per statement. onChange is also
+// called after the listener reconnects, as notifications may have been
+// missed meanwhile. It blocks until ctx is done.
+func WatchChanges(ctx context.Context, dbURL string, quiet time.Duration, onChange func()) error {
+	listener := pq.NewListener(dbURL, 10*time.Second, time.Minute, nil)
+	defer listener.Close()
+	if err := listener.Listen(ChangesChannel); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(quiet)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-listener.Notify:
+			// A nil notification means the connection was re-established.
+			timer.Reset(quiet)
+		case <-timer.C:
+			onChange()
+		case <-time.After(90 * time.Second):
+			// Detect a dead connection even when nothing is being written.
+			go listener.Ping()
+		}
+	}
+}
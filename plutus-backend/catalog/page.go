@@ -0,0 +1,175 @@
+package catalog
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"plutus-backend/querybuilder"
+)
+
+const (
+	// DefaultPageSize is used when a connection query omits first.
+	DefaultPageSize = 20
+	// MaxPageSize caps first so a single request can't dump a table.
+	MaxPageSize = 100
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed or were issued
+// for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageParams selects one keyset page of a filtered, sorted product list.
+// Limit and Offset in ListParams are ignored.
+type PageParams struct {
+	ListParams
+	First *int
+	After *string
+	// WithTotal also counts every product matching the filters.
+	WithTotal bool
+}
+
+// Edge is one product in a page together with its cursor.
+type Edge[T any] struct {
+	Cursor string
+	Node   *T
+}
+
+// Page is a window of results plus what a Relay connection needs to
+// describe it.
+type Page[T any] struct {
+	Edges           []Edge[T]
+	HasNextPage     bool
+	HasPreviousPage bool
+	TotalCount      int
+}
+
+// cursor is the decoded form of an opaque page cursor. It pins the sort
+// order it was issued for, the sort key of the last row and that row's id.
+type cursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   string `json:"id"`
+}
+
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	// The id is compared as an integer; anything else would only fail in
+	// the query.
+	if _, err := strconv.Atoi(c.ID); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Page returns the page of products after p.After in the requested order.
+func (r *Repository[T]) Page(ctx context.Context, p PageParams) (*Page[T], error) {
+	key, err := r.Category.sortKey(p.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	first := DefaultPageSize
+	if p.First != nil {
+		if *p.First < 0 {
+			return nil, fmt.Errorf("first must not be negative")
+		}
+		first = *p.First
+	}
+	if first > MaxPageSize {
+		first = MaxPageSize
+	}
+
+	// The key and id are selected as text after the category's columns and
+	// become the row's cursor.
+	qb := r.selectQuery("("+key.expr+")::text", "id::text")
+	key.apply(qb)
+	if err := r.Category.applyFilters(qb, p.ListParams); err != nil {
+		return nil, err
+	}
+	if p.After != nil && *p.After != "" {
+		after, err := decodeCursor(*p.After)
+		if err != nil || after.Sort != key.name {
+			return nil, ErrInvalidCursor
+		}
+		op := ">"
+		if key.dir == "DESC" {
+			op = "<"
+		}
+		qb.Where("(" + key.expr + ", id) " + op + " (" + qb.Arg(after.Key) + ", " + qb.Arg(after.ID) + ")")
+	}
+	// Fetch one extra row to learn whether another page follows.
+	qb.Limit(first + 1)
+
+	query, args := qb.Build()
+	page := &Page[T]{HasPreviousPage: p.After != nil && *p.After != ""}
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if len(page.Edges) == first {
+			page.HasNextPage = true
+			break
+		}
+		var c cursor
+		node, err := r.Scan(withTrailing(rows, &c.Key, &c.ID))
+		if err != nil {
+			return nil, err
+		}
+		c.Sort = key.name
+		page.Edges = append(page.Edges, Edge[T]{Cursor: c.encode(), Node: node})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if p.WithTotal {
+		if page.TotalCount, err = r.Count(ctx, p.ListParams); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// Count returns how many products match p's filters.
+func (r *Repository[T]) Count(ctx context.Context, p ListParams) (int, error) {
+	qb := querybuilder.New("SELECT COUNT(*) FROM " + r.Category.Table)
+	if err := r.Category.applyFilters(qb, p); err != nil {
+		return 0, err
+	}
+	query, args := qb.Build()
+	var n int
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+// trailingScanner appends extra destinations after the ones a category
+// scanner asks for, so pagination columns can ride along with each row.
+type trailingScanner struct {
+	Scanner
+	extra []interface{}
+}
+
+func withTrailing(s Scanner, extra ...interface{}) Scanner {
+	return trailingScanner{Scanner: s, extra: extra}
+}
+
+func (t trailingScanner) Scan(dest ...interface{}) error {
+	return t.Scanner.Scan(append(dest, t.extra...)...)
+}
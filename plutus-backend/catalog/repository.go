@@ -0,0 +1,172 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"plutus-backend/querybuilder"
+)
+
+// Scanner is satisfied by *sql.Row and *sql.Rows.
+type Scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Filters holds filter argument values keyed by Category.Filters name, plus
+// "brand" (any spelling or alias) and "brandSlug", which every category
+// has. Nil and empty values are ignored.
+type Filters map[string]*string
+
+// ListParams are the arguments shared by every product list query.
+type ListParams struct {
+	Filters   Filters
+	Search    *string
+	SortOrder *string
+	// MinPrice and MaxPrice are in the category's currency.
+	MinPrice *float64
+	MaxPrice *float64
+	Limit    *int
+	Offset   *int
+}
+
+// Repository reads one category's products into model type T.
+type Repository[T any] struct {
+	DB       *sql.DB
+	Category *Category
+	Scan     func(Scanner) (*T, error)
+}
+
+// List returns the products matching p.
+func (r *Repository[T]) List(ctx context.Context, p ListParams) ([]*T, error) {
+	key, err := r.Category.sortKey(p.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	qb := r.selectQuery()
+	if err := r.Category.applyFilters(qb, p); err != nil {
+		return nil, err
+	}
+	key.apply(qb)
+	if p.Limit != nil {
+		qb.Limit(*p.Limit)
+	}
+	if p.Offset != nil {
+		qb.Offset(*p.Offset)
+	}
+
+	query, args := qb.Build()
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*T
+	for rows.Next() {
+		item, err := r.Scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Get returns the listed product with the given id.
+func (r *Repository[T]) Get(ctx context.Context, id string) (*T, error) {
+	query, args := r.selectQuery().Where(Listed).Where("id = ?", id).Build()
+	return r.Scan(r.DB.QueryRowContext(ctx, query, args...))
+}
+
+// stored returns the product with the given id, archived or not.
+func (r *Repository[T]) stored(ctx context.Context, id string) (*T, error) {
+	query, args := r.selectQuery().Where("id = ?", id).Build()
+	return r.Scan(r.DB.QueryRowContext(ctx, query, args...))
+}
+
+// ByIDs returns the listed products with the given ids, keyed by id. Ids
+// that don't exist or are archived are left out.
+func (r *Repository[T]) ByIDs(ctx context.Context, ids []string) (map[string]*T, error) {
+	query, args := r.selectQuery("id::text").Where(Listed).Where("id = ANY(?::integer[])", pq.Array(ids)).Build()
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make(map[string]*T, len(ids))
+	for rows.Next() {
+		var id string
+		item, err := r.Scan(withTrailing(rows, &id))
+		if err != nil {
+			return nil, err
+		}
+		items[id] = item
+	}
+	return items, rows.Err()
+}
+
+// selectQuery starts a SELECT of the category's columns followed by any
+// extra expressions.
+func (r *Repository[T]) selectQuery(extra ...string) *querybuilder.Builder {
+	columns := append(append([]string(nil), r.Category.Columns...), extra...)
+	return querybuilder.New("SELECT " + strings.Join(columns, ", ") + " FROM " + r.Category.Table)
+}
+
+// applyFilters adds the WHERE conditions for p to qb, which only ever
+// match listed products.
+func (c *Category) applyFilters(qb *querybuilder.Builder, p ListParams) error {
+	qb.Where(Listed)
+	names := make([]string, 0, len(p.Filters))
+	for name, value := range p.Filters {
+		if value != nil && *value != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := p.Filters[name]
+		switch name {
+		case "brand":
+			whereBrand(qb, brandMatch, *value)
+			continue
+		case "brandSlug":
+			whereBrand(qb, brandSlugMatch, *value)
+			continue
+		}
+		f, ok := c.Filters[name]
+		if !ok {
+			return fmt.Errorf("%s cannot be filtered by %s", c.Key, name)
+		}
+		switch f.Match {
+		case MatchContains:
+			qb.Where(f.Column+" ILIKE ?", querybuilder.Contains(*value))
+		case MatchSize:
+			qb.Where("EXISTS (SELECT 1 FROM jsonb_array_elements("+f.Column+") e WHERE e->>'size' = ?)", *value)
+		}
+	}
+	if p.Search != nil && strings.TrimSpace(*p.Search) != "" {
+		// A search with no words in it matches nothing rather than
+		// everything.
+		tsquery := querybuilder.PrefixTSQuery(*p.Search)
+		if tsquery == "" {
+			qb.Where("FALSE")
+		} else {
+			qb.Where(SearchVectorColumn+" @@ to_tsquery('"+TextSearchConfig+"', ?)", tsquery)
+		}
+	}
+	if c.PriceExpr != "" {
+		if p.MinPrice != nil && *p.MinPrice > 0 {
+			qb.Where(c.maxPriceExpr() + " >= " + qb.Arg(*p.MinPrice))
+		}
+		if p.MaxPrice != nil && *p.MaxPrice > 0 {
+			qb.Where(c.PriceExpr + " <= " + qb.Arg(*p.MaxPrice))
+		}
+	}
+	return nil
+}
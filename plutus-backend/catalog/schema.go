@@ -0,0 +1,53 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// BackfillPriceRanges recomputes min_price/max_price on every row whose
+// stored range disagrees with its prices column, as rows written before the
+// price range triggers existed do. It returns the number of rows updated
+// per category key.
+func BackfillPriceRanges(ctx context.Context, db *sql.DB) (map[string]int64, error) {
+	updated := map[string]int64{}
+	for _, c := range order {
+		if c.PricesColumn == "" {
+			continue
+		}
+		lo, hi := "jsonb_min_price("+c.PricesColumn+")", "jsonb_max_price("+c.PricesColumn+")"
+		res, err := db.ExecContext(ctx, "UPDATE "+c.Table+" SET min_price = "+lo+", max_price = "+hi+
+			" WHERE min_price IS DISTINCT FROM "+lo+" OR max_price IS DISTINCT FROM "+hi)
+		if err != nil {
+			return updated, fmt.Errorf("backfill %s: %w", c.Key, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return updated, err
+		}
+		updated[c.Key] = n
+	}
+	return updated, nil
+}
+
+const (
+	// SearchVectorColumn is the generated tsvector column holding each
+	// category's weighted search document (see migration 0006_search).
+	SearchVectorColumn = "search_vector"
+	// TextSearchConfig is the configuration the search vectors are built
+	// with, and so the one to query them with. "simple" lower-cases
+	// without stemming, which suits brand and model names better than a
+	// language dictionary.
+	TextSearchConfig = "simple"
+	// Listed is the condition a product row meets while it is on the
+	// storefront: archived rows keep their place in the table but are left
+	// out of everything customers see (see migration 0016_archived_at).
+	Listed = "archived_at IS NULL"
+)
+
+// ChangesChannel is the NOTIFY channel that writes to any product table are
+// announced on, along with edits to the brand tables (see package brands).
+// The payload is the table name. The triggers are installed by migration
+// 0005_change_notifications.
+const ChangesChannel = "catalog_changed"
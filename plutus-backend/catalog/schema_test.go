@@ -0,0 +1,77 @@
+package catalog
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"plutus-backend/migrations"
+)
+
+var (
+	createTable = regexp.MustCompile(`(?s)CREATE TABLE (?:IF NOT EXISTS )?(\w+) \((.*?)\n\);`)
+	columnDef   = regexp.MustCompile(`(?m)^\s+(\w+)\s`)
+	alterTable  = regexp.MustCompile(`(?s)ALTER TABLE (\w+) (.*?);`)
+	addColumn   = regexp.MustCompile(`ADD COLUMN (?:IF NOT EXISTS )?(\w+)`)
+)
+
+// migratedColumns returns the columns each table has once every migration
+// has run, read from the CREATE TABLE and ALTER TABLE ... ADD COLUMN
+// statements.
+func migratedColumns(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+	all, err := migrations.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := map[string]map[string]bool{}
+	add := func(table, column string) {
+		if tables[table] == nil {
+			tables[table] = map[string]bool{}
+		}
+		tables[table][column] = true
+	}
+	for _, m := range all {
+		for _, match := range createTable.FindAllStringSubmatch(m.Up, -1) {
+			for _, col := range columnDef.FindAllStringSubmatch(match[2], -1) {
+				switch strings.ToUpper(col[1]) {
+				case "PRIMARY", "UNIQUE", "CONSTRAINT", "FOREIGN", "CHECK":
+					continue
+				}
+				add(match[1], col[1])
+			}
+		}
+		for _, match := range alterTable.FindAllStringSubmatch(m.Up, -1) {
+			for _, col := range addColumn.FindAllStringSubmatch(match[2], -1) {
+				add(match[1], col[1])
+			}
+		}
+	}
+	return tables
+}
+
+// TestColumnsExistInSchema checks the columns the catalog reads against the
+// migrated schema. Distinct only queries columns listed in Columns, so this
+// covers the all*Subcategories and all*Genders queries too.
+func TestColumnsExistInSchema(t *testing.T) {
+	tables := migratedColumns(t)
+	for _, c := range order {
+		have, ok := tables[c.Table]
+		if !ok {
+			t.Errorf("%s: no migration creates table %s", c.Key, c.Table)
+			continue
+		}
+		want := append([]string{c.NameColumn, c.LinkColumn, "archived_at", SearchVectorColumn}, c.Columns...)
+		if c.PricesColumn != "" {
+			want = append(want, c.PricesColumn, "min_price", "max_price")
+		}
+		for _, f := range c.Filters {
+			want = append(want, f.Column)
+		}
+		for _, column := range want {
+			if !have[column] {
+				t.Errorf("%s: column %s isn't in the migrated %s table", c.Key, column, c.Table)
+			}
+		}
+	}
+}
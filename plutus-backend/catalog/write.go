@@ -0,0 +1,430 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"plutus-backend/graph/model"
+)
+
+// ErrProductNotFound is returned when writing to a product that doesn't
+// exist, or restoring one that isn't archived.
+var ErrProductNotFound = errors.New("product not found")
+
+// MaxSizeLength bounds a size label such as "UK 9" or "100ml".
+const MaxSizeLength = 40
+
+// ArchivedProduct is a product taken off the storefront by Archive.
+type ArchivedProduct struct {
+	Category    string
+	ID          string
+	Name        string
+	ProductLink string
+	ArchivedAt  time.Time
+}
+
+// UpsertSneaker validates in and writes it, returning the stored sneaker.
+func (c *Catalog) UpsertSneaker(ctx context.Context, in model.SneakerInput) (*model.Sneaker, error) {
+	var v validation
+	v.required("brand", in.Brand)
+	v.required("productName", in.ProductName)
+	v.link("productLink", in.ProductLink)
+	v.images(in.Images)
+	sizePrices := v.sizePrices(in.SizePrices)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	id, err := c.upsert(ctx, Sneakers, in.ID, in.ProductLink, []column{
+		{"brand", strings.TrimSpace(in.Brand)},
+		{"product_name", strings.TrimSpace(in.ProductName)},
+		{"size_prices", sizePrices},
+		{"images", pq.Array(trimAll(in.Images))},
+		{"sold_out", in.SoldOut != nil && *in.SoldOut},
+		{"product_link", strings.TrimSpace(in.ProductLink)},
+		{"seller_name", trimmed(in.SellerName)},
+		{"seller_url", trimmed(in.SellerURL)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.Sneakers.stored(ctx, id)
+}
+
+// UpsertWatch validates in and writes it, returning the stored watch.
+// salePrice is in the category's currency, dirhams.
+func (c *Catalog) UpsertWatch(ctx context.Context, in model.WatchInput) (*model.Watch, error) {
+	var v validation
+	v.required("brand", in.Brand)
+	v.required("name", in.Name)
+	v.link("link", in.Link)
+	v.images(in.Images)
+	v.price("salePrice", in.SalePrice)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	id, err := c.upsert(ctx, Watches, in.ID, in.Link, []column{
+		{"brand", strings.TrimSpace(in.Brand)},
+		{"name", strings.TrimSpace(in.Name)},
+		{"color", orEmpty(in.Color)},
+		{"sale_price", in.SalePrice},
+		{"market_price", orEmpty(in.MarketPrice)},
+		{"images", pq.Array(trimAll(in.Images))},
+		{"link", strings.TrimSpace(in.Link)},
+		{"seller_name", trimmed(in.SellerName)},
+		{"seller_url", trimmed(in.SellerURL)},
+		{"gender", trimmed(in.Gender)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.Watches.stored(ctx, id)
+}
+
+// UpsertPerfume validates in and writes it, returning the stored perfume.
+func (c *Catalog) UpsertPerfume(ctx context.Context, in model.PerfumeInput) (*model.Perfume, error) {
+	var v validation
+	v.required("brand", in.Brand)
+	v.required("title", in.Title)
+	v.link("url", in.URL)
+	v.images(in.Images)
+	v.sizes("variants", in.Variants)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	variants := make([]map[string]any, len(in.Variants))
+	for i, variant := range in.Variants {
+		variants[i] = map[string]any{"size": strings.TrimSpace(variant.Size), "price": variant.Price}
+	}
+	variantsJSON, err := json.Marshal(variants)
+	if err != nil {
+		return nil, err
+	}
+	id, err := c.upsert(ctx, Perfumes, in.ID, in.URL, []column{
+		{"brand", strings.TrimSpace(in.Brand)},
+		{"title", strings.TrimSpace(in.Title)},
+		{"fragrance_family", orEmpty(in.FragranceFamily)},
+		{"concentration", trimmed(in.Concentration)},
+		{"subcategory", trimmed(in.Subcategory)},
+		{"variants", string(variantsJSON)},
+		{"images", pq.Array(trimAll(in.Images))},
+		{"url", strings.TrimSpace(in.URL)},
+		{"seller_name", trimmed(in.SellerName)},
+		{"seller_url", trimmed(in.SellerURL)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.Perfumes.stored(ctx, id)
+}
+
+// UpsertAccessory validates in and writes it, returning the stored
+// accessory.
+func (c *Catalog) UpsertAccessory(ctx context.Context, in model.AccessoryInput) (*model.Accessory, error) {
+	id, err := c.upsertSized(ctx, Accessories, sizedInput(in))
+	if err != nil {
+		return nil, err
+	}
+	return c.Accessories.stored(ctx, id)
+}
+
+// UpsertApparel validates in and writes it, returning the stored apparel.
+func (c *Catalog) UpsertApparel(ctx context.Context, in model.ApparelInput) (*model.Apparel, error) {
+	id, err := c.upsertSized(ctx, Apparel, sizedInput(in))
+	if err != nil {
+		return nil, err
+	}
+	return c.Apparel.stored(ctx, id)
+}
+
+// sizedInput is the shared shape of AccessoryInput and ApparelInput, whose
+// tables have identical layouts.
+type sizedInput struct {
+	ID          *string
+	Brand       string
+	ProductName string
+	Subcategory string
+	Gender      string
+	SizePrices  []*model.SizePriceInput
+	Images      []string
+	InStock     *bool
+	ProductLink string
+	SellerName  *string
+	SellerURL   *string
+}
+
+func (c *Catalog) upsertSized(ctx context.Context, cat *Category, in sizedInput) (string, error) {
+	var v validation
+	v.required("brand", in.Brand)
+	v.required("productName", in.ProductName)
+	v.required("subcategory", in.Subcategory)
+	v.required("gender", in.Gender)
+	v.link("productLink", in.ProductLink)
+	v.images(in.Images)
+	sizePrices := v.sizePrices(in.SizePrices)
+	if err := v.err(); err != nil {
+		return "", err
+	}
+	return c.upsert(ctx, cat, in.ID, in.ProductLink, []column{
+		{"brand", strings.TrimSpace(in.Brand)},
+		{"product_name", strings.TrimSpace(in.ProductName)},
+		{"subcategory", strings.TrimSpace(in.Subcategory)},
+		{"gender", strings.TrimSpace(in.Gender)},
+		{"size_prices", sizePrices},
+		{"images", pq.Array(trimAll(in.Images))},
+		{"in_stock", in.InStock == nil || *in.InStock},
+		{"product_link", strings.TrimSpace(in.ProductLink)},
+		{"seller_name", trimmed(in.SellerName)},
+		{"seller_url", trimmed(in.SellerURL)},
+	})
+}
+
+type column struct {
+	name  string
+	value any
+}
+
+// upsert writes a product's columns and returns its id. With an id it
+// updates that product; without one it updates the product with the same
+// link, since links identify products across re-seeds, or else inserts a
+// new one. An archived product stays archived.
+func (c *Catalog) upsert(ctx context.Context, cat *Category, id *string, link string, columns []column) (string, error) {
+	names := make([]string, len(columns))
+	args := make([]any, len(columns))
+	for i, col := range columns {
+		names[i] = col.name
+		args[i] = col.value
+	}
+	assignments := make([]string, len(columns))
+	for i, name := range names {
+		assignments[i] = name + " = $" + strconv.Itoa(i+1)
+	}
+	update := "UPDATE " + cat.Table + " SET " + strings.Join(assignments, ", ")
+
+	if id != nil && *id != "" {
+		if _, err := strconv.Atoi(*id); err != nil {
+			return "", fmt.Errorf("%s %q: %w", cat.Key, *id, ErrProductNotFound)
+		}
+		var updated string
+		err := c.DB.QueryRowContext(ctx, update+" WHERE id = $"+strconv.Itoa(len(args)+1)+" RETURNING id::text",
+			append(args, *id)...).Scan(&updated)
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("%s %q: %w", cat.Key, *id, ErrProductNotFound)
+		}
+		return updated, err
+	}
+
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	// Serialize writers of the same link so two creates can't both insert.
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", cat.Table+":"+strings.TrimSpace(link)); err != nil {
+		return "", err
+	}
+	var written string
+	err = tx.QueryRowContext(ctx, update+" WHERE id = (SELECT id FROM "+cat.Table+
+		" WHERE NULLIF(TRIM("+cat.LinkColumn+"), '') = $"+strconv.Itoa(len(args)+1)+" ORDER BY id LIMIT 1) RETURNING id::text",
+		append(args, strings.TrimSpace(link))...).Scan(&written)
+	if err == sql.ErrNoRows {
+		placeholders := make([]string, len(args))
+		for i := range args {
+			placeholders[i] = "$" + strconv.Itoa(i+1)
+		}
+		err = tx.QueryRowContext(ctx, "INSERT INTO "+cat.Table+" ("+strings.Join(names, ", ")+") VALUES ("+
+			strings.Join(placeholders, ", ")+") RETURNING id::text", args...).Scan(&written)
+	}
+	if err != nil {
+		return "", err
+	}
+	return written, tx.Commit()
+}
+
+// Archive takes a product off the storefront, recording who did it. The
+// row stays where it is with archived_at set, so the product keeps its id
+// and every column; storefront queries leave it out (see Listed). It returns ErrProductNotFound for a product that doesn't exist.
+func (c *Catalog) Archive(ctx context.Context, cat *Category, id string, byUserID int) error {
+	if _, err := strconv.Atoi(id); err != nil {
+		return fmt.Errorf("%s %q: %w", cat.Key, id, ErrProductNotFound)
+	}
+	res, err := c.DB.ExecContext(ctx, "UPDATE "+cat.Table+" SET archived_at = now(), archived_by = $2 WHERE id = $1", id, byUserID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("%s %q: %w", cat.Key, id, ErrProductNotFound)
+	}
+	return nil
+}
+
+// Restore puts an archived product back on the storefront and returns it.
+func (c *Catalog) Restore(ctx context.Context, cat *Category, id string) (model.Product, error) {
+	if _, err := strconv.Atoi(id); err != nil {
+		return nil, fmt.Errorf("%s %q: %w", cat.Key, id, ErrProductNotFound)
+	}
+	res, err := c.DB.ExecContext(ctx, "UPDATE "+cat.Table+" SET archived_at = NULL, archived_by = NULL WHERE id = $1 AND archived_at IS NOT NULL", id)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, fmt.Errorf("%s %q is not archived: %w", cat.Key, id, ErrProductNotFound)
+	}
+	found, err := c.Products(ctx, cat.Key, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	if found[id] == nil {
+		return nil, fmt.Errorf("%s %q: %w", cat.Key, id, ErrProductNotFound)
+	}
+	return found[id], nil
+}
+
+// Archived lists archived products, most recently archived first, of one
+// category or, with a nil cat, of all.
+func (c *Catalog) Archived(ctx context.Context, cat *Category) ([]*ArchivedProduct, error) {
+	cats := Categories()
+	if cat != nil {
+		cats = []*Category{cat}
+	}
+	branches := make([]string, len(cats))
+	for i, c := range cats {
+		branches[i] = "SELECT '" + c.Key + "' AS category, id, COALESCE(" + c.NameColumn + ", '') AS name," +
+			" COALESCE(TRIM(" + c.LinkColumn + "), '') AS link, archived_at FROM " + c.Table + " WHERE NOT " + Listed
+	}
+	rows, err := c.DB.QueryContext(ctx, strings.Join(branches, " UNION ALL ")+" ORDER BY archived_at DESC, category, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []*ArchivedProduct{}
+	for rows.Next() {
+		var a ArchivedProduct
+		if err := rows.Scan(&a.Category, &a.ID, &a.Name, &a.ProductLink, &a.ArchivedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &a)
+	}
+	return out, rows.Err()
+}
+
+// validation collects what's wrong with a product input, so one error can
+// name every problem.
+type validation struct {
+	problems []string
+}
+
+func (v *validation) addf(format string, args ...any) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validation) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid product: %s", strings.Join(v.problems, "; "))
+}
+
+func (v *validation) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf("%s is required", field)
+	}
+}
+
+func (v *validation) link(field, value string) {
+	if !validURL(value) {
+		v.addf("%s must be an http or https URL", field)
+	}
+}
+
+func (v *validation) images(images []string) {
+	if len(images) == 0 {
+		v.addf("images: at least one is required")
+	}
+	for i, image := range images {
+		image = strings.TrimSpace(image)
+		if !validURL(image) && !(strings.HasPrefix(image, "/") && !strings.HasPrefix(image, "//")) {
+			v.addf("images[%d] must be an http or https URL or a path", i)
+		}
+	}
+}
+
+func (v *validation) price(field string, price float64) {
+	if !(price > 0) {
+		v.addf("%s must be positive", field)
+	}
+}
+
+// sizes checks a list of sizes and prices: at least one, each size a
+// non-blank label used once, each price positive.
+func (v *validation) sizes(field string, pairs []*model.SizePriceInput) {
+	if len(pairs) == 0 {
+		v.addf("%s: at least one is required", field)
+	}
+	seen := map[string]bool{}
+	for i, p := range pairs {
+		size := strings.TrimSpace(p.Size)
+		switch {
+		case size == "" || size == "-":
+			v.addf("%s[%d].size is required", field, i)
+		case len(size) > MaxSizeLength:
+			v.addf("%s[%d].size must be at most %d characters", field, i, MaxSizeLength)
+		case seen[strings.ToLower(size)]:
+			v.addf("%s[%d].size %q is listed twice", field, i, size)
+		}
+		seen[strings.ToLower(size)] = true
+		v.price(fmt.Sprintf("%s[%d].price", field, i), p.Price)
+	}
+}
+
+// sizePrices checks sizePrices and returns them as a size_prices value.
+func (v *validation) sizePrices(pairs []*model.SizePriceInput) string {
+	v.sizes("sizePrices", pairs)
+	out := make([]map[string]any, len(pairs))
+	for i, p := range pairs {
+		out[i] = map[string]any{"size": strings.TrimSpace(p.Size), "price": p.Price}
+	}
+	b, _ := json.Marshal(out)
+	return string(b)
+}
+
+func validURL(s string) bool {
+	u, err := url.Parse(strings.TrimSpace(s))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func trimAll(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.TrimSpace(v)
+	}
+	return out
+}
+
+// trimmed returns a nullable column value: nil for a missing or blank
+// string.
+func trimmed(s *string) any {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return nil
+	}
+	return strings.TrimSpace(*s)
+}
+
+func orEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimSpace(*s)
+}
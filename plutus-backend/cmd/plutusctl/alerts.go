@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"plutus-backend/alerts"
+	"plutus-backend/catalog"
+	"plutus-backend/currency"
+	"plutus-backend/notify"
+)
+
+var alertsCommand = &cli.Command{
+	Name:  "alerts",
+	Usage: "manage price and stock alerts",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "evaluate",
+			Usage: "send the alerts whose conditions are met, as the server does after each catalog change",
+			Action: func(c *cli.Context) error {
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				notifier, err := notify.FromEnv()
+				if err != nil {
+					return err
+				}
+				rates := currency.NewRates(db)
+				if err := rates.Load(c.Context); err != nil {
+					return err
+				}
+				e := &alerts.Evaluator{Catalog: catalog.New(db), Rates: rates, Notifier: notifier, StorefrontURL: os.Getenv("STOREFRONT_URL")}
+				sent, err := e.Run(c.Context)
+				fmt.Printf("✅ Sent %d alerts\n", sent)
+				return err
+			},
+		},
+	},
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"plutus-backend/brands"
+)
+
+var brandsCommand = &cli.Command{
+	Name:  "brands",
+	Usage: "manage canonical brands and their aliases",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "sync",
+			Usage: "register brands used by products that no alias covers yet",
+			Action: func(c *cli.Context) error {
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				n, err := brands.Sync(c.Context, db)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("✅ Registered %d new brands\n", n)
+				return nil
+			},
+		},
+		{
+			Name:      "alias",
+			Usage:     "make a spelling resolve to the brand with the given slug",
+			ArgsUsage: "<slug> <alias>",
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 2 {
+					return cli.ShowSubcommandHelp(c)
+				}
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				if err := brands.AddAlias(c.Context, db, c.Args().Get(0), c.Args().Get(1)); err != nil {
+					return err
+				}
+				fmt.Printf("✅ %q now resolves to %s\n", c.Args().Get(1), c.Args().Get(0))
+				return nil
+			},
+		},
+		{
+			Name:      "set",
+			Usage:     "edit a brand's name, logo, description or country",
+			ArgsUsage: "<slug>",
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "name", Usage: "display name"},
+				&cli.StringFlag{Name: "logo", Usage: "logo URL"},
+				&cli.StringFlag{Name: "description", Usage: "short description"},
+				&cli.StringFlag{Name: "country", Usage: "country of origin"},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 1 {
+					return cli.ShowSubcommandHelp(c)
+				}
+				var d brands.Details
+				for name, field := range map[string]**string{"name": &d.Name, "logo": &d.Logo, "description": &d.Description, "country": &d.Country} {
+					if c.IsSet(name) {
+						v := c.String(name)
+						*field = &v
+					}
+				}
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				if err := brands.SetDetails(c.Context, db, c.Args().First(), d); err != nil {
+					return err
+				}
+				fmt.Printf("✅ Updated %s\n", c.Args().First())
+				return nil
+			},
+		},
+		{
+			Name:      "resolve",
+			Usage:     "show the canonical brand for a spelling",
+			ArgsUsage: "<name>",
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 1 {
+					return cli.ShowSubcommandHelp(c)
+				}
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				r := brands.NewResolver(db)
+				if err := r.Load(c.Context); err != nil {
+					return err
+				}
+				b := r.Resolve(c.Args().First())
+				if b == nil {
+					return fmt.Errorf("no brand for %q", c.Args().First())
+				}
+				fmt.Printf("%s\t%s\n", b.Slug, b.Name)
+				return nil
+			},
+		},
+		{
+			Name:  "list",
+			Usage: "list every brand with its aliases",
+			Action: func(c *cli.Context) error {
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				r := brands.NewResolver(db)
+				if err := r.Load(c.Context); err != nil {
+					return err
+				}
+				for _, b := range r.All() {
+					fmt.Printf("%s\t%s\t%v\n", b.Slug, b.Name, b.Aliases)
+				}
+				return nil
+			},
+		},
+	},
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"plutus-backend/enquiries"
+	"plutus-backend/notify"
+)
+
+var enquiriesCommand = &cli.Command{
+	Name:  "enquiries",
+	Usage: "manage enquiry notifications",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "deliver",
+			Usage: "send the queued enquiry notifications that are due, as the server does in the background",
+			Action: func(c *cli.Context) error {
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				notifier, err := notify.FromEnv()
+				if err != nil {
+					return err
+				}
+				o := &enquiries.Outbox{DB: db, Mailer: notifier}
+				sent, err := o.Deliver(c.Context)
+				fmt.Printf("✅ Sent %d notifications\n", sent)
+				return err
+			},
+		},
+		{
+			Name:  "retry-failed",
+			Usage: "queue the notifications that were given up on to be tried again",
+			Action: func(c *cli.Context) error {
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				res, err := db.ExecContext(c.Context, "UPDATE enquiry_outbox SET failed_at = NULL, attempts = 0, next_attempt_at = now()"+
+					" WHERE failed_at IS NOT NULL AND sent_at IS NULL")
+				if err != nil {
+					return err
+				}
+				n, _ := res.RowsAffected()
+				fmt.Printf("✅ Queued %d notifications again\n", n)
+				return nil
+			},
+		},
+	},
+}
+
+var smtpSinkCommand = &cli.Command{
+	Name:  "smtp-sink",
+	Usage: "run a local SMTP server that stores every message in a maildir instead of sending it",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "listen", Value: "127.0.0.1:2525", Usage: "address to accept SMTP connections on"},
+		&cli.StringFlag{Name: "maildir", Value: "maildir", Usage: "directory to store messages in, under new/"},
+	},
+	Action: func(c *cli.Context) error {
+		dir := &notify.Maildir{Dir: c.String("maildir")}
+		sink := &notify.SMTPSink{Deliver: func(from string, to []string, msg []byte) error {
+			log.Printf("📧 %s -> %v (%d bytes)", from, to, len(msg))
+			return dir.Write(msg)
+		}}
+		fmt.Fprintf(os.Stderr, "Accepting mail on %s; set SMTP_HOST and SMTP_PORT to match and leave SMTP_USERNAME empty\n", c.String("listen"))
+		return sink.ListenAndServe(c.String("listen"))
+	},
+}
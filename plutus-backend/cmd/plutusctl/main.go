@@ -0,0 +1,62 @@
+// Command plutusctl runs maintenance tasks against the catalog database.
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/urfave/cli/v2"
+)
+
+func main() {
+	// A missing .env is fine; the environment may already be set.
+	_ = godotenv.Load()
+
+	app := &cli.App{
+		Name:  "plutusctl",
+		Usage: "maintenance tasks for the Plutus catalog database",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "database-url",
+				Usage:   "Postgres connection string",
+				EnvVars: []string{"DATABASE_URL"},
+			},
+		},
+		Commands: []*cli.Command{
+			backfillPricesCommand,
+			snapshotPricesCommand,
+			refreshSearchCommand,
+			brandsCommand,
+			ratesCommand,
+			alertsCommand,
+			usersCommand,
+			enquiriesCommand,
+			smtpSinkCommand,
+			migrateCommand,
+		},
+	}
+	if err := app.Run(os.Args); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+// openDB connects to the database named by --database-url.
+func openDB(c *cli.Context) (*sql.DB, error) {
+	url := c.String("database-url")
+	if url == "" {
+		return nil, fmt.Errorf("DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(c.Context); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
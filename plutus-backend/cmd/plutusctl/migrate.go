@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"plutus-backend/migrations"
+)
+
+var migrateCommand = &cli.Command{
+	Name:  "migrate",
+	Usage: "apply, revert and list the schema migrations",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "up",
+			Usage: "apply every pending migration",
+			Action: func(c *cli.Context) error {
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				ran, err := migrations.Up(c.Context, db)
+				for _, m := range ran {
+					fmt.Printf("✅ Applied %s\n", m)
+				}
+				if err == nil && len(ran) == 0 {
+					fmt.Println("✅ Schema up to date")
+				}
+				return err
+			},
+		},
+		{
+			Name:  "down",
+			Usage: "revert the latest applied migrations",
+			Flags: []cli.Flag{
+				&cli.IntFlag{Name: "steps", Value: 1, Usage: "how many migrations to revert"},
+			},
+			Action: func(c *cli.Context) error {
+				if c.Int("steps") < 1 {
+					return fmt.Errorf("--steps must be at least 1")
+				}
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				reverted, err := migrations.Down(c.Context, db, c.Int("steps"))
+				for _, m := range reverted {
+					fmt.Printf("✅ Reverted %s\n", m)
+				}
+				return err
+			},
+		},
+		{
+			Name:  "status",
+			Usage: "list the migrations and when each was applied",
+			Action: func(c *cli.Context) error {
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				states, err := migrations.Status(c.Context, db)
+				if err != nil {
+					return err
+				}
+				for _, s := range states {
+					status := "pending"
+					if s.AppliedAt != nil {
+						status = "applied " + s.AppliedAt.Local().Format(time.DateTime)
+					}
+					if s.Unknown {
+						status += " (not in this build)"
+					}
+					fmt.Printf("%04d_%-28s %s\n", s.Version, s.Name, status)
+				}
+				return nil
+			},
+		},
+	},
+}
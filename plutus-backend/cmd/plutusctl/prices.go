@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"plutus-backend/catalog"
+	"plutus-backend/prices"
+)
+
+var backfillPricesCommand = &cli.Command{
+	Name:  "backfill-prices",
+	Usage: "recompute min_price/max_price for rows written before the price triggers existed",
+	Action: func(c *cli.Context) error {
+		db, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		updated, err := catalog.BackfillPriceRanges(c.Context, db)
+		if err != nil {
+			return err
+		}
+		for _, cat := range catalog.Categories() {
+			if n, ok := updated[cat.Key]; ok {
+				fmt.Printf("✅ %s: %d rows updated\n", cat.Key, n)
+			}
+		}
+		return nil
+	},
+}
+
+var snapshotPricesCommand = &cli.Command{
+	Name:  "snapshot-prices",
+	Usage: "record current prices in the price history, e.g. for rows written before tracking began",
+	Action: func(c *cli.Context) error {
+		db, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		recorded, err := prices.Snapshot(c.Context, db)
+		if err != nil {
+			return err
+		}
+		for _, cat := range catalog.Categories() {
+			if n, ok := recorded[cat.Key]; ok {
+				fmt.Printf("✅ %s: %d prices recorded\n", cat.Key, n)
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+
+	"plutus-backend/currency"
+)
+
+var ratesCommand = &cli.Command{
+	Name:  "rates",
+	Usage: "manage the exchange rates used to show prices in other currencies",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "list every exchange rate",
+			Action: func(c *cli.Context) error {
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				rates := currency.NewRates(db)
+				if err := rates.Load(c.Context); err != nil {
+					return err
+				}
+				for _, r := range rates.All() {
+					fmt.Printf("%s\t%g\t%s\n", r.Currency, r.Rate, r.UpdatedAt.Format("2006-01-02 15:04"))
+				}
+				return nil
+			},
+		},
+		{
+			Name:      "set",
+			Usage:     "set how many units of a currency one " + currency.Base + " buys",
+			ArgsUsage: "<currency> <rate>",
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 2 {
+					return cli.ShowSubcommandHelp(c)
+				}
+				rate, err := strconv.ParseFloat(c.Args().Get(1), 64)
+				if err != nil {
+					return fmt.Errorf("invalid rate %q", c.Args().Get(1))
+				}
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				if err := currency.Set(c.Context, db, c.Args().Get(0), rate); err != nil {
+					return err
+				}
+				fmt.Printf("✅ Rate for %s set\n", c.Args().Get(0))
+				return nil
+			},
+		},
+		{
+			Name:      "import",
+			Usage:     "load rates from a CSV file of currency,rate lines",
+			ArgsUsage: "<file>",
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 1 {
+					return cli.ShowSubcommandHelp(c)
+				}
+				f, err := os.Open(c.Args().First())
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				n, err := currency.Import(c.Context, db, f)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("✅ Imported %d exchange rates\n", n)
+				return nil
+			},
+		},
+	},
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"plutus-backend/search"
+)
+
+var refreshSearchCommand = &cli.Command{
+	Name:  "refresh-search",
+	Usage: "rebuild the vocabulary used for search typo correction",
+	Action: func(c *cli.Context) error {
+		db, err := openDB(c)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if err := search.RefreshTerms(c.Context, db); err != nil {
+			return err
+		}
+		fmt.Println("✅ Search vocabulary refreshed")
+		return nil
+	},
+}
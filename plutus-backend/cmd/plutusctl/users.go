@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"plutus-backend/auth"
+)
+
+var usersCommand = &cli.Command{
+	Name:  "users",
+	Usage: "manage user accounts",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "set-role",
+			Usage:     "make an account a customer, staff member or admin; this is how the first admin is made",
+			ArgsUsage: "<email> <customer|staff|admin>",
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 2 {
+					return cli.ShowSubcommandHelp(c)
+				}
+				role, ok := auth.ParseRole(c.Args().Get(1))
+				if !ok {
+					return fmt.Errorf("unknown role %q", c.Args().Get(1))
+				}
+				db, err := openDB(c)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				u, err := auth.SetRole(c.Context, db, c.Args().Get(0), role)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("✅ %s (%s) is now %s\n", u.Email, u.UserID, u.Role)
+				return nil
+			},
+		},
+	},
+}
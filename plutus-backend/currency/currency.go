@@ -0,0 +1,218 @@
+// Package currency converts catalog prices between currencies using the
+// exchange_rates table. Each category stores prices in one currency (see
+// catalog.Category.Currency). Rates are maintained offline (see plutusctl
+// rates) rather than fetched live, so a price never changes between two page
+// loads unless someone updated a rate.
+package currency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Base is the currency exchange rates are quoted against, and the one
+// prices are stored and shown in unless stated otherwise.
+const Base = "INR"
+
+// Header is the request header a storefront sends to pick its display
+// currency, e.g. "X-Currency: AED".
+const Header = "X-Currency"
+
+// ErrUnknownCurrency is returned for a currency that has no exchange rate.
+var ErrUnknownCurrency = errors.New("no exchange rate for currency")
+
+// Rate is how many units of Currency one unit of Base buys.
+type Rate struct {
+	Currency  string
+	Rate      float64
+	UpdatedAt time.Time
+}
+
+// Normalize upper-cases and validates an ISO 4217 code.
+func Normalize(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 || strings.IndexFunc(code, func(r rune) bool { return r < 'A' || r > 'Z' }) >= 0 {
+		return "", fmt.Errorf("invalid currency code %q", code)
+	}
+	return code, nil
+}
+
+const upsertRate = "INSERT INTO exchange_rates (currency, rate, updated_at) VALUES ($1, $2, CURRENT_TIMESTAMP)" +
+	" ON CONFLICT (currency) DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at"
+
+// Set stores the rate for one currency. The base currency's rate is fixed.
+func Set(ctx context.Context, db *sql.DB, code string, rate float64) error {
+	code, err := Normalize(code)
+	if err != nil {
+		return err
+	}
+	if code == Base {
+		return fmt.Errorf("the rate for %s is always 1", Base)
+	}
+	if !(rate > 0) || math.IsInf(rate, 0) {
+		return fmt.Errorf("rate for %s must be a positive number", code)
+	}
+	_, err = db.ExecContext(ctx, upsertRate, code, rate)
+	return err
+}
+
+// Import reads "currency,rate" lines, such as "USD,0.012", and stores them
+// in one transaction. A header line, blank lines and lines starting with #
+// are skipped. It returns the number of rates stored.
+func Import(ctx context.Context, db *sql.DB, r io.Reader) (int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+	rates := map[string]float64{}
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		line, _ := cr.FieldPos(0)
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "currency") {
+			continue
+		}
+		code, err := Normalize(record[0])
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil || !(rate > 0) || math.IsInf(rate, 0) {
+			return 0, fmt.Errorf("line %d: invalid rate %q", line, record[1])
+		}
+		if code == Base {
+			if rate != 1 {
+				return 0, fmt.Errorf("line %d: the rate for %s is always 1", line, Base)
+			}
+			continue
+		}
+		rates[code] = rate
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for code, rate := range rates {
+		if _, err := tx.ExecContext(ctx, upsertRate, code, rate); err != nil {
+			return 0, err
+		}
+	}
+	return len(rates), tx.Commit()
+}
+
+// Rates converts base-currency amounts using an in-memory copy of the
+// exchange_rates table. Load replaces the copy wholesale.
+type Rates struct {
+	DB    *sql.DB
+	rates atomic.Pointer[map[string]Rate]
+}
+
+// NewRates returns Rates over db that only know the base currency until
+// Load.
+func NewRates(db *sql.DB) *Rates {
+	r := &Rates{DB: db}
+	r.rates.Store(&map[string]Rate{Base: {Currency: Base, Rate: 1}})
+	return r
+}
+
+// Load reads every rate.
+func (r *Rates) Load(ctx context.Context) error {
+	rows, err := r.DB.QueryContext(ctx, "SELECT currency, rate, COALESCE(updated_at, CURRENT_TIMESTAMP) FROM exchange_rates")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	rates := map[string]Rate{Base: {Currency: Base, Rate: 1}}
+	for rows.Next() {
+		var rate Rate
+		if err := rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return err
+		}
+		rates[rate.Currency] = rate
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	r.rates.Store(&rates)
+	return nil
+}
+
+// All returns every rate ordered by currency.
+func (r *Rates) All() []Rate {
+	rates := *r.rates.Load()
+	out := make([]Rate, 0, len(rates))
+	for _, rate := range rates {
+		out = append(out, rate)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Currency < out[j].Currency })
+	return out
+}
+
+// Convert turns an amount in one currency into another, rounded to two
+// decimals.
+func (r *Rates) Convert(amount float64, from, to string) (float64, error) {
+	rates := *r.rates.Load()
+	var factor [2]float64
+	for i, code := range []string{from, to} {
+		code, err := Normalize(code)
+		if err != nil {
+			return 0, err
+		}
+		rate, ok := rates[code]
+		if !ok {
+			return 0, fmt.Errorf("%w %s", ErrUnknownCurrency, code)
+		}
+		factor[i] = rate.Rate
+	}
+	return math.Round(amount/factor[0]*factor[1]*100) / 100, nil
+}
+
+type contextKey struct{}
+
+// WithCurrency returns a context whose display currency is code.
+func WithCurrency(ctx context.Context, code string) context.Context {
+	return context.WithValue(ctx, contextKey{}, code)
+}
+
+// FromContext returns the display currency set by WithCurrency, or Base.
+func FromContext(ctx context.Context) string {
+	if code, ok := Requested(ctx); ok {
+		return code
+	}
+	return Base
+}
+
+// Requested returns the display currency set by WithCurrency, and whether
+// the client chose one at all.
+func Requested(ctx context.Context) (string, bool) {
+	code, ok := ctx.Value(contextKey{}).(string)
+	return code, ok && code != ""
+}
+
+// Middleware sets each request's display currency from the Header.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code := r.Header.Get(Header); code != "" {
+			r = r.WithContext(WithCurrency(r.Context(), code))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
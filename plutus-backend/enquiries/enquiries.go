@@ -0,0 +1,377 @@
+// Package enquiries is the staff side of the enquiries customers send from
+// the storefront: each enquiry moves through a sales pipeline of statuses,
+// can be assigned to a member of staff, scheduled for a follow-up and
+// annotated with internal notes.
+package enquiries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"plutus-backend/auth"
+	"plutus-backend/catalog"
+	"plutus-backend/graph/model"
+	"plutus-backend/querybuilder"
+)
+
+// Status is where an enquiry is in the sales pipeline.
+type Status string
+
+const (
+	New       Status = "new"
+	Contacted Status = "contacted"
+	Quoted    Status = "quoted"
+	Won       Status = "won"
+	Lost      Status = "lost"
+)
+
+// Statuses lists the statuses in pipeline order. The
+// enquiries_status_check constraint (migration 0017_enquiry_workflow)
+// allows exactly these.
+var Statuses = []Status{New, Contacted, Quoted, Won, Lost}
+
+var (
+	// ErrNotFound is returned for an enquiry that doesn't exist.
+	ErrNotFound = errors.New("enquiry not found")
+	// ErrNotStaff is returned when assigning an enquiry to someone who
+	// isn't staff.
+	ErrNotStaff = errors.New("enquiries can only be assigned to staff")
+)
+
+// DefaultLimit and MaxLimit bound a page of List.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// Enquiry is a customer's enquiry and its progress.
+type Enquiry struct {
+	ID      int
+	Name    string
+	Email   string
+	Phone   string
+	Message string
+	// ProductID, ProductName and ProductCategory are what the storefront
+	// sent; they are empty for enquiries about no product in particular.
+	ProductID       string
+	ProductName     string
+	ProductCategory string
+	Status          Status
+	SpamScore       int
+	Assignee        *auth.User
+	FollowUpOn      *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Notes           []*Note
+
+	// Product is the product ProductID and ProductCategory name, while it
+	// is listed.
+	Product model.Product
+}
+
+// Note is an internal note on an enquiry, seen only by staff.
+type Note struct {
+	ID        int
+	Body      string
+	Author    *auth.User // nil once the author's account is deleted
+	CreatedAt time.Time
+}
+
+// Filter narrows List. Zero fields don't filter.
+type Filter struct {
+	Statuses []Status
+	// Category is a category key or product type, as in catalog.Find.
+	Category  string
+	ProductID string
+	// AssigneeEmail keeps the enquiries assigned to one member of staff;
+	// Unassigned keeps those assigned to nobody.
+	AssigneeEmail string
+	Unassigned    bool
+	// FollowUpDue keeps open enquiries whose follow-up date has come.
+	FollowUpDue bool
+	// IncludeSpam keeps enquiries scored as spam, which are otherwise left
+	// out.
+	IncludeSpam bool
+	// From and To bound when enquiries were made, To exclusive.
+	From, To time.Time
+	Limit    int
+	Offset   int
+}
+
+const columns = "e.id, e.name, e.email, COALESCE(e.phone, ''), e.message, COALESCE(e.product_id, ''), COALESCE(e.product_name, '')," +
+	" COALESCE(e.product_category, ''), e.status, e.spam_score, e.follow_up_on, e.created_at, COALESCE(e.updated_at, e.created_at)," +
+	" u.id, u.user_id, u.email, u.full_name, u.role"
+
+const from = " FROM enquiries e LEFT JOIN users u ON u.id = e.assignee_id"
+
+func scanEnquiry(s interface{ Scan(...interface{}) error }, extra ...interface{}) (*Enquiry, error) {
+	var e Enquiry
+	var followUp sql.NullTime
+	var assigneeID sql.NullInt64
+	var assigneeUserID, assigneeEmail, assigneeName, assigneeRole sql.NullString
+	dest := append([]interface{}{&e.ID, &e.Name, &e.Email, &e.Phone, &e.Message, &e.ProductID, &e.ProductName,
+		&e.ProductCategory, &e.Status, &e.SpamScore, &followUp, &e.CreatedAt, &e.UpdatedAt,
+		&assigneeID, &assigneeUserID, &assigneeEmail, &assigneeName, &assigneeRole}, extra...)
+	if err := s.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if followUp.Valid {
+		e.FollowUpOn = &followUp.Time
+	}
+	if assigneeID.Valid {
+		e.Assignee = &auth.User{ID: int(assigneeID.Int64), UserID: assigneeUserID.String, Email: assigneeEmail.String,
+			FullName: assigneeName.String, Role: auth.Role(assigneeRole.String)}
+	}
+	e.Notes = []*Note{}
+	return &e, nil
+}
+
+// List returns the enquiries matching f, newest first, and how many match
+// in all.
+func List(ctx context.Context, cat *catalog.Catalog, f Filter) ([]*Enquiry, int, error) {
+	q := f.where(querybuilder.New("SELECT " + columns + ", COUNT(*) OVER ()" + from))
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	q.OrderBy("e.created_at DESC").OrderBy("e.id DESC").Limit(min(limit, MaxLimit)).Offset(max(f.Offset, 0))
+	query, args := q.Build()
+
+	rows, err := cat.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	out := []*Enquiry{}
+	total := 0
+	for rows.Next() {
+		e, err := scanEnquiry(rows, &total)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(out) == 0 && f.Offset > 0 {
+		// Past the last page there are no rows to carry the count.
+		query, args := f.where(querybuilder.New("SELECT COUNT(*)" + from)).Build()
+		if err := cat.DB.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err := attach(ctx, cat, out); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+// where adds f's conditions to q.
+func (f Filter) where(q *querybuilder.Builder) *querybuilder.Builder {
+	if len(f.Statuses) > 0 {
+		statuses := make([]string, len(f.Statuses))
+		for i, s := range f.Statuses {
+			statuses[i] = string(s)
+		}
+		q.Where("e.status = ANY(?)", pq.Array(statuses))
+	}
+	if f.Category != "" {
+		q.Where("lower(e.product_category) = ANY(?)", pq.Array(categoryNames(f.Category)))
+	}
+	if f.ProductID != "" {
+		q.Where("e.product_id = ?", f.ProductID)
+	}
+	if f.AssigneeEmail != "" {
+		q.Where("lower(u.email) = lower(?)", f.AssigneeEmail)
+	}
+	if f.Unassigned {
+		q.Where("e.assignee_id IS NULL")
+	}
+	if f.FollowUpDue {
+		q.Where("e.follow_up_on <= CURRENT_DATE AND e.status NOT IN ('won', 'lost')")
+	}
+	if !f.IncludeSpam {
+		q.Where("e.spam_score < ?", SpamThreshold)
+	}
+	if !f.From.IsZero() {
+		q.Where("e.created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q.Where("e.created_at < ?", f.To)
+	}
+	return q
+}
+
+// Get returns one enquiry.
+func Get(ctx context.Context, cat *catalog.Catalog, id int) (*Enquiry, error) {
+	e, err := scanEnquiry(cat.DB.QueryRowContext(ctx, "SELECT "+columns+from+" WHERE e.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := attach(ctx, cat, []*Enquiry{e}); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// SetStatus moves an enquiry to status.
+func SetStatus(ctx context.Context, db *sql.DB, id int, status Status) error {
+	return update(ctx, db, id, "status = $2", string(status))
+}
+
+// Assign gives an enquiry to the member of staff registered under email, or
+// with an empty email to nobody.
+func Assign(ctx context.Context, db *sql.DB, id int, email string) error {
+	if email == "" {
+		return update(ctx, db, id, "assignee_id = NULL")
+	}
+	var assignee int
+	var role auth.Role
+	err := db.QueryRowContext(ctx, "SELECT id, role FROM users WHERE lower(email) = lower($1) ORDER BY id LIMIT 1", email).Scan(&assignee, &role)
+	if err == sql.ErrNoRows {
+		return auth.ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !role.Includes(auth.Staff) {
+		return ErrNotStaff
+	}
+	return update(ctx, db, id, "assignee_id = $2", assignee)
+}
+
+// SetFollowUp schedules a follow-up on the date of on, or with nil clears
+// it.
+func SetFollowUp(ctx context.Context, db *sql.DB, id int, on *time.Time) error {
+	if on == nil {
+		return update(ctx, db, id, "follow_up_on = NULL")
+	}
+	return update(ctx, db, id, "follow_up_on = $2", on.Format(time.DateOnly))
+}
+
+// AddNote adds an internal note to an enquiry.
+func AddNote(ctx context.Context, db *sql.DB, id int, author *auth.User, body string) (*Note, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errors.New("a note can't be empty")
+	}
+	n := &Note{Body: body, Author: author}
+	err := db.QueryRowContext(ctx, "INSERT INTO enquiry_notes (enquiry_id, author_id, body) SELECT id, $2, $3 FROM enquiries WHERE id = $1"+
+		" RETURNING id, created_at", id, author.ID, body).Scan(&n.ID, &n.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return n, update(ctx, db, id, "")
+}
+
+// update sets an enquiry's columns and bumps updated_at.
+func update(ctx context.Context, db *sql.DB, id int, set string, args ...interface{}) error {
+	assignments := "updated_at = CURRENT_TIMESTAMP"
+	if set != "" {
+		assignments = set + ", " + assignments
+	}
+	res, err := db.ExecContext(ctx, "UPDATE enquiries SET "+assignments+" WHERE id = $1", append([]interface{}{id}, args...)...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+// attach loads the notes and products of enquiries.
+func attach(ctx context.Context, cat *catalog.Catalog, enquiries []*Enquiry) error {
+	if len(enquiries) == 0 {
+		return nil
+	}
+	byID := make(map[int]*Enquiry, len(enquiries))
+	ids := make([]int64, len(enquiries))
+	for i, e := range enquiries {
+		byID[e.ID] = e
+		ids[i] = int64(e.ID)
+	}
+	rows, err := cat.DB.QueryContext(ctx, "SELECT n.enquiry_id, n.id, n.body, n.created_at, u.id, u.user_id, u.email, u.full_name, u.role"+
+		" FROM enquiry_notes n LEFT JOIN users u ON u.id = n.author_id WHERE n.enquiry_id = ANY($1) ORDER BY n.created_at, n.id", pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var enquiryID int
+		var n Note
+		var authorID sql.NullInt64
+		var authorUserID, authorEmail, authorName, authorRole sql.NullString
+		if err := rows.Scan(&enquiryID, &n.ID, &n.Body, &n.CreatedAt, &authorID, &authorUserID, &authorEmail, &authorName, &authorRole); err != nil {
+			return err
+		}
+		if authorID.Valid {
+			n.Author = &auth.User{ID: int(authorID.Int64), UserID: authorUserID.String, Email: authorEmail.String,
+				FullName: authorName.String, Role: auth.Role(authorRole.String)}
+		}
+		byID[enquiryID].Notes = append(byID[enquiryID].Notes, &n)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return attachProducts(ctx, cat, enquiries)
+}
+
+// attachProducts looks up the products enquiries were made about, one query
+// per category. Enquiries whose category isn't one of the catalog's, such as
+// those from the contact form, have no product.
+func attachProducts(ctx context.Context, cat *catalog.Catalog, enquiries []*Enquiry) error {
+	byCategory := map[string][]*Enquiry{}
+	for _, e := range enquiries {
+		c, ok := catalog.Find(e.ProductCategory)
+		if !ok || e.ProductID == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(e.ProductID); err != nil {
+			continue
+		}
+		byCategory[c.Key] = append(byCategory[c.Key], e)
+	}
+	for key, list := range byCategory {
+		ids := make([]string, len(list))
+		for i, e := range list {
+			ids[i] = e.ProductID
+		}
+		found, err := cat.Products(ctx, key, ids)
+		if err != nil {
+			return err
+		}
+		for _, e := range list {
+			e.Product = found[e.ProductID]
+		}
+	}
+	return nil
+}
+
+// categoryNames returns the lower-case values product_category may hold for
+// a category: its key and its product type, as the storefront sends either.
+// A name that isn't a category is matched as it is.
+func categoryNames(name string) []string {
+	c, ok := catalog.Find(name)
+	if !ok {
+		return []string{strings.ToLower(strings.TrimSpace(name))}
+	}
+	names := []string{c.Key}
+	if t := strings.Trim(c.PagePath, "/"); t != "" {
+		names = append(names, t)
+	}
+	return names
+}
@@ -0,0 +1,419 @@
+package enquiries
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"plutus-backend/catalog"
+	"plutus-backend/notify"
+)
+
+// Channels an outbox message is delivered through.
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Target is where the sales team is told about an enquiry: an email address
+// or a webhook URL that is POSTed the enquiry as JSON.
+type Target struct {
+	Email   string
+	Webhook string
+}
+
+// Routes say who hears about enquiries, by product category. The key "*"
+// is the default, for enquiries about a category without routes of its own
+// or about no product.
+type Routes map[string][]Target
+
+// DefaultRoute is the Routes key of the default targets.
+const DefaultRoute = "*"
+
+// ParseRoutes reads routing rules such as
+//
+//	*=sales@example.com; watches=watches@example.com,https://hooks.example.com/enquiries
+//
+// Rules are separated by semicolons or newlines. Each names a category key
+// or product type (see catalog.Find), any other product_category value the
+// storefront sends, such as "contact_form", or "*"; and, after "=", a comma
+// separated list of email addresses and http(s) webhook URLs. A category's
+// rule replaces the default rather than adding to it.
+func ParseRoutes(s string) (Routes, error) {
+	routes := Routes{}
+	for _, rule := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == '\n' }) {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		category, list, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("enquiry route %q: want category=target,...", rule)
+		}
+		key := routeKey(category)
+		if key == "" {
+			return nil, fmt.Errorf("enquiry route %q: missing category", rule)
+		}
+		for _, target := range strings.Split(list, ",") {
+			target = strings.TrimSpace(target)
+			if target == "" {
+				continue
+			}
+			if u, err := url.Parse(target); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+				routes[key] = append(routes[key], Target{Webhook: target})
+				continue
+			}
+			addr, err := mail.ParseAddress(target)
+			if err != nil {
+				return nil, fmt.Errorf("enquiry route %q: %q is neither an email address nor an http(s) URL", rule, target)
+			}
+			routes[key] = append(routes[key], Target{Email: addr.Address})
+		}
+	}
+	return routes, nil
+}
+
+// For returns the targets of an enquiry about category.
+func (r Routes) For(category string) []Target {
+	if targets, ok := r[routeKey(category)]; ok && category != "" {
+		return targets
+	}
+	return r[DefaultRoute]
+}
+
+// routeKey normalises a category so its product type and key share routes.
+func routeKey(category string) string {
+	if c, ok := catalog.Find(category); ok {
+		return c.Key
+	}
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// Outbox queues and delivers the notifications about new enquiries: one to
+// each of the sales team's targets and an acknowledgement to the customer.
+// They are queued in the transaction storing the enquiry and delivered
+// afterwards, so a mail server being down delays them but never loses the
+// enquiry; failed deliveries are retried with backoff.
+type Outbox struct {
+	DB     *sql.DB
+	Mailer notify.Notifier
+	Routes Routes
+	// Client posts to webhooks. It defaults to a client with a 10 second
+	// timeout.
+	Client *http.Client
+	// StorefrontURL is the storefront's base URL, for links to the product
+	// an enquiry is about. Without one messages carry no link.
+	StorefrontURL string
+	// MaxAttempts is how often a delivery is tried before it is given up
+	// on. It defaults to 10, which spans about a day.
+	MaxAttempts int
+
+	wakeOnce sync.Once
+	wake     chan struct{}
+}
+
+// Retry backoff of failed deliveries.
+const (
+	retryBase = 30 * time.Second
+	retryMax  = 6 * time.Hour
+)
+
+// pollInterval is how often Run looks for deliveries that are due without
+// being woken.
+const pollInterval = time.Minute
+
+var (
+	staffSubject = template.Must(template.New("staffSubject").Parse(
+		`New enquiry{{with .ProductName}} about {{.}}{{end}} from {{.Name}}`))
+	staffBody = template.Must(template.New("staffBody").Parse(`A new enquiry came in{{with .ProductCategory}} ({{.}}){{end}}.
+
+Name:    {{.Name}}
+Email:   {{.Email}}
+{{- with .Phone}}
+Phone:   {{.}}{{end}}
+{{- with .ProductName}}
+Product: {{.}}{{end}}
+{{- with .ProductURL}}
+         {{.}}{{end}}
+
+{{.Message}}
+
+Enquiry #{{.ID}}, received {{.Received}}.
+`))
+	ackSubject = template.Must(template.New("ackSubject").Parse(
+		`We've received your enquiry{{with .ProductName}} about {{.}}{{end}}`))
+	ackBody = template.Must(template.New("ackBody").Parse(`Hi {{.FirstName}},
+
+Thank you for getting in touch with House of Plutus. We've received your enquiry{{with .ProductName}} about {{.}}{{end}} and a member of our team will reply to you at {{.Email}} shortly.
+{{- with .ProductURL}}
+
+{{.}}{{end}}
+
+For reference, you wrote:
+
+{{.Message}}
+
+House of Plutus
+`))
+)
+
+// templateData is what the message templates see of an enquiry.
+type templateData struct {
+	ID              int
+	Name            string
+	FirstName       string
+	Email           string
+	Phone           string
+	Message         string
+	ProductName     string
+	ProductCategory string
+	ProductURL      string
+	Received        string
+}
+
+// Enqueue queues the notifications about the enquiry with id, in tx, the
+// transaction that stored it. Call Wake once tx is committed.
+func (o *Outbox) Enqueue(ctx context.Context, tx *sql.Tx, id int) error {
+	var e Enquiry
+	err := tx.QueryRowContext(ctx, "SELECT id, name, email, COALESCE(phone, ''), message, COALESCE(product_id, ''), COALESCE(product_name, ''),"+
+		" COALESCE(product_category, ''), created_at FROM enquiries WHERE id = $1", id).Scan(
+		&e.ID, &e.Name, &e.Email, &e.Phone, &e.Message, &e.ProductID, &e.ProductName, &e.ProductCategory, &e.CreatedAt)
+	if err != nil {
+		return err
+	}
+	data := o.templateData(&e)
+
+	queue := func(channel, recipient, subject, body string) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO enquiry_outbox (enquiry_id, channel, recipient, subject, body) VALUES ($1, $2, $3, $4, $5)",
+			e.ID, channel, recipient, subject, body)
+		return err
+	}
+	for _, t := range o.Routes.For(e.ProductCategory) {
+		if t.Webhook != "" {
+			payload, err := json.Marshal(webhookPayload(&e, data))
+			if err != nil {
+				return err
+			}
+			if err := queue(ChannelWebhook, t.Webhook, "", string(payload)); err != nil {
+				return err
+			}
+			continue
+		}
+		subject, body, err := render(staffSubject, staffBody, data)
+		if err != nil {
+			return err
+		}
+		if err := queue(ChannelEmail, t.Email, subject, body); err != nil {
+			return err
+		}
+	}
+	// An address that can't be mailed still leaves the enquiry for staff to
+	// follow up some other way.
+	if _, err := mail.ParseAddress(e.Email); err == nil {
+		subject, body, err := render(ackSubject, ackBody, data)
+		if err != nil {
+			return err
+		}
+		if err := queue(ChannelEmail, e.Email, subject, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *Outbox) templateData(e *Enquiry) templateData {
+	d := templateData{
+		ID:              e.ID,
+		Name:            e.Name,
+		FirstName:       "there",
+		Email:           e.Email,
+		Phone:           e.Phone,
+		Message:         e.Message,
+		ProductName:     e.ProductName,
+		ProductCategory: e.ProductCategory,
+		Received:        e.CreatedAt.Format("2 Jan 2006 15:04"),
+	}
+	if first := strings.Fields(e.Name); len(first) > 0 {
+		d.FirstName = first[0]
+	}
+	if c, ok := catalog.Find(e.ProductCategory); ok && o.StorefrontURL != "" && e.ProductID != "" {
+		if _, err := strconv.Atoi(e.ProductID); err == nil {
+			d.ProductURL = strings.TrimRight(o.StorefrontURL, "/") + c.PagePath + e.ProductID
+		}
+	}
+	return d
+}
+
+// webhookPayload is the JSON body posted to webhook targets.
+func webhookPayload(e *Enquiry, d templateData) map[string]any {
+	return map[string]any{
+		"event":           "enquiry.created",
+		"id":              e.ID,
+		"name":            e.Name,
+		"email":           e.Email,
+		"phone":           e.Phone,
+		"message":         e.Message,
+		"productId":       e.ProductID,
+		"productName":     e.ProductName,
+		"productCategory": e.ProductCategory,
+		"productUrl":      d.ProductURL,
+		"createdAt":       e.CreatedAt.Format(time.RFC3339),
+		// Chat webhooks such as Slack's show text.
+		"text": fmt.Sprintf("New enquiry #%d from %s <%s>: %s", e.ID, e.Name, e.Email, e.Message),
+	}
+}
+
+func render(subject, body *template.Template, data templateData) (string, string, error) {
+	var s, b strings.Builder
+	if err := subject.Execute(&s, data); err != nil {
+		return "", "", err
+	}
+	if err := body.Execute(&b, data); err != nil {
+		return "", "", err
+	}
+	return s.String(), b.String(), nil
+}
+
+// Wake asks Run to deliver straight away rather than at its next poll.
+func (o *Outbox) Wake() {
+	select {
+	case o.wakeup() <- struct{}{}:
+	default:
+	}
+}
+
+func (o *Outbox) wakeup() chan struct{} {
+	o.wakeOnce.Do(func() { o.wake = make(chan struct{}, 1) })
+	return o.wake
+}
+
+// Run delivers queued notifications as they become due until ctx is done.
+func (o *Outbox) Run(ctx context.Context) {
+	wake := o.wakeup()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := o.Deliver(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️ Warning: Enquiry notifications: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// outboxMessage is a claimed outbox row.
+type outboxMessage struct {
+	id        int64
+	channel   string
+	recipient string
+	subject   string
+	body      string
+	attempts  int
+}
+
+// deliverBatch bounds the messages one Deliver call claims at a time.
+const deliverBatch = 20
+
+// Deliver sends every queued notification that is due and returns how many
+// were sent. Each message is claimed by pushing its next attempt back by the
+// retry backoff before it is sent, so concurrent servers don't send it twice
+// and one that crashes mid-send retries it later.
+func (o *Outbox) Deliver(ctx context.Context) (int, error) {
+	sent := 0
+	var errs []error
+	for {
+		batch, err := o.claim(ctx)
+		if err != nil {
+			return sent, errors.Join(append(errs, err)...)
+		}
+		for _, m := range batch {
+			if err := o.send(ctx, m); err != nil {
+				errs = append(errs, fmt.Errorf("%s to %s: %w", m.channel, m.recipient, err))
+				if err := o.failed(ctx, m, err); err != nil {
+					return sent, errors.Join(append(errs, err)...)
+				}
+				continue
+			}
+			if _, err := o.DB.ExecContext(ctx, "UPDATE enquiry_outbox SET sent_at = now(), last_error = NULL WHERE id = $1", m.id); err != nil {
+				return sent, errors.Join(append(errs, err)...)
+			}
+			sent++
+		}
+		if len(batch) < deliverBatch {
+			return sent, errors.Join(errs...)
+		}
+	}
+}
+
+func (o *Outbox) claim(ctx context.Context) ([]*outboxMessage, error) {
+	rows, err := o.DB.QueryContext(ctx, "UPDATE enquiry_outbox SET attempts = attempts + 1,"+
+		" next_attempt_at = now() + make_interval(secs => LEAST($2 * power(2, attempts), $3))"+
+		" WHERE id IN (SELECT id FROM enquiry_outbox WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= now()"+
+		" ORDER BY next_attempt_at, id LIMIT $1 FOR UPDATE SKIP LOCKED)"+
+		" RETURNING id, channel, recipient, subject, body, attempts",
+		deliverBatch, retryBase.Seconds(), retryMax.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var batch []*outboxMessage
+	for rows.Next() {
+		var m outboxMessage
+		if err := rows.Scan(&m.id, &m.channel, &m.recipient, &m.subject, &m.body, &m.attempts); err != nil {
+			return nil, err
+		}
+		batch = append(batch, &m)
+	}
+	return batch, rows.Err()
+}
+
+// failed records a failed delivery, giving up on it after MaxAttempts.
+func (o *Outbox) failed(ctx context.Context, m *outboxMessage, cause error) error {
+	maxAttempts := o.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+	_, err := o.DB.ExecContext(ctx, "UPDATE enquiry_outbox SET last_error = $2,"+
+		" failed_at = CASE WHEN attempts >= $3 THEN now() END WHERE id = $1", m.id, cause.Error(), maxAttempts)
+	return err
+}
+
+func (o *Outbox) send(ctx context.Context, m *outboxMessage) error {
+	if m.channel == ChannelEmail {
+		return o.Mailer.Notify(ctx, notify.Message{To: m.recipient, Subject: m.subject, Body: m.body})
+	}
+	client := o.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.recipient, bytes.NewReader([]byte(m.body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return nil
+}
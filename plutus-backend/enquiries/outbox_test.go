@@ -0,0 +1,367 @@
+package enquiries
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"plutus-backend/notify"
+)
+
+// fakeDB is an in-memory stand-in for the enquiries and enquiry_outbox
+// tables, served through database/sql by answering the statements Service
+// and Outbox run. Transactions aren't isolated and never roll back.
+type fakeDB struct {
+	mu        sync.Mutex
+	enquiries []*fakeEnquiry
+	outbox    []*fakeOutboxRow
+}
+
+type fakeEnquiry struct {
+	Enquiry
+	ip, idempotencyKey string
+}
+
+type fakeOutboxRow struct {
+	channel, recipient, subject, body string
+	attempts                          int
+	nextAttempt                       time.Time
+	lastError                         string
+	sent, failed                      bool
+}
+
+var (
+	fakeDBsMu sync.Mutex
+	fakeDBs   = map[string]*fakeDB{}
+)
+
+func init() {
+	sql.Register("fakeenquiries", fakeDriver{})
+}
+
+func newFakeDB(t *testing.T) (*sql.DB, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{}
+	fakeDBsMu.Lock()
+	fakeDBs[t.Name()] = fake
+	fakeDBsMu.Unlock()
+	db, err := sql.Open("fakeenquiries", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+
+// add stores an enquiry as Submit would and returns its id.
+func (f *fakeDB) add(e Enquiry) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e.ID = len(f.enquiries) + 1
+	e.CreatedAt = time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	f.enquiries = append(f.enquiries, &fakeEnquiry{Enquiry: e})
+	return e.ID
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	fake, ok := fakeDBs[name]
+	if !ok {
+		return nil, fmt.Errorf("no fake database %q", name)
+	}
+	return fake, nil
+}
+
+func (f *fakeDB) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{f, query}, nil
+}
+func (f *fakeDB) Close() error              { return nil }
+func (f *fakeDB) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	f     *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "INSERT INTO enquiry_outbox"):
+		if int(args[0].(int64)) > len(s.f.enquiries) {
+			return nil, fmt.Errorf("enquiry %v doesn't exist", args[0])
+		}
+		s.f.outbox = append(s.f.outbox, &fakeOutboxRow{
+			channel: args[1].(string), recipient: args[2].(string), subject: args[3].(string), body: args[4].(string),
+		})
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "UPDATE enquiry_outbox SET sent_at = now()"):
+		m := s.f.outbox[args[0].(int64)-1]
+		m.sent, m.lastError = true, ""
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "UPDATE enquiry_outbox SET last_error = $2"):
+		m := s.f.outbox[args[0].(int64)-1]
+		m.lastError = args[1].(string)
+		m.failed = int64(m.attempts) >= args[2].(int64)
+		return driver.RowsAffected(1), nil
+	}
+	return nil, fmt.Errorf("unexpected exec: %s", s.query)
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "SELECT id, name, email, COALESCE(phone, '')"):
+		rows := &fakeRows{columns: []string{"id", "name", "email", "phone", "message", "product_id", "product_name", "product_category", "created_at"}}
+		if id := int(args[0].(int64)); id >= 1 && id <= len(s.f.enquiries) {
+			e := s.f.enquiries[id-1]
+			rows.rows = append(rows.rows, []driver.Value{int64(e.ID), e.Name, e.Email, e.Phone, e.Message, e.ProductID, e.ProductName, e.ProductCategory, e.CreatedAt})
+		}
+		return rows, nil
+	case strings.HasPrefix(s.query, "INSERT INTO enquiries"):
+		email, key := args[1].(string), args[9].(string)
+		rows := &fakeRows{columns: []string{"id"}}
+		for _, e := range s.f.enquiries {
+			if key != "" && e.idempotencyKey == key && strings.EqualFold(e.Email, email) {
+				return rows, nil
+			}
+		}
+		e := &fakeEnquiry{
+			Enquiry: Enquiry{
+				ID: len(s.f.enquiries) + 1, Name: args[0].(string), Email: email, Phone: args[2].(string), Message: args[3].(string),
+				ProductID: args[4].(string), ProductName: args[5].(string), ProductCategory: args[6].(string),
+				SpamScore: int(args[7].(int64)), CreatedAt: time.Now(),
+			},
+			ip: args[8].(string), idempotencyKey: key,
+		}
+		s.f.enquiries = append(s.f.enquiries, e)
+		rows.rows = append(rows.rows, []driver.Value{int64(e.ID)})
+		return rows, nil
+	case strings.HasPrefix(s.query, "SELECT id, spam_score FROM enquiries"):
+		rows := &fakeRows{columns: []string{"id", "spam_score"}}
+		for _, e := range s.f.enquiries {
+			if strings.EqualFold(e.Email, args[0].(string)) && e.idempotencyKey == args[1].(string) {
+				rows.rows = append(rows.rows, []driver.Value{int64(e.ID), int64(e.SpamScore)})
+			}
+		}
+		return rows, nil
+	case strings.HasPrefix(s.query, "SELECT COUNT(*)"):
+		value, window, limit := args[0].(string), time.Duration(args[1].(float64)*float64(time.Second)), int(args[2].(int64))
+		byIP := strings.Contains(s.query, "ip = $1")
+		var recent []time.Time
+		for _, e := range slices.Backward(s.f.enquiries) {
+			match := strings.EqualFold(e.Email, value)
+			if byIP {
+				match = e.ip == value
+			}
+			if match && time.Since(e.CreatedAt) < window && len(recent) < limit {
+				recent = append(recent, e.CreatedAt)
+			}
+		}
+		wait := 0.0
+		if len(recent) > 0 {
+			wait = time.Until(recent[len(recent)-1].Add(window)).Seconds()
+		}
+		return &fakeRows{columns: []string{"count", "wait"}, rows: [][]driver.Value{{int64(len(recent)), wait}}}, nil
+	case strings.HasPrefix(s.query, "UPDATE enquiry_outbox SET attempts = attempts + 1"):
+		limit, base, most := args[0].(int64), args[1].(float64), args[2].(float64)
+		rows := &fakeRows{columns: []string{"id", "channel", "recipient", "subject", "body", "attempts"}}
+		for i, m := range s.f.outbox {
+			if m.sent || m.failed || m.nextAttempt.After(time.Now()) || int64(len(rows.rows)) == limit {
+				continue
+			}
+			backoff := min(base*float64(int(1)<<m.attempts), most)
+			m.attempts++
+			m.nextAttempt = time.Now().Add(time.Duration(backoff * float64(time.Second)))
+			rows.rows = append(rows.rows, []driver.Value{int64(i + 1), m.channel, m.recipient, m.subject, m.body, int64(m.attempts)})
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", s.query)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// enqueue queues the notifications about the enquiry with id as Submit
+// does.
+func enqueue(t *testing.T, db *sql.DB, o *Outbox, id int) {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Enqueue(ctx, tx, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// flakyMailer fails while down is set and records what it sends otherwise.
+type flakyMailer struct {
+	down bool
+	sent []notify.Message
+}
+
+func (m *flakyMailer) Notify(ctx context.Context, msg notify.Message) error {
+	if m.down {
+		return errors.New("connection refused")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestOutboxRetriesFailedSends(t *testing.T) {
+	ctx := context.Background()
+	db, fake := newFakeDB(t)
+	mailer := &flakyMailer{down: true}
+	o := &Outbox{DB: db, Mailer: mailer, Routes: Routes{DefaultRoute: {{Email: "sales@example.com"}}}, MaxAttempts: 3}
+	id := fake.add(Enquiry{Name: "Ada Lovelace", Email: "ada@example.com", Message: "Is this in stock?"})
+	enqueue(t, db, o, id)
+
+	sent, err := o.Deliver(ctx)
+	if sent != 0 || err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("Deliver with the mail server down = %d, %v; want 0 and its error", sent, err)
+	}
+	if len(fake.enquiries) != 1 {
+		t.Fatalf("%d enquiries stored, want the one still there", len(fake.enquiries))
+	}
+	for _, m := range fake.outbox {
+		if m.sent || m.failed || m.attempts != 1 || m.lastError == "" {
+			t.Fatalf("after a failed send: %+v, want one attempt recorded and the message kept for a retry", *m)
+		}
+	}
+	// Nothing is due again until the backoff has passed.
+	if sent, err := o.Deliver(ctx); sent != 0 || err != nil {
+		t.Fatalf("Deliver during the backoff = %d, %v; want 0, nil", sent, err)
+	}
+
+	mailer.down = false
+	for _, m := range fake.outbox {
+		m.nextAttempt = time.Time{}
+	}
+	if sent, err := o.Deliver(ctx); sent != 2 || err != nil {
+		t.Fatalf("Deliver once the mail server is back = %d, %v; want 2, nil", sent, err)
+	}
+	var to []string
+	for _, m := range mailer.sent {
+		to = append(to, m.To)
+	}
+	slices.Sort(to)
+	if want := []string{"ada@example.com", "sales@example.com"}; !slices.Equal(to, want) {
+		t.Errorf("sent to %q, want %q", to, want)
+	}
+	for _, m := range fake.outbox {
+		if !m.sent || m.attempts != 2 || m.lastError != "" {
+			t.Errorf("after the retry: %+v, want sent on the second attempt", *m)
+		}
+	}
+}
+
+func TestOutboxGivesUpAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	db, fake := newFakeDB(t)
+	o := &Outbox{DB: db, Mailer: &flakyMailer{down: true}, Routes: Routes{DefaultRoute: {{Email: "sales@example.com"}}}, MaxAttempts: 3}
+	enqueue(t, db, o, fake.add(Enquiry{Name: "Ada", Email: "ada@example.com", Message: "Hello"}))
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		for _, m := range fake.outbox {
+			m.nextAttempt = time.Time{}
+		}
+		if _, err := o.Deliver(ctx); err == nil {
+			t.Fatalf("attempt %d: err = nil, want the send error", attempt)
+		}
+		for _, m := range fake.outbox {
+			if failed := attempt == 3; m.failed != failed {
+				t.Fatalf("attempt %d: failed = %v, want %v", attempt, m.failed, failed)
+			}
+		}
+	}
+	for _, m := range fake.outbox {
+		m.nextAttempt = time.Time{}
+	}
+	if sent, err := o.Deliver(ctx); sent != 0 || err != nil {
+		t.Errorf("Deliver after giving up = %d, %v; want 0, nil", sent, err)
+	}
+	if len(fake.enquiries) != 1 {
+		t.Errorf("%d enquiries stored, want the one still there", len(fake.enquiries))
+	}
+}
+
+func TestOutboxRoutesByCategory(t *testing.T) {
+	routes, err := ParseRoutes("*=sales@example.com; watches=watches@example.com,https://hooks.example.com/watches; contact_form=concierge@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		category string
+		want     []string
+	}{
+		{"watches", []string{"watches@example.com", "https://hooks.example.com/watches"}},
+		// Product types share their category's route.
+		{"watch", []string{"watches@example.com", "https://hooks.example.com/watches"}},
+		{" Watches ", []string{"watches@example.com", "https://hooks.example.com/watches"}},
+		{"contact_form", []string{"concierge@example.com"}},
+		{"sneakers", []string{"sales@example.com"}},
+		{"", []string{"sales@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			db, fake := newFakeDB(t)
+			o := &Outbox{DB: db, Routes: routes}
+			// An address that can't be mailed gets no acknowledgement, which
+			// leaves only the staff messages.
+			enqueue(t, db, o, fake.add(Enquiry{Name: "Ada", Email: "not an address", Message: "Hello", ProductCategory: tt.category}))
+			var got []string
+			for _, m := range fake.outbox {
+				got = append(got, m.recipient)
+				want := ChannelEmail
+				if strings.HasPrefix(m.recipient, "https://") {
+					want = ChannelWebhook
+				}
+				if m.channel != want {
+					t.Errorf("%s queued on %s, want %s", m.recipient, m.channel, want)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("queued for %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package enquiries
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Submission is an enquiry as a customer sent it, from the storefront's
+// forms through GraphQL or REST.
+type Submission struct {
+	Name    string
+	Email   string
+	Phone   string
+	Message string
+	// ProductID, ProductName and ProductCategory name the product the
+	// enquiry is about, if any.
+	ProductID       string
+	ProductName     string
+	ProductCategory string
+	// Website is the honeypot: a form field hidden from people, so whoever
+	// fills it in is a bot.
+	Website string
+	// IdempotencyKey, chosen by the client, identifies one submission across
+	// retries: sending it again from the same email returns the first
+	// enquiry instead of storing another one. Keys are scoped to the email,
+	// so another sender's key never matches.
+	IdempotencyKey string
+	// IP is the address the submission came from, for throttling.
+	IP string
+}
+
+// Receipt is what Submit did with a submission.
+type Receipt struct {
+	ID int
+	// Duplicate reports a retry of a submission already stored under the
+	// same email and idempotency key.
+	Duplicate bool
+	SpamScore int
+}
+
+// SpamThreshold is the spam score from which an enquiry counts as spam: it
+// is stored, but nobody is notified and staff lists leave it out unless
+// asked for spam.
+const SpamThreshold = 50
+
+// Field lengths, matching the enquiries columns.
+const (
+	maxNameLength     = 255
+	maxEmailLength    = 255
+	maxPhoneLength    = 20
+	maxMessageLength  = 5000
+	maxProductID      = 100
+	maxProductName    = 255
+	maxCategoryLength = 100
+	maxIdempotencyKey = 200
+)
+
+// ValidationError lists what's wrong with a submission, by field.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for f := range e.Fields {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	problems := make([]string, len(fields))
+	for i, f := range fields {
+		problems[i] = e.Fields[f]
+	}
+	return strings.Join(problems, " ")
+}
+
+// ThrottledError is returned when an email address or IP address has sent
+// too many enquiries lately.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return "You've sent a lot of enquiries in a short time. Please try again later."
+}
+
+// Service stores enquiries from every channel the same way: it validates
+// them, scores them for spam, throttles senders, recognises retries and
+// queues the notifications.
+type Service struct {
+	DB     *sql.DB
+	Outbox *Outbox
+	// PerEmail and PerIP are how many enquiries one email address and one
+	// IP address may send within Window.
+	PerEmail int
+	PerIP    int
+	Window   time.Duration
+}
+
+// NewService returns a Service with the default limits: 5 enquiries an
+// hour per email address and 20 per IP address, which many people can
+// share.
+func NewService(db *sql.DB, outbox *Outbox) *Service {
+	return &Service{DB: db, Outbox: outbox, PerEmail: 5, PerIP: 20, Window: time.Hour}
+}
+
+// Submit validates and stores an enquiry and queues its notifications. It
+// returns a *ValidationError for a malformed submission and a
+// *ThrottledError when the sender is over their limit. Bots that fill in
+// the honeypot get a receipt like anyone else, so they can't tell they
+// were caught.
+func (s *Service) Submit(ctx context.Context, sub Submission) (*Receipt, error) {
+	sub = normalize(sub)
+	if err := validate(sub); err != nil {
+		return nil, err
+	}
+	score := spamScore(sub)
+
+	if sub.IdempotencyKey != "" {
+		if r, err := s.previous(ctx, s.DB, sub.Email, sub.IdempotencyKey); err != sql.ErrNoRows {
+			return r, err
+		}
+	}
+	if err := s.throttle(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var id int
+	err = tx.QueryRowContext(ctx, "INSERT INTO enquiries (name, email, phone, message, product_id, product_name, product_category, spam_score, ip, idempotency_key)"+
+		" VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, NULLIF($9, ''), NULLIF($10, ''))"+
+		" ON CONFLICT (lower(email), idempotency_key) WHERE idempotency_key IS NOT NULL DO NOTHING RETURNING id",
+		sub.Name, sub.Email, sub.Phone, sub.Message, sub.ProductID, sub.ProductName, sub.ProductCategory,
+		score, sub.IP, sub.IdempotencyKey).Scan(&id)
+	if err == sql.ErrNoRows {
+		// A retry that raced the first attempt.
+		return s.previous(ctx, tx, sub.Email, sub.IdempotencyKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if score < SpamThreshold && s.Outbox != nil {
+		if err := s.Outbox.Enqueue(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if s.Outbox != nil {
+		s.Outbox.Wake()
+	}
+	return &Receipt{ID: id, SpamScore: score}, nil
+}
+
+// previous returns the receipt of the enquiry email sent under key, or
+// sql.ErrNoRows.
+func (s *Service) previous(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}, email, key string) (*Receipt, error) {
+	r := &Receipt{Duplicate: true}
+	err := q.QueryRowContext(ctx, "SELECT id, spam_score FROM enquiries WHERE lower(email) = lower($1) AND idempotency_key = $2",
+		email, key).Scan(&r.ID, &r.SpamScore)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// throttle returns a *ThrottledError when the submission's email or IP
+// address has sent its limit within the window.
+func (s *Service) throttle(ctx context.Context, sub Submission) error {
+	checks := []struct {
+		cond  string
+		value string
+		limit int
+	}{
+		{"lower(email) = lower($1)", sub.Email, s.PerEmail},
+		{"ip = $1", sub.IP, s.PerIP},
+	}
+	for _, c := range checks {
+		if c.value == "" || c.limit <= 0 {
+			continue
+		}
+		// The oldest of the last limit enquiries in the window decides when
+		// the sender may send another.
+		var count int
+		var wait float64
+		err := s.DB.QueryRowContext(ctx, "SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM MIN(created_at) + make_interval(secs => $2) - LOCALTIMESTAMP), 0)"+
+			" FROM (SELECT created_at FROM enquiries WHERE "+c.cond+" AND created_at > LOCALTIMESTAMP - make_interval(secs => $2)"+
+			" ORDER BY created_at DESC LIMIT $3) recent", c.value, s.Window.Seconds(), c.limit).Scan(&count, &wait)
+		if err != nil {
+			return err
+		}
+		if count >= c.limit {
+			return &ThrottledError{RetryAfter: max(time.Duration(wait*float64(time.Second)), time.Second)}
+		}
+	}
+	return nil
+}
+
+// normalize trims a submission's fields.
+func normalize(sub Submission) Submission {
+	for _, f := range []*string{&sub.Name, &sub.Email, &sub.Phone, &sub.ProductID, &sub.ProductName, &sub.ProductCategory, &sub.IdempotencyKey, &sub.IP} {
+		*f = strings.TrimSpace(*f)
+	}
+	sub.Message = strings.TrimSpace(strings.ReplaceAll(sub.Message, "\r\n", "\n"))
+	return sub
+}
+
+var phoneChars = regexp.MustCompile(`^\+?[0-9 ().\-]+$`)
+
+func validate(sub Submission) error {
+	problems := map[string]string{}
+	tooLong := func(field, label, value string, limit int) bool {
+		if utf8.RuneCountInString(value) > limit {
+			problems[field] = fmt.Sprintf("%s must be at most %d characters.", label, limit)
+			return true
+		}
+		return false
+	}
+	if sub.Name == "" {
+		problems["name"] = "Please enter your name."
+	} else {
+		tooLong("name", "Your name", sub.Name, maxNameLength)
+	}
+	if sub.Email == "" {
+		problems["email"] = "Please enter your email address."
+	} else if !tooLong("email", "Your email address", sub.Email, maxEmailLength) && !validEmail(sub.Email) {
+		problems["email"] = "Please enter a valid email address."
+	}
+	if sub.Phone != "" && !tooLong("phone", "Your phone number", sub.Phone, maxPhoneLength) && !validPhone(sub.Phone) {
+		problems["phone"] = "Please enter a valid phone number."
+	}
+	if sub.Message == "" {
+		problems["message"] = "Please enter a message."
+	} else {
+		tooLong("message", "Your message", sub.Message, maxMessageLength)
+	}
+	tooLong("productId", "productId", sub.ProductID, maxProductID)
+	tooLong("productName", "productName", sub.ProductName, maxProductName)
+	tooLong("productCategory", "productCategory", sub.ProductCategory, maxCategoryLength)
+	tooLong("idempotencyKey", "The idempotency key", sub.IdempotencyKey, maxIdempotencyKey)
+	if len(problems) > 0 {
+		return &ValidationError{Fields: problems}
+	}
+	return nil
+}
+
+// validEmail accepts a bare address, without a display name, whose domain
+// has a dot.
+func validEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return false
+	}
+	_, domain, _ := strings.Cut(addr.Address, "@")
+	return strings.Contains(strings.Trim(domain, "."), ".")
+}
+
+// validPhone accepts digits with the usual separators and an optional
+// leading +, between 7 and 15 digits as E.164 allows.
+func validPhone(s string) bool {
+	if !phoneChars.MatchString(s) {
+		return false
+	}
+	digits := 0
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	return digits >= 7 && digits <= 15
+}
+
+var (
+	linkPattern = regexp.MustCompile(`(?i)https?://|www\.|\[url|<a\s`)
+	spamWords   = regexp.MustCompile(`(?i)\b(viagra|cialis|casino|betting|crypto|bitcoin|forex|loan|seo|backlinks?|escort|porn)\b`)
+)
+
+// spamScore rates how likely a submission is spam, from 0 to 100. Filling
+// in the honeypot is conclusive; otherwise links, in the message or where
+// there should be none, and words common in spam add up.
+func spamScore(sub Submission) int {
+	if sub.Website != "" {
+		return 100
+	}
+	score := 0
+	score += min(len(linkPattern.FindAllString(sub.Message, -1))*20, 60)
+	if linkPattern.MatchString(sub.Name) || linkPattern.MatchString(sub.ProductName) {
+		score += 50
+	}
+	score += min(len(spamWords.FindAllString(sub.Message+" "+sub.Name, -1))*15, 45)
+	if letters, upper := countLetters(sub.Message); letters >= 20 && upper*10 >= letters*8 {
+		// Shouting.
+		score += 10
+	}
+	return min(score, 100)
+}
+
+func countLetters(s string) (letters, upper int) {
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters, upper
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a context carrying the address a request came from,
+// for resolvers that submit enquiries.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the address WithClientIP stored in ctx, or "".
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
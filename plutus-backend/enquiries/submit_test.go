@@ -0,0 +1,256 @@
+package enquiries
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validSubmission() Submission {
+	return Submission{Name: "Ada Lovelace", Email: "ada@example.com", Phone: "+971 50 123 4567", Message: "Is the Submariner still available?"}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Submission)
+		fields []string
+	}{
+		{"valid", func(*Submission) {}, nil},
+		{"no phone", func(s *Submission) { s.Phone = "" }, nil},
+		{"nothing", func(s *Submission) { *s = Submission{} }, []string{"email", "message", "name"}},
+		{"bad email", func(s *Submission) { s.Email = "ada@localhost" }, []string{"email"}},
+		{"bad phone", func(s *Submission) { s.Phone = "call me" }, []string{"phone"}},
+		{"long name", func(s *Submission) { s.Name = strings.Repeat("a", maxNameLength+1) }, []string{"name"}},
+		{"long email", func(s *Submission) { s.Email = strings.Repeat("a", maxEmailLength) + "@example.com" }, []string{"email"}},
+		{"long phone", func(s *Submission) { s.Phone = strings.Repeat("1", maxPhoneLength+1) }, []string{"phone"}},
+		{"long message", func(s *Submission) { s.Message = strings.Repeat("a", maxMessageLength+1) }, []string{"message"}},
+		// Lengths count characters, not bytes.
+		{"message at the limit", func(s *Submission) { s.Message = strings.Repeat("é", maxMessageLength) }, nil},
+		{"long product fields", func(s *Submission) {
+			s.ProductID = strings.Repeat("1", maxProductID+1)
+			s.ProductName = strings.Repeat("a", maxProductName+1)
+			s.ProductCategory = strings.Repeat("a", maxCategoryLength+1)
+		}, []string{"productCategory", "productId", "productName"}},
+		{"long idempotency key", func(s *Submission) { s.IdempotencyKey = strings.Repeat("k", maxIdempotencyKey+1) }, []string{"idempotencyKey"}},
+	}
+	for _, tt := range tests {
+		sub := validSubmission()
+		tt.change(&sub)
+		err := validate(sub)
+		var fields []string
+		if verr, ok := err.(*ValidationError); ok {
+			fields = slices.Sorted(maps.Keys(verr.Fields))
+		} else if err != nil {
+			t.Errorf("%s: err = %v, want a *ValidationError", tt.name, err)
+			continue
+		}
+		if !slices.Equal(fields, tt.fields) {
+			t.Errorf("%s: problems with %q, want %q", tt.name, fields, tt.fields)
+		}
+	}
+}
+
+func TestValidEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{"ada@example.com", true},
+		{"ada.lovelace+shop@mail.example.co.uk", true},
+		{"ada@localhost", false},
+		{"ada@.com.", false},
+		{"ada", false},
+		{"@example.com", false},
+		{"Ada <ada@example.com>", false},
+		{"ada@example.com, bob@example.com", false},
+		{"ada@example.com\r\nBcc: bob@example.com", false},
+	}
+	for _, tt := range tests {
+		if got := validEmail(tt.email); got != tt.want {
+			t.Errorf("validEmail(%q) = %v, want %v", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestValidPhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  bool
+	}{
+		{"+971 50 123 4567", true},
+		{"(020) 7946-0958", true},
+		{"020.7946.0958", true},
+		{"1234567", true},
+		{"123456", false},
+		{"+1234567890123456", false},
+		{"+971 50 123 4567 ext 2", false},
+		{"++971501234567", false},
+		{"0501234567+", false},
+		{"call me", false},
+	}
+	for _, tt := range tests {
+		if got := validPhone(tt.phone); got != tt.want {
+			t.Errorf("validPhone(%q) = %v, want %v", tt.phone, got, tt.want)
+		}
+	}
+}
+
+func TestSpamScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Submission)
+		want   int
+	}{
+		{"clean", func(*Submission) {}, 0},
+		{"honeypot", func(s *Submission) { s.Website = "https://example.com" }, 100},
+		// The honeypot decides even for an otherwise clean message.
+		{"honeypot with a space", func(s *Submission) { s.Website = " " }, 100},
+		{"one link", func(s *Submission) { s.Message = "See https://example.com" }, 20},
+		{"many links", func(s *Submission) {
+			s.Message = "http://a.example www.b.example <a href=x> [url=y] https://c.example"
+		}, 60},
+		{"link in the name", func(s *Submission) { s.Name = "www.example.com" }, 50},
+		{"link in the product name", func(s *Submission) { s.ProductName = "<a href=x>watch</a>" }, 50},
+		{"spam word", func(s *Submission) { s.Message = "Cheap loan offers" }, 15},
+		{"spam words", func(s *Submission) { s.Message = "crypto bitcoin forex casino betting" }, 45},
+		{"word inside another", func(s *Submission) { s.Message = "I'd like the seoul edition" }, 0},
+		{"shouting", func(s *Submission) { s.Message = "IS THIS WATCH STILL AVAILABLE PLEASE" }, 10},
+		{"short and loud", func(s *Submission) { s.Message = "HELLO THERE" }, 0},
+		{"everything", func(s *Submission) {
+			s.Name = "www.casino.example"
+			s.Message = "CASINO BITCOIN LOAN HTTPS://A.EXAMPLE HTTPS://B.EXAMPLE HTTPS://C.EXAMPLE"
+		}, 100},
+	}
+	for _, tt := range tests {
+		sub := validSubmission()
+		tt.change(&sub)
+		if got := spamScore(sub); got != tt.want {
+			t.Errorf("%s: spamScore = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	const window = time.Hour
+	type sender struct{ email, ip string }
+	tests := []struct {
+		name      string
+		earlier   []sender
+		sub       sender
+		throttled bool
+	}{
+		{"first enquiry", nil, sender{"ada@example.com", "10.0.0.1"}, false},
+		{"under the email limit", []sender{{"ada@example.com", "10.0.0.1"}}, sender{"ada@example.com", "10.0.0.1"}, false},
+		{"at the email limit", []sender{{"ada@example.com", "10.0.0.1"}, {"ada@example.com", "10.0.0.2"}}, sender{"ada@example.com", "10.0.0.3"}, true},
+		{"email compared without case", []sender{{"Ada@Example.com", "10.0.0.1"}, {"ada@example.com", "10.0.0.2"}}, sender{"ADA@example.com", "10.0.0.3"}, true},
+		{"others' enquiries", []sender{{"bob@example.com", "10.0.0.1"}, {"eve@example.com", "10.0.0.2"}}, sender{"ada@example.com", "10.0.0.3"}, false},
+		{"at the IP limit", []sender{{"bob@example.com", "10.0.0.1"}, {"eve@example.com", "10.0.0.1"}, {"joe@example.com", "10.0.0.1"}}, sender{"ada@example.com", "10.0.0.1"}, true},
+		{"no IP", []sender{{"bob@example.com", ""}, {"eve@example.com", ""}, {"joe@example.com", ""}}, sender{"ada@example.com", ""}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db, _ := newFakeDB(t)
+			s := &Service{DB: db, PerEmail: 2, PerIP: 3, Window: window}
+			for _, e := range tt.earlier {
+				sub := validSubmission()
+				sub.Email, sub.IP = e.email, e.ip
+				if _, err := s.Submit(ctx, sub); err != nil {
+					t.Fatal(err)
+				}
+			}
+			sub := validSubmission()
+			sub.Email, sub.IP = tt.sub.email, tt.sub.ip
+			err := s.throttle(ctx, sub)
+			var throttled *ThrottledError
+			if !errors.As(err, &throttled) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.throttled {
+					t.Fatal("err = nil, want a *ThrottledError")
+				}
+				return
+			}
+			if !tt.throttled {
+				t.Fatalf("err = %v, want nil", err)
+			}
+			// The oldest enquiry counted leaves the window in about an hour.
+			if throttled.RetryAfter < window-time.Minute || throttled.RetryAfter > window {
+				t.Errorf("RetryAfter = %v, want about %v", throttled.RetryAfter, window)
+			}
+		})
+	}
+}
+
+func TestSubmitHoneypot(t *testing.T) {
+	db, fake := newFakeDB(t)
+	s := NewService(db, &Outbox{DB: db, Routes: Routes{DefaultRoute: {{Email: "sales@example.com"}}}})
+	sub := validSubmission()
+	sub.Website = "https://example.com"
+	r, err := s.Submit(context.Background(), sub)
+	if err != nil {
+		t.Fatalf("err = %v, want a receipt like anyone else's", err)
+	}
+	if r.SpamScore != 100 || r.Duplicate {
+		t.Errorf("receipt = %+v, want spam score 100", *r)
+	}
+	if len(fake.enquiries) != 1 || fake.enquiries[0].SpamScore != 100 {
+		t.Errorf("want the enquiry stored with spam score 100")
+	}
+	if len(fake.outbox) != 0 {
+		t.Errorf("%d notifications queued for spam, want none", len(fake.outbox))
+	}
+}
+
+func TestSubmitIdempotent(t *testing.T) {
+	ctx := context.Background()
+	db, fake := newFakeDB(t)
+	s := NewService(db, &Outbox{DB: db, Routes: Routes{DefaultRoute: {{Email: "sales@example.com"}}}})
+	s.PerEmail = 1
+	sub := validSubmission()
+	sub.IdempotencyKey = "form-1"
+
+	first, err := s.Submit(ctx, sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Duplicate {
+		t.Error("first submission reported as a duplicate")
+	}
+	queued := len(fake.outbox)
+
+	tests := []struct {
+		name      string
+		change    func(*Submission)
+		duplicate bool
+	}{
+		// A retry isn't held against the sender's limit.
+		{"retry", func(*Submission) {}, true},
+		{"retry with different case and spacing", func(s *Submission) { s.Email = "  ADA@example.com "; s.IdempotencyKey = " form-1" }, true},
+		// Keys are scoped to the sender, so another sender's key is theirs.
+		{"another sender, same key", func(s *Submission) { s.Email = "bob@example.com" }, false},
+	}
+	for _, tt := range tests {
+		retry := sub
+		tt.change(&retry)
+		r, err := s.Submit(ctx, retry)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if r.Duplicate != tt.duplicate || (r.ID == first.ID) != tt.duplicate {
+			t.Errorf("%s: receipt = %+v, want duplicate %v of enquiry %d", tt.name, *r, tt.duplicate, first.ID)
+		}
+	}
+	if len(fake.enquiries) != 2 {
+		t.Errorf("%d enquiries stored, want 2", len(fake.enquiries))
+	}
+	if len(fake.outbox) != 2*queued {
+		t.Errorf("%d notifications queued, want %d: retries mustn't notify again", len(fake.outbox), 2*queued)
+	}
+}
@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"strconv"
+	"time"
+
+	"plutus-backend/alerts"
+	"plutus-backend/graph/model"
+)
+
+var alertKinds = map[model.AlertKind]alerts.Kind{
+	model.AlertKindPriceDrop:   alerts.PriceDrop,
+	model.AlertKindBackInStock: alerts.BackInStock,
+}
+
+// alertModel converts an alert into its GraphQL form.
+func alertModel(a *alerts.Alert) *model.Alert {
+	m := &model.Alert{
+		ID:        strconv.Itoa(a.ID),
+		Category:  a.Category,
+		Product:   a.Product,
+		Size:      optional(a.Size),
+		CreatedAt: a.CreatedAt.Format(time.RFC3339),
+	}
+	for kind, k := range alertKinds {
+		if k == a.Kind {
+			m.Kind = kind
+		}
+	}
+	if a.TargetPrice != nil {
+		m.TargetPrice = &model.Money{Amount: *a.TargetPrice, Currency: a.Currency}
+	}
+	if a.TriggeredAt != nil {
+		triggered := a.TriggeredAt.Format(time.RFC3339)
+		m.TriggeredAt = &triggered
+	}
+	return m
+}
@@ -0,0 +1,33 @@
+package graph
+
+import (
+	"plutus-backend/brands"
+	"plutus-backend/catalog"
+	"plutus-backend/graph/model"
+)
+
+// brandModel converts a canonical brand into its GraphQL form. Its products
+// are loaded by the Brand.products resolver.
+func brandModel(b *brands.Brand) *model.Brand {
+	m := &model.Brand{
+		Slug:          b.Slug,
+		Name:          b.Name,
+		Logo:          optional(b.Logo),
+		Description:   optional(b.Description),
+		Country:       optional(b.Country),
+		ProductCounts: make([]*model.CategoryCount, 0, len(catalog.Categories())),
+		ProductCount:  b.ProductCount(),
+	}
+	for _, c := range catalog.Categories() {
+		m.ProductCounts = append(m.ProductCounts, &model.CategoryCount{Category: c.Key, Count: b.Counts[c.Key]})
+	}
+	return m
+}
+
+// optional returns nil for an empty string.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
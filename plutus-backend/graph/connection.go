@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"context"
+	"sort"
+
+	"github.com/99designs/gqlgen/graphql"
+
+	"plutus-backend/catalog"
+	"plutus-backend/currency"
+	"plutus-backend/graph/model"
+	"plutus-backend/search"
+)
+
+// pageParams wraps list arguments for a connection query. The total count
+// costs an extra query, so it is only computed when the client selected it.
+func pageParams(ctx context.Context, p catalog.ListParams, first *int, after *string) catalog.PageParams {
+	return catalog.PageParams{
+		ListParams: p,
+		First:      first,
+		After:      after,
+		WithTotal:  fieldRequested(ctx, "totalCount"),
+	}
+}
+
+// facetsFor counts a connection's filter options, or returns nil when the
+// client didn't select facets. Price buckets are bounded and reported in
+// boundsCurrency, so they can be passed back as minPrice and maxPrice.
+func facetsFor[T any](ctx context.Context, r *Resolver, repo *catalog.Repository[T], p catalog.ListParams) (*model.Facets, error) {
+	if !fieldRequested(ctx, "facets") {
+		return nil, nil
+	}
+	code, err := currency.Normalize(boundsCurrency(ctx, repo.Category))
+	if err != nil {
+		return nil, err
+	}
+	shown, err := r.priceBucketBounds(code)
+	if err != nil {
+		return nil, err
+	}
+	stored := make([]float64, len(shown))
+	for i, b := range shown {
+		if stored[i], err = r.Rates.Convert(b, code, repo.Category.PriceCurrency()); err != nil {
+			return nil, err
+		}
+	}
+	facets, err := repo.Facets(ctx, p, stored)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range facets.PriceBuckets {
+		b.Currency = code
+		for _, bound := range []*float64{b.Min, b.Max} {
+			if bound != nil {
+				*bound = shown[sort.SearchFloat64s(stored, *bound)]
+			}
+		}
+	}
+	return facets, nil
+}
+
+// fieldRequested reports whether the current field's selection set
+// includes name.
+func fieldRequested(ctx context.Context, name string) bool {
+	for _, field := range graphql.CollectAllFields(ctx) {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// connection converts a catalog page into Relay edges and page info.
+func connection[T any, E any](page *catalog.Page[T], edge func(cursor string, node *T) E) ([]E, *model.PageInfo) {
+	edges := make([]E, 0, len(page.Edges))
+	for _, e := range page.Edges {
+		edges = append(edges, edge(e.Cursor, e.Node))
+	}
+	info := &model.PageInfo{
+		HasNextPage:     page.HasNextPage,
+		HasPreviousPage: page.HasPreviousPage,
+	}
+	if n := len(page.Edges); n > 0 {
+		info.StartCursor = &page.Edges[0].Cursor
+		info.EndCursor = &page.Edges[n-1].Cursor
+	}
+	return edges, info
+}
+
+// searchConnection converts a page of search hits into a SearchConnection.
+func searchConnection(page *search.Page) *model.SearchConnection {
+	conn := &model.SearchConnection{
+		Edges:          make([]*model.SearchEdge, 0, len(page.Hits)),
+		PageInfo:       &model.PageInfo{HasNextPage: page.HasNextPage, HasPreviousPage: page.HasPreviousPage},
+		TotalCount:     page.TotalCount,
+		CategoryCounts: page.CategoryCounts,
+	}
+	for _, h := range page.Hits {
+		conn.Edges = append(conn.Edges, &model.SearchEdge{
+			Cursor: h.Cursor,
+			Node:   &model.SearchResult{Category: h.Category, Rank: h.Rank, Product: h.Product},
+		})
+	}
+	if n := len(page.Hits); n > 0 {
+		conn.PageInfo.StartCursor = &page.Hits[0].Cursor
+		conn.PageInfo.EndCursor = &page.Hits[n-1].Cursor
+	}
+	if page.DidYouMean != "" {
+		conn.DidYouMean = &page.DidYouMean
+	}
+	if conn.CategoryCounts == nil {
+		conn.CategoryCounts = []*model.CategoryCount{}
+	}
+	return conn
+}
@@ -0,0 +1,144 @@
+package graph
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"plutus-backend/enquiries"
+	"plutus-backend/graph/model"
+)
+
+var enquiryStatuses = map[model.EnquiryStatus]enquiries.Status{
+	model.EnquiryStatusNew:       enquiries.New,
+	model.EnquiryStatusContacted: enquiries.Contacted,
+	model.EnquiryStatusQuoted:    enquiries.Quoted,
+	model.EnquiryStatusWon:       enquiries.Won,
+	model.EnquiryStatusLost:      enquiries.Lost,
+}
+
+// enquiryModel converts an enquiry into its GraphQL form.
+func enquiryModel(e *enquiries.Enquiry) *model.Enquiry {
+	m := &model.Enquiry{
+		ID:              strconv.Itoa(e.ID),
+		Name:            e.Name,
+		Email:           e.Email,
+		Phone:           optional(e.Phone),
+		Message:         e.Message,
+		ProductID:       optional(e.ProductID),
+		ProductName:     optional(e.ProductName),
+		ProductCategory: optional(e.ProductCategory),
+		Product:         e.Product,
+		SpamScore:       e.SpamScore,
+		Notes:           make([]*model.EnquiryNote, len(e.Notes)),
+		CreatedAt:       e.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       e.UpdatedAt.Format(time.RFC3339),
+	}
+	for status, s := range enquiryStatuses {
+		if s == e.Status {
+			m.Status = status
+		}
+	}
+	if e.Assignee != nil {
+		m.Assignee = userModel(e.Assignee)
+	}
+	if e.FollowUpOn != nil {
+		on := e.FollowUpOn.Format(time.DateOnly)
+		m.FollowUpOn = &on
+	}
+	for i, n := range e.Notes {
+		m.Notes[i] = enquiryNoteModel(n)
+	}
+	return m
+}
+
+// enquiryNoteModel converts a note on an enquiry into its GraphQL form.
+func enquiryNoteModel(n *enquiries.Note) *model.EnquiryNote {
+	m := &model.EnquiryNote{
+		ID:        strconv.Itoa(n.ID),
+		Body:      n.Body,
+		CreatedAt: n.CreatedAt.Format(time.RFC3339),
+	}
+	if n.Author != nil {
+		m.Author = userModel(n.Author)
+	}
+	return m
+}
+
+// enquiryFilter converts the enquiries query's arguments.
+func enquiryFilter(f *model.EnquiryFilter, limit, offset *int) (enquiries.Filter, error) {
+	var out enquiries.Filter
+	if limit != nil {
+		out.Limit = *limit
+	}
+	if offset != nil {
+		out.Offset = *offset
+	}
+	if f == nil {
+		return out, nil
+	}
+	for _, status := range f.Status {
+		out.Statuses = append(out.Statuses, enquiryStatuses[status])
+	}
+	if f.Category != nil {
+		out.Category = *f.Category
+	}
+	if f.ProductID != nil {
+		out.ProductID = *f.ProductID
+	}
+	if f.Assignee != nil {
+		out.AssigneeEmail = *f.Assignee
+	}
+	out.Unassigned = f.Unassigned != nil && *f.Unassigned
+	out.FollowUpDue = f.FollowUpDue != nil && *f.FollowUpDue
+	out.IncludeSpam = f.IncludeSpam != nil && *f.IncludeSpam
+	if f.From != nil && *f.From != "" {
+		t, err := parseTime("from", *f.From)
+		if err != nil {
+			return out, err
+		}
+		out.From = t
+	}
+	if f.To != nil && *f.To != "" {
+		t, err := parseTime("to", *f.To)
+		if err != nil {
+			return out, err
+		}
+		// A date names the whole day.
+		if _, err := time.Parse(time.DateOnly, *f.To); err == nil {
+			t = t.AddDate(0, 0, 1)
+		}
+		out.To = t
+	}
+	return out, nil
+}
+
+// enquiryID reads an enquiry's GraphQL id.
+func enquiryID(id string) (int, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, enquiries.ErrNotFound
+	}
+	return n, nil
+}
+
+// enquiry loads an enquiry in its GraphQL form, after a mutation returning
+// err.
+func (r *Resolver) enquiry(ctx context.Context, id int, err error) (*model.Enquiry, error) {
+	if err != nil {
+		return nil, err
+	}
+	e, err := enquiries.Get(ctx, r.Catalog, id)
+	if err != nil {
+		return nil, err
+	}
+	return enquiryModel(e), nil
+}
+
+// value returns the string p points to, or "" for nil.
+func value(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
@@ -5,16 +5,6 @@ import (
 	"strconv"
 )
 
-type Enquiry struct {
-	Name            string
-	Email           string
-	Phone           *string
-	Message         string
-	ProductID       *string
-	ProductName     *string
-	ProductCategory *string
-}
-
 // Custom unmarshal to handle both string and number for price
 func (v *PerfumeVariant) UnmarshalJSON(data []byte) error {
 	type Alias PerfumeVariant
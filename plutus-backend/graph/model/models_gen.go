@@ -2,6 +2,19 @@
 
 package model
 
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"plutus-backend/auth"
+	"strconv"
+)
+
+// Any product, as returned by cross-category search.
+type Product interface {
+	IsProduct()
+}
+
 type Accessory struct {
 	ID          string       `json:"id"`
 	Brand       string       `json:"brand"`
@@ -14,6 +27,56 @@ type Accessory struct {
 	ProductLink string       `json:"productLink"`
 	SellerName  *string      `json:"sellerName,omitempty"`
 	SellerURL   *string      `json:"sellerUrl,omitempty"`
+	// The lowest size price, converted like SizePrice.money.
+	Price *Money `json:"price,omitempty"`
+	// Recorded prices, oldest first within each size. size restricts them to
+	// one size; prices are converted like SizePrice.money.
+	PriceHistory []*PricePoint `json:"priceHistory"`
+}
+
+func (Accessory) IsProduct() {}
+
+type AccessoryConnection struct {
+	Edges      []*AccessoryEdge `json:"edges"`
+	PageInfo   *PageInfo        `json:"pageInfo"`
+	TotalCount int              `json:"totalCount"`
+	Facets     *Facets          `json:"facets"`
+}
+
+type AccessoryEdge struct {
+	Cursor string     `json:"cursor"`
+	Node   *Accessory `json:"node"`
+}
+
+// An accessory to create or update, matched like SneakerInput.
+type AccessoryInput struct {
+	ID          *string           `json:"id,omitempty"`
+	Brand       string            `json:"brand"`
+	ProductName string            `json:"productName"`
+	Subcategory string            `json:"subcategory"`
+	Gender      string            `json:"gender"`
+	SizePrices  []*SizePriceInput `json:"sizePrices"`
+	Images      []string          `json:"images"`
+	// Defaults to true.
+	InStock     *bool   `json:"inStock,omitempty"`
+	ProductLink string  `json:"productLink"`
+	SellerName  *string `json:"sellerName,omitempty"`
+	SellerURL   *string `json:"sellerUrl,omitempty"`
+}
+
+// A signed-in user's request to be emailed when a product's price falls or when
+// it comes back in stock. An alert fires once, setting triggeredAt. product is
+// null while the product isn't listed, such as during a re-seed.
+type Alert struct {
+	ID       string    `json:"id"`
+	Kind     AlertKind `json:"kind"`
+	Category string    `json:"category"`
+	Product  Product   `json:"product,omitempty"`
+	Size     *string   `json:"size,omitempty"`
+	// Notify at or below this price. Without one, any drop notifies.
+	TargetPrice *Money  `json:"targetPrice,omitempty"`
+	CreatedAt   string  `json:"createdAt"`
+	TriggeredAt *string `json:"triggeredAt,omitempty"`
 }
 
 type Apparel struct {
@@ -28,11 +91,174 @@ type Apparel struct {
 	ProductLink string       `json:"productLink"`
 	SellerName  *string      `json:"sellerName,omitempty"`
 	SellerURL   *string      `json:"sellerUrl,omitempty"`
+	// The lowest size price, converted like SizePrice.money.
+	Price *Money `json:"price,omitempty"`
+	// Recorded prices, oldest first within each size. size restricts them to
+	// one size; prices are converted like SizePrice.money.
+	PriceHistory []*PricePoint `json:"priceHistory"`
+}
+
+func (Apparel) IsProduct() {}
+
+type ApparelConnection struct {
+	Edges      []*ApparelEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int            `json:"totalCount"`
+	Facets     *Facets        `json:"facets"`
+}
+
+type ApparelEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Apparel `json:"node"`
+}
+
+// An apparel item to create or update, matched like SneakerInput.
+type ApparelInput struct {
+	ID          *string           `json:"id,omitempty"`
+	Brand       string            `json:"brand"`
+	ProductName string            `json:"productName"`
+	Subcategory string            `json:"subcategory"`
+	Gender      string            `json:"gender"`
+	SizePrices  []*SizePriceInput `json:"sizePrices"`
+	Images      []string          `json:"images"`
+	// Defaults to true.
+	InStock     *bool   `json:"inStock,omitempty"`
+	ProductLink string  `json:"productLink"`
+	SellerName  *string `json:"sellerName,omitempty"`
+	SellerURL   *string `json:"sellerUrl,omitempty"`
+}
+
+// A product taken off the storefront by archiveProduct.
+type ArchivedProduct struct {
+	Category    string `json:"category"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	ProductLink string `json:"productLink"`
+	ArchivedAt  string `json:"archivedAt"`
+}
+
+// A canonical brand. Every spelling of the brand in the catalog, such as
+// "Hermès", "HERMES" and "Hermes", belongs to the same Brand.
+type Brand struct {
+	Slug        string  `json:"slug"`
+	Name        string  `json:"name"`
+	Logo        *string `json:"logo,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Country     *string `json:"country,omitempty"`
+	// Products per category, for every category, in category order.
+	ProductCounts []*CategoryCount `json:"productCounts"`
+	ProductCount  int              `json:"productCount"`
+	// The brand's products across every category, or only the given category
+	// keys, ordered by category and then id.
+	Products *SearchConnection `json:"products"`
+}
+
+type CategoryCount struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// A customer's enquiry and its progress through the sales pipeline.
+type Enquiry struct {
+	ID     string        `json:"id"`
+	Status EnquiryStatus `json:"status"`
+	// From 0 to 100; enquiries from 50 up are spam and notified to nobody.
+	SpamScore       int     `json:"spamScore"`
+	Name            string  `json:"name"`
+	Email           string  `json:"email"`
+	Phone           *string `json:"phone,omitempty"`
+	Message         string  `json:"message"`
+	ProductID       *string `json:"productId,omitempty"`
+	ProductName     *string `json:"productName,omitempty"`
+	ProductCategory *string `json:"productCategory,omitempty"`
+	// The product productId and productCategory name, while it is listed. Null
+	// for enquiries about no product in particular.
+	Product  Product `json:"product,omitempty"`
+	Assignee *User   `json:"assignee,omitempty"`
+	// YYYY-MM-DD.
+	FollowUpOn *string `json:"followUpOn,omitempty"`
+	// Oldest first.
+	Notes     []*EnquiryNote `json:"notes"`
+	CreatedAt string         `json:"createdAt"`
+	UpdatedAt string         `json:"updatedAt"`
+}
+
+// Narrows the enquiries query; every field given must match. category is a
+// category key or product type. from and to are RFC 3339 times or YYYY-MM-DD
+// dates bounding when enquiries were made; a date for to includes that day.
+type EnquiryFilter struct {
+	Status    []EnquiryStatus `json:"status,omitempty"`
+	Category  *string         `json:"category,omitempty"`
+	ProductID *string         `json:"productId,omitempty"`
+	// The email of the member of staff the enquiries are assigned to.
+	Assignee   *string `json:"assignee,omitempty"`
+	Unassigned *bool   `json:"unassigned,omitempty"`
+	// Open enquiries whose follow-up date has come.
+	FollowUpDue *bool `json:"followUpDue,omitempty"`
+	// Also list enquiries scored as spam, which are otherwise left out.
+	IncludeSpam *bool   `json:"includeSpam,omitempty"`
+	From        *string `json:"from,omitempty"`
+	To          *string `json:"to,omitempty"`
+}
+
+// An internal note on an enquiry, seen only by staff.
+type EnquiryNote struct {
+	ID   string `json:"id"`
+	Body string `json:"body"`
+	// Null once the author's account is deleted.
+	Author    *User  `json:"author,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+type EnquiryPage struct {
+	// Newest first.
+	Items []*Enquiry `json:"items"`
+	// How many enquiries match the filter in all.
+	TotalCount int `json:"totalCount"`
+}
+
+// How many units of currency one Indian rupee buys.
+type ExchangeRate struct {
+	Currency  string  `json:"currency"`
+	Rate      float64 `json:"rate"`
+	UpdatedAt string  `json:"updatedAt"`
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Filter options with product counts. Each facet is counted with every active
+// filter applied except its own, so the counts show what selecting that option
+// would return. Facets a category doesn't have are empty.
+type Facets struct {
+	Brands            []*FacetValue  `json:"brands"`
+	Subcategories     []*FacetValue  `json:"subcategories"`
+	Genders           []*FacetValue  `json:"genders"`
+	Sizes             []*FacetValue  `json:"sizes"`
+	Colors            []*FacetValue  `json:"colors"`
+	Concentrations    []*FacetValue  `json:"concentrations"`
+	FragranceFamilies []*FacetValue  `json:"fragranceFamilies"`
+	PriceBuckets      []*PriceBucket `json:"priceBuckets"`
+}
+
+// An amount of money in an ISO 4217 currency, rounded to two decimals.
+type Money struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
 }
 
 type Mutation struct {
 }
 
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
 type Perfume struct {
 	ID              string            `json:"id"`
 	Brand           string            `json:"brand"`
@@ -45,19 +271,120 @@ type Perfume struct {
 	URL             string            `json:"url"`
 	SellerName      *string           `json:"sellerName,omitempty"`
 	SellerURL       *string           `json:"sellerUrl,omitempty"`
+	// The lowest variant price, converted like SizePrice.money.
+	Price *Money `json:"price,omitempty"`
+	// Recorded prices, oldest first within each size. size restricts them to
+	// one size; prices are converted like SizePrice.money.
+	PriceHistory []*PricePoint `json:"priceHistory"`
+}
+
+func (Perfume) IsProduct() {}
+
+type PerfumeConnection struct {
+	Edges      []*PerfumeEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int            `json:"totalCount"`
+	Facets     *Facets        `json:"facets"`
+}
+
+type PerfumeEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Perfume `json:"node"`
+}
+
+// A perfume to create or update, matched like SneakerInput by id or url.
+type PerfumeInput struct {
+	ID              *string           `json:"id,omitempty"`
+	Brand           string            `json:"brand"`
+	Title           string            `json:"title"`
+	FragranceFamily *string           `json:"fragranceFamily,omitempty"`
+	Concentration   *string           `json:"concentration,omitempty"`
+	Subcategory     *string           `json:"subcategory,omitempty"`
+	Variants        []*SizePriceInput `json:"variants"`
+	Images          []string          `json:"images"`
+	URL             string            `json:"url"`
+	SellerName      *string           `json:"sellerName,omitempty"`
+	SellerURL       *string           `json:"sellerUrl,omitempty"`
 }
 
 type PerfumeVariant struct {
 	Size  *string  `json:"size,omitempty"`
 	Price *float64 `json:"price,omitempty"`
+	// price, converted like SizePrice.money.
+	Money *Money `json:"money,omitempty"`
+}
+
+// A price range and how many products fall into it. min is inclusive and max
+// exclusive; a null bound is open-ended. Bounds are in currency, as are the
+// minPrice and maxPrice arguments they are meant to be passed back as: the
+// X-Currency display currency when the request sends that header, and
+// otherwise the currency the category stores prices in (AED for watches, INR
+// for the rest).
+type PriceBucket struct {
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+	Currency string   `json:"currency"`
+	Count    int      `json:"count"`
+}
+
+// A product, size or variant whose price fell. previousPrice was in effect at
+// the start of the window and price is the current one.
+type PriceDrop struct {
+	Category      string  `json:"category"`
+	Product       Product `json:"product"`
+	Size          *string `json:"size,omitempty"`
+	PreviousPrice *Money  `json:"previousPrice"`
+	Price         *Money  `json:"price"`
+	PercentOff    float64 `json:"percentOff"`
+	DroppedAt     string  `json:"droppedAt"`
+}
+
+// A product's price for one size at the time it was recorded. size is null
+// for products without sizes.
+type PricePoint struct {
+	Size       *string `json:"size,omitempty"`
+	Price      *Money  `json:"price"`
+	RecordedAt string  `json:"recordedAt"`
 }
 
 type Query struct {
 }
 
+type SearchConnection struct {
+	Edges          []*SearchEdge    `json:"edges"`
+	PageInfo       *PageInfo        `json:"pageInfo"`
+	TotalCount     int              `json:"totalCount"`
+	CategoryCounts []*CategoryCount `json:"categoryCounts"`
+	// A spelling-corrected query, set when the original matched only a few
+	// products. The results already include the corrected query's matches.
+	DidYouMean *string `json:"didYouMean,omitempty"`
+}
+
+type SearchEdge struct {
+	Cursor string        `json:"cursor"`
+	Node   *SearchResult `json:"node"`
+}
+
+// A search match. rank is the full-text relevance score results are ordered
+// by; it is 0 for every result when the query has no words.
+type SearchResult struct {
+	Category string  `json:"category"`
+	Rank     float64 `json:"rank"`
+	Product  Product `json:"product"`
+}
+
 type SizePrice struct {
 	Size  string  `json:"size"`
 	Price float64 `json:"price"`
+	// price in the given currency. Without one, the X-Currency request header
+	// picks it, and failing that INR.
+	Money *Money `json:"money"`
+}
+
+// A size and its price, as written by the upsert mutations.
+type SizePriceInput struct {
+	Size  string  `json:"size"`
+	Price float64 `json:"price"`
 }
 
 type Sneaker struct {
@@ -70,6 +397,71 @@ type Sneaker struct {
 	ProductLink string       `json:"productLink"`
 	SellerName  *string      `json:"sellerName,omitempty"`
 	SellerURL   *string      `json:"sellerUrl,omitempty"`
+	// The lowest size price, converted like SizePrice.money.
+	Price *Money `json:"price,omitempty"`
+	// Recorded prices, oldest first within each size. size restricts them to
+	// one size; prices are converted like SizePrice.money.
+	PriceHistory []*PricePoint `json:"priceHistory"`
+}
+
+func (Sneaker) IsProduct() {}
+
+type SneakerConnection struct {
+	Edges      []*SneakerEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int            `json:"totalCount"`
+	Facets     *Facets        `json:"facets"`
+}
+
+type SneakerEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Sneaker `json:"node"`
+}
+
+// A sneaker to create or update. With an id the upsert updates that sneaker;
+// without one it updates the sneaker with the same productLink or creates a
+// new one. Images are http(s) URLs or storefront paths, at least one; sizes
+// are listed once each, with positive prices.
+type SneakerInput struct {
+	ID          *string           `json:"id,omitempty"`
+	Brand       string            `json:"brand"`
+	ProductName string            `json:"productName"`
+	SizePrices  []*SizePriceInput `json:"sizePrices"`
+	Images      []string          `json:"images"`
+	SoldOut     *bool             `json:"soldOut,omitempty"`
+	ProductLink string            `json:"productLink"`
+	SellerName  *string           `json:"sellerName,omitempty"`
+	SellerURL   *string           `json:"sellerUrl,omitempty"`
+}
+
+// A product in the signed-in user's stash.
+type StashItem struct {
+	Category string  `json:"category"`
+	Product  Product `json:"product"`
+	AddedAt  string  `json:"addedAt"`
+}
+
+// A product as the storefront names it. category is a category key such as
+// "sneakers" or a product type such as "sneaker".
+type StashItemInput struct {
+	Category  string `json:"category"`
+	ProductID string `json:"productId"`
+}
+
+// An autocomplete entry: a brand, subcategory or product name in one category,
+// with how many products carry it there.
+type Suggestion struct {
+	Kind     SuggestionKind `json:"kind"`
+	Text     string         `json:"text"`
+	Category string         `json:"category"`
+	Count    int            `json:"count"`
+}
+
+type User struct {
+	ID       string    `json:"id"`
+	FullName string    `json:"fullName"`
+	Email    string    `json:"email"`
+	Role     auth.Role `json:"role"`
 }
 
 type Watch struct {
@@ -84,4 +476,215 @@ type Watch struct {
 	SellerName  *string  `json:"sellerName,omitempty"`
 	SellerURL   *string  `json:"sellerUrl,omitempty"`
 	Gender      *string  `json:"gender,omitempty"`
+	// salePrice, converted like SizePrice.money.
+	Price *Money `json:"price"`
+	// marketPrice, when it holds an amount, converted like SizePrice.money.
+	RetailPrice *Money `json:"retailPrice,omitempty"`
+	// Recorded prices, oldest first within each size. size restricts them to
+	// one size; prices are converted like SizePrice.money.
+	PriceHistory []*PricePoint `json:"priceHistory"`
+}
+
+func (Watch) IsProduct() {}
+
+type WatchConnection struct {
+	Edges      []*WatchEdge `json:"edges"`
+	PageInfo   *PageInfo    `json:"pageInfo"`
+	TotalCount int          `json:"totalCount"`
+	Facets     *Facets      `json:"facets"`
+}
+
+type WatchEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Watch `json:"node"`
+}
+
+// A watch to create or update, matched like SneakerInput by id or link.
+type WatchInput struct {
+	ID    *string `json:"id,omitempty"`
+	Brand string  `json:"brand"`
+	Name  string  `json:"name"`
+	Color *string `json:"color,omitempty"`
+	// In dirhams, like Watch.salePrice.
+	SalePrice   float64  `json:"salePrice"`
+	MarketPrice *string  `json:"marketPrice,omitempty"`
+	Images      []string `json:"images"`
+	Link        string   `json:"link"`
+	SellerName  *string  `json:"sellerName,omitempty"`
+	SellerURL   *string  `json:"sellerUrl,omitempty"`
+	Gender      *string  `json:"gender,omitempty"`
+}
+
+type AlertKind string
+
+const (
+	AlertKindPriceDrop   AlertKind = "PRICE_DROP"
+	AlertKindBackInStock AlertKind = "BACK_IN_STOCK"
+)
+
+var AllAlertKind = []AlertKind{
+	AlertKindPriceDrop,
+	AlertKindBackInStock,
+}
+
+func (e AlertKind) IsValid() bool {
+	switch e {
+	case AlertKindPriceDrop, AlertKindBackInStock:
+		return true
+	}
+	return false
+}
+
+func (e AlertKind) String() string {
+	return string(e)
+}
+
+func (e *AlertKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AlertKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AlertKind", str)
+	}
+	return nil
+}
+
+func (e AlertKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *AlertKind) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e AlertKind) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+// Where an enquiry is in the sales pipeline.
+type EnquiryStatus string
+
+const (
+	EnquiryStatusNew       EnquiryStatus = "NEW"
+	EnquiryStatusContacted EnquiryStatus = "CONTACTED"
+	EnquiryStatusQuoted    EnquiryStatus = "QUOTED"
+	EnquiryStatusWon       EnquiryStatus = "WON"
+	EnquiryStatusLost      EnquiryStatus = "LOST"
+)
+
+var AllEnquiryStatus = []EnquiryStatus{
+	EnquiryStatusNew,
+	EnquiryStatusContacted,
+	EnquiryStatusQuoted,
+	EnquiryStatusWon,
+	EnquiryStatusLost,
+}
+
+func (e EnquiryStatus) IsValid() bool {
+	switch e {
+	case EnquiryStatusNew, EnquiryStatusContacted, EnquiryStatusQuoted, EnquiryStatusWon, EnquiryStatusLost:
+		return true
+	}
+	return false
+}
+
+func (e EnquiryStatus) String() string {
+	return string(e)
+}
+
+func (e *EnquiryStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = EnquiryStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid EnquiryStatus", str)
+	}
+	return nil
+}
+
+func (e EnquiryStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *EnquiryStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e EnquiryStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type SuggestionKind string
+
+const (
+	SuggestionKindBrand       SuggestionKind = "BRAND"
+	SuggestionKindSubcategory SuggestionKind = "SUBCATEGORY"
+	SuggestionKindProduct     SuggestionKind = "PRODUCT"
+)
+
+var AllSuggestionKind = []SuggestionKind{
+	SuggestionKindBrand,
+	SuggestionKindSubcategory,
+	SuggestionKindProduct,
+}
+
+func (e SuggestionKind) IsValid() bool {
+	switch e {
+	case SuggestionKindBrand, SuggestionKindSubcategory, SuggestionKindProduct:
+		return true
+	}
+	return false
+}
+
+func (e SuggestionKind) String() string {
+	return string(e)
+}
+
+func (e *SuggestionKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SuggestionKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SuggestionKind", str)
+	}
+	return nil
+}
+
+func (e SuggestionKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *SuggestionKind) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e SuggestionKind) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,154 @@
+package graph
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+
+	"plutus-backend/catalog"
+	"plutus-backend/currency"
+	"plutus-backend/graph/model"
+)
+
+// productCategories maps product types to the category whose currency
+// their prices are stored in.
+var productCategories = map[string]*catalog.Category{
+	"Sneaker":   catalog.Sneakers,
+	"Watch":     catalog.Watches,
+	"Perfume":   catalog.Perfumes,
+	"Accessory": catalog.Accessories,
+	"Apparel":   catalog.Apparel,
+}
+
+// money converts an amount stored in c's currency into the currency the
+// client asked for: the field's currency argument, else the X-Currency
+// header, else the base currency.
+func (r *Resolver) money(ctx context.Context, c *catalog.Category, amount float64, code *string) (*model.Money, error) {
+	return r.convert(ctx, c.PriceCurrency(), amount, code)
+}
+
+// convert is money for an amount stored in the currency from.
+func (r *Resolver) convert(ctx context.Context, from string, amount float64, code *string) (*model.Money, error) {
+	to, err := currency.Normalize(displayCurrency(ctx, code))
+	if err != nil {
+		return nil, err
+	}
+	converted, err := r.Rates.Convert(amount, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Money{Amount: converted, Currency: to}, nil
+}
+
+// displayCurrency is the currency the client asked for: code, else the
+// X-Currency header, else the base currency.
+func displayCurrency(ctx context.Context, code *string) string {
+	if code != nil && *code != "" {
+		return *code
+	}
+	return currency.FromContext(ctx)
+}
+
+// boundsCurrency is the currency minPrice and maxPrice arguments, and the
+// price buckets they come from, are in for c: the X-Currency header's when
+// the request sends one, so they match the prices shown, and otherwise the
+// currency c stores prices in, as they were before display currencies.
+func boundsCurrency(ctx context.Context, c *catalog.Category) string {
+	if code, ok := currency.Requested(ctx); ok {
+		return code
+	}
+	return c.PriceCurrency()
+}
+
+// inStoredCurrency converts p's minPrice and maxPrice from boundsCurrency
+// into the currency c stores prices in.
+func (r *Resolver) inStoredCurrency(ctx context.Context, c *catalog.Category, p catalog.ListParams) (catalog.ListParams, error) {
+	from, err := currency.Normalize(boundsCurrency(ctx, c))
+	if err != nil {
+		return p, err
+	}
+	for _, bound := range []**float64{&p.MinPrice, &p.MaxPrice} {
+		if *bound == nil {
+			continue
+		}
+		stored, err := r.Rates.Convert(**bound, from, c.PriceCurrency())
+		if err != nil {
+			return p, err
+		}
+		*bound = &stored
+	}
+	return p, nil
+}
+
+// priceBucketBounds returns catalog.PriceBucketBounds converted into the
+// currency code, rounded to two significant figures so the buckets read as
+// round amounts.
+func (r *Resolver) priceBucketBounds(code string) ([]float64, error) {
+	bounds := make([]float64, len(catalog.PriceBucketBounds))
+	for i, b := range catalog.PriceBucketBounds {
+		converted, err := r.Rates.Convert(b, currency.Base, code)
+		if err != nil {
+			return nil, err
+		}
+		if converted > 0 {
+			unit := math.Pow(10, math.Floor(math.Log10(converted))-1)
+			converted = math.Round(converted/unit) * unit
+		}
+		bounds[i] = converted
+	}
+	return bounds, nil
+}
+
+// enclosingCurrency returns the stored currency of the product a nested
+// price, such as a SizePrice, was selected from.
+func enclosingCurrency(ctx context.Context) string {
+	for fc := graphql.GetFieldContext(ctx); fc != nil; fc = fc.Parent {
+		if c, ok := productCategories[fc.Object]; ok {
+			return c.PriceCurrency()
+		}
+	}
+	return currency.Base
+}
+
+// lowestSizePrice returns the cheapest size's price, or false when there
+// are no sizes.
+func lowestSizePrice(sizePrices []*model.SizePrice) (float64, bool) {
+	lowest, ok := 0.0, false
+	for _, sp := range sizePrices {
+		if !ok || sp.Price < lowest {
+			lowest, ok = sp.Price, true
+		}
+	}
+	return lowest, ok
+}
+
+// lowestVariantPrice returns the cheapest priced variant's price, or false
+// when no variant has a price.
+func lowestVariantPrice(variants []*model.PerfumeVariant) (float64, bool) {
+	lowest, ok := 0.0, false
+	for _, v := range variants {
+		if v.Price != nil && (!ok || *v.Price < lowest) {
+			lowest, ok = *v.Price, true
+		}
+	}
+	return lowest, ok
+}
+
+// parseAmount reads a free-text price such as "AED6,500.00". It reports
+// false for text without an amount, such as "N/A".
+func parseAmount(s string) (float64, bool) {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' || r == '.' {
+			return r
+		}
+		return -1
+	}, s)
+	amount, err := strconv.ParseFloat(digits, 64)
+	if err != nil || amount <= 0 {
+		return 0, false
+	}
+	return amount, true
+}
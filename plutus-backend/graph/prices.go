@@ -0,0 +1,66 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"plutus-backend/catalog"
+	"plutus-backend/graph/model"
+	"plutus-backend/prices"
+)
+
+// priceHistory loads a product's recorded prices and converts them into the
+// requested currency.
+func (r *Resolver) priceHistory(ctx context.Context, c *catalog.Category, id string, size, code *string) ([]*model.PricePoint, error) {
+	s := ""
+	if size != nil {
+		s = *size
+	}
+	points, err := prices.History(ctx, r.DB, c, id, s)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*model.PricePoint, len(points))
+	for i, p := range points {
+		price, err := r.convert(ctx, p.Currency, p.Price, code)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = &model.PricePoint{Size: optional(p.Size), Price: price, RecordedAt: p.RecordedAt.Format(time.RFC3339)}
+	}
+	return out, nil
+}
+
+// priceDrop converts a drop into its GraphQL form.
+func (r *Resolver) priceDrop(ctx context.Context, d prices.Drop, code *string) (*model.PriceDrop, error) {
+	previous, err := r.convert(ctx, d.Currency, d.PreviousPrice, code)
+	if err != nil {
+		return nil, err
+	}
+	price, err := r.convert(ctx, d.Currency, d.Price, code)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PriceDrop{
+		Category:      d.Category,
+		Product:       d.Product,
+		Size:          optional(d.Size),
+		PreviousPrice: previous,
+		Price:         price,
+		PercentOff:    d.Percent,
+		DroppedAt:     d.DroppedAt.Format(time.RFC3339),
+	}, nil
+}
+
+// parseTime reads the argument named field, an RFC 3339 time or a
+// YYYY-MM-DD date.
+func parseTime(field, s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time or a YYYY-MM-DD date, got %q", field, s)
+}
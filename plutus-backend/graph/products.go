@@ -0,0 +1,30 @@
+package graph
+
+import (
+	"fmt"
+	"time"
+
+	"plutus-backend/catalog"
+	"plutus-backend/graph/model"
+)
+
+// findCategory resolves a category key or product type, as in
+// StashItemInput.
+func findCategory(name string) (*catalog.Category, error) {
+	c, ok := catalog.Find(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown category %q", name)
+	}
+	return c, nil
+}
+
+// archivedProductModel converts an archived product into its GraphQL form.
+func archivedProductModel(a *catalog.ArchivedProduct) *model.ArchivedProduct {
+	return &model.ArchivedProduct{
+		Category:    a.Category,
+		ID:          a.ID,
+		Name:        a.Name,
+		ProductLink: a.ProductLink,
+		ArchivedAt:  a.ArchivedAt.Format(time.RFC3339),
+	}
+}
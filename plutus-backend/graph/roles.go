@@ -0,0 +1,24 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+
+	"plutus-backend/auth"
+	"plutus-backend/graph/model"
+)
+
+// HasRole implements the @hasRole directive: the field resolves only for a
+// signed-in user whose role includes role.
+func HasRole(ctx context.Context, obj any, next graphql.Resolver, role auth.Role) (any, error) {
+	if _, err := auth.RequireRole(ctx, role); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+// userModel converts a user into its GraphQL form.
+func userModel(u *auth.User) *model.User {
+	return &model.User{ID: u.UserID, FullName: u.FullName, Email: u.Email, Role: u.Role}
+}
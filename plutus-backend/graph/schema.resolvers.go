@@ -6,1024 +6,896 @@ package graph
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
 	"fmt"
+	"plutus-backend/alerts"
+	"plutus-backend/auth"
+	"plutus-backend/brands"
+	"plutus-backend/catalog"
+	"plutus-backend/enquiries"
 	"plutus-backend/graph/generated"
 	"plutus-backend/graph/model"
-	"sort"
+	"plutus-backend/prices"
+	"plutus-backend/search"
+	"plutus-backend/stash"
 	"strings"
-
-	"github.com/lib/pq"
+	"time"
 )
 
+// Price is the resolver for the price field.
+func (r *accessoryResolver) Price(ctx context.Context, obj *model.Accessory, currency *string) (*model.Money, error) {
+	amount, ok := lowestSizePrice(obj.SizePrices)
+	if !ok {
+		return nil, nil
+	}
+	return r.money(ctx, catalog.Accessories, amount, currency)
+}
+
+// PriceHistory is the resolver for the priceHistory field.
+func (r *accessoryResolver) PriceHistory(ctx context.Context, obj *model.Accessory, size *string, currency *string) ([]*model.PricePoint, error) {
+	return r.priceHistory(ctx, catalog.Accessories, obj.ID, size, currency)
+}
+
+// Price is the resolver for the price field.
+func (r *apparelResolver) Price(ctx context.Context, obj *model.Apparel, currency *string) (*model.Money, error) {
+	amount, ok := lowestSizePrice(obj.SizePrices)
+	if !ok {
+		return nil, nil
+	}
+	return r.money(ctx, catalog.Apparel, amount, currency)
+}
+
+// PriceHistory is the resolver for the priceHistory field.
+func (r *apparelResolver) PriceHistory(ctx context.Context, obj *model.Apparel, size *string, currency *string) ([]*model.PricePoint, error) {
+	return r.priceHistory(ctx, catalog.Apparel, obj.ID, size, currency)
+}
+
+// Products is the resolver for the products field.
+func (r *brandResolver) Products(ctx context.Context, obj *model.Brand, categories []string, first *int, after *string) (*model.SearchConnection, error) {
+	b := r.BrandResolver.BySlug(obj.Slug)
+	if b == nil {
+		// Removed since obj was resolved; a brand without aliases has no
+		// products.
+		b = &brands.Brand{Slug: obj.Slug}
+	}
+	page, err := r.Searcher.Search(ctx, search.Params{
+		Categories: categories,
+		First:      first,
+		After:      after,
+		Brand:      b,
+		WithCounts: fieldRequested(ctx, "totalCount") || fieldRequested(ctx, "categoryCounts"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return searchConnection(page), nil
+}
+
 // CreateEnquiry is the resolver for the createEnquiry field.
-func (r *mutationResolver) CreateEnquiry(ctx context.Context, name string, email string, phone *string, message string, productID *string, productName *string, productCategory *string) (bool, error) {
-	query := `INSERT INTO enquiries (name, email, phone, message, product_id, product_name, product_category) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+func (r *mutationResolver) CreateEnquiry(ctx context.Context, name string, email string, phone *string, message string, productID *string, productName *string, productCategory *string, website *string, idempotencyKey *string) (bool, error) {
+	_, err := r.Enquiries.Submit(ctx, enquiries.Submission{
+		Name:            name,
+		Email:           email,
+		Phone:           value(phone),
+		Message:         message,
+		ProductID:       value(productID),
+		ProductName:     value(productName),
+		ProductCategory: value(productCategory),
+		Website:         value(website),
+		IdempotencyKey:  value(idempotencyKey),
+		IP:              enquiries.ClientIP(ctx),
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
 
-	var phoneVal, productIDVal, productNameVal, productCategoryVal interface{}
-	if phone != nil {
-		phoneVal = *phone
-	} else {
-		phoneVal = nil
+// CreateAlert is the resolver for the createAlert field.
+func (r *mutationResolver) CreateAlert(ctx context.Context, kind model.AlertKind, category string, productID string, size *string, targetPrice *float64, currency *string) (*model.Alert, error) {
+	user, err := auth.Require(ctx)
+	if err != nil {
+		return nil, err
 	}
-	if productID != nil {
-		productIDVal = *productID
-	} else {
-		productIDVal = nil
+	p := alerts.Params{
+		Kind:        alertKinds[kind],
+		Category:    category,
+		ProductID:   productID,
+		TargetPrice: targetPrice,
+		Currency:    displayCurrency(ctx, currency),
 	}
-	if productName != nil {
-		productNameVal = *productName
-	} else {
-		productNameVal = nil
+	if size != nil {
+		p.Size = *size
 	}
-	if productCategory != nil {
-		productCategoryVal = *productCategory
-	} else {
-		productCategoryVal = nil
+	a, err := alerts.Create(ctx, r.Catalog, r.Rates, user.ID, p)
+	if err != nil {
+		return nil, err
 	}
+	return alertModel(a), nil
+}
 
-	_, err := r.DB.Exec(query, name, email, phoneVal, message, productIDVal, productNameVal, productCategoryVal)
+// DeleteAlert is the resolver for the deleteAlert field.
+func (r *mutationResolver) DeleteAlert(ctx context.Context, id string) (bool, error) {
+	user, err := auth.Require(ctx)
 	if err != nil {
 		return false, err
 	}
+	if err := alerts.Delete(ctx, r.DB, user.ID, id); err != nil {
+		return false, err
+	}
 	return true, nil
 }
 
-// Sneakers is the resolver for the sneakers field.
-func (r *queryResolver) Sneakers(ctx context.Context, brand *string, size *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, limit *int, offset *int) ([]*model.Sneaker, error) {
-	query := `
-		SELECT id, brand, product_name, size_prices, images, sold_out, product_link, seller_name, seller_url
-		FROM sneakers
-		WHERE 1=1
-	`
-	if brand != nil && *brand != "" {
-		// Enhanced brand comparison with multiple fallback strategies
-		normalizedBrand := strings.ToLower(strings.TrimSpace(*brand))
-
-		// Strategy 1: Exact case-insensitive match
-		query += fmt.Sprintf(" AND (LOWER(TRIM(brand)) = '%s'", normalizedBrand)
-
-		// Strategy 2: Handle ampersand variations
-		if strings.Contains(normalizedBrand, "&") {
-			// Try with "and" instead of "&"
-			andVersion := strings.ReplaceAll(normalizedBrand, "&", "and")
-			query += fmt.Sprintf(" OR LOWER(TRIM(brand)) = '%s'", andVersion)
-		} else if strings.Contains(normalizedBrand, "and") {
-			// Try with "&" instead of "and"
-			ampersandVersion := strings.ReplaceAll(normalizedBrand, "and", "&")
-			query += fmt.Sprintf(" OR LOWER(TRIM(brand)) = '%s'", ampersandVersion)
-		}
-
-		// Strategy 3: Handle period variations (e.g., "A. LANGE" vs "A LANGE")
-		if strings.Contains(normalizedBrand, ".") {
-			noPeriodVersion := strings.ReplaceAll(normalizedBrand, ".", "")
-			query += fmt.Sprintf(" OR LOWER(TRIM(brand)) = '%s'", noPeriodVersion)
-		} else {
-			// Try adding periods for common patterns
-			withPeriodVersion := strings.ReplaceAll(normalizedBrand, "a lange", "a. lange")
-			if withPeriodVersion != normalizedBrand {
-				query += fmt.Sprintf(" OR LOWER(TRIM(brand)) = '%s'", withPeriodVersion)
-			}
-		}
-
-		// Strategy 4: Handle accent normalization
-		accentNormalized := normalizeAccents(normalizedBrand)
-		if accentNormalized != normalizedBrand {
-			query += fmt.Sprintf(" OR LOWER(TRIM(brand)) = '%s'", accentNormalized)
-		}
-
-		query += ")"
+// AddToStash is the resolver for the addToStash field.
+func (r *mutationResolver) AddToStash(ctx context.Context, category string, productID string) (*model.StashItem, error) {
+	user, err := auth.Require(ctx)
+	if err != nil {
+		return nil, err
 	}
-	if size != nil && *size != "" {
-		query += fmt.Sprintf(" AND size_prices::text ILIKE '%%%s%%'", *size)
+	item, err := stash.Add(ctx, r.Catalog, user.ID, stash.Ref{Category: category, ProductID: productID})
+	if err != nil {
+		return nil, err
 	}
-	if search != nil && *search != "" {
-		query += fmt.Sprintf(" AND (brand ILIKE '%%%s%%' OR product_name ILIKE '%%%s%%')", *search, *search)
+	return stashItemModel(item), nil
+}
+
+// RemoveFromStash is the resolver for the removeFromStash field.
+func (r *mutationResolver) RemoveFromStash(ctx context.Context, category string, productID string) (bool, error) {
+	user, err := auth.Require(ctx)
+	if err != nil {
+		return false, err
 	}
-	// Sorting
-	if sortOrder != nil && (*sortOrder == "asc" || *sortOrder == "desc") {
-		query += " ORDER BY (SELECT MIN((sp->>'price')::float) FROM jsonb_array_elements(size_prices) sp) " + *sortOrder
+	return stash.Remove(ctx, r.Catalog, user.ID, stash.Ref{Category: category, ProductID: productID})
+}
+
+// MergeStash is the resolver for the mergeStash field.
+func (r *mutationResolver) MergeStash(ctx context.Context, items []*model.StashItemInput) ([]*model.StashItem, error) {
+	user, err := auth.Require(ctx)
+	if err != nil {
+		return nil, err
 	}
-	// Pagination
-	if limit != nil {
-		query += fmt.Sprintf(" LIMIT %d", *limit)
+	refs := make([]stash.Ref, len(items))
+	for i, item := range items {
+		refs[i] = stash.Ref{Category: item.Category, ProductID: item.ProductID}
 	}
-	if offset != nil {
-		query += fmt.Sprintf(" OFFSET %d", *offset)
+	if _, err := stash.Merge(ctx, r.Catalog, user.ID, refs); err != nil {
+		return nil, err
 	}
+	return r.myStash(ctx, user.ID)
+}
 
-	rows, err := r.DB.Query(query)
+// SetUserRole is the resolver for the setUserRole field.
+func (r *mutationResolver) SetUserRole(ctx context.Context, email string, role auth.Role) (*model.User, error) {
+	u, err := auth.SetRole(ctx, r.DB, email, role)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return userModel(u), nil
+}
 
-	var sneakers []*model.Sneaker
+// UpsertSneaker is the resolver for the upsertSneaker field.
+func (r *mutationResolver) UpsertSneaker(ctx context.Context, input model.SneakerInput) (*model.Sneaker, error) {
+	return r.Catalog.UpsertSneaker(ctx, input)
+}
 
-	for rows.Next() {
-		var id, brandVal, productName, productLink string
-		var sizePricesRaw []byte
-		var images []string
-		var soldOut bool
-		var sellerName, sellerUrl *string
+// UpsertWatch is the resolver for the upsertWatch field.
+func (r *mutationResolver) UpsertWatch(ctx context.Context, input model.WatchInput) (*model.Watch, error) {
+	return r.Catalog.UpsertWatch(ctx, input)
+}
 
-		if err := rows.Scan(&id, &brandVal, &productName, &sizePricesRaw, pq.Array(&images), &soldOut, &productLink, &sellerName, &sellerUrl); err != nil {
-			return nil, err
-		}
+// UpsertPerfume is the resolver for the upsertPerfume field.
+func (r *mutationResolver) UpsertPerfume(ctx context.Context, input model.PerfumeInput) (*model.Perfume, error) {
+	return r.Catalog.UpsertPerfume(ctx, input)
+}
 
-		var sizePrices []model.SizePrice
-		if err := json.Unmarshal(sizePricesRaw, &sizePrices); err != nil {
-			return nil, err
-		}
+// UpsertAccessory is the resolver for the upsertAccessory field.
+func (r *mutationResolver) UpsertAccessory(ctx context.Context, input model.AccessoryInput) (*model.Accessory, error) {
+	return r.Catalog.UpsertAccessory(ctx, input)
+}
 
-		var sizePricesPtr []*model.SizePrice
-		for _, sp := range sizePrices {
-			spCopy := sp
-			sizePricesPtr = append(sizePricesPtr, &spCopy)
-		}
+// UpsertApparel is the resolver for the upsertApparel field.
+func (r *mutationResolver) UpsertApparel(ctx context.Context, input model.ApparelInput) (*model.Apparel, error) {
+	return r.Catalog.UpsertApparel(ctx, input)
+}
 
-		sneakers = append(sneakers, &model.Sneaker{
-			ID:          id,
-			Brand:       brandVal,
-			ProductName: productName,
-			SizePrices:  sizePricesPtr,
-			Images:      images,
-			SoldOut:     soldOut,
-			SellerName:  sellerName,
-			SellerURL:   sellerUrl,
-			ProductLink: productLink,
-		})
+// ArchiveProduct is the resolver for the archiveProduct field.
+func (r *mutationResolver) ArchiveProduct(ctx context.Context, category string, id string) (bool, error) {
+	user, err := auth.Require(ctx)
+	if err != nil {
+		return false, err
+	}
+	c, err := findCategory(category)
+	if err != nil {
+		return false, err
 	}
-	return sneakers, nil
+	if err := r.Catalog.Archive(ctx, c, id, user.ID); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-// Sneaker is the resolver for the sneaker field.
-func (r *queryResolver) Sneaker(ctx context.Context, id string) (*model.Sneaker, error) {
-	query := `SELECT id, brand, product_name, size_prices, images, sold_out, product_link, seller_name, seller_url FROM sneakers WHERE id = $1`
-	row := r.DB.QueryRow(query, id)
-
-	var idVal, brandVal, productName, productLink string
-	var sizePricesRaw []byte
-	var images []string
-	var soldOut bool
-	var sellerName, sellerUrl *string
-
-	err := row.Scan(&idVal, &brandVal, &productName, &sizePricesRaw, pq.Array(&images), &soldOut, &productLink, &sellerName, &sellerUrl)
+// RestoreProduct is the resolver for the restoreProduct field.
+func (r *mutationResolver) RestoreProduct(ctx context.Context, category string, id string) (model.Product, error) {
+	c, err := findCategory(category)
 	if err != nil {
 		return nil, err
 	}
+	return r.Catalog.Restore(ctx, c, id)
+}
 
-	var sizePrices []model.SizePrice
-	if err := json.Unmarshal(sizePricesRaw, &sizePrices); err != nil {
+// SetEnquiryStatus is the resolver for the setEnquiryStatus field.
+func (r *mutationResolver) SetEnquiryStatus(ctx context.Context, id string, status model.EnquiryStatus) (*model.Enquiry, error) {
+	n, err := enquiryID(id)
+	if err != nil {
 		return nil, err
 	}
-	var sizePricesPtr []*model.SizePrice
-	for _, sp := range sizePrices {
-		spCopy := sp
-		sizePricesPtr = append(sizePricesPtr, &spCopy)
-	}
-	return &model.Sneaker{
-		ID:          idVal,
-		Brand:       brandVal,
-		ProductName: productName,
-		SizePrices:  sizePricesPtr,
-		Images:      images,
-		SoldOut:     soldOut,
-		SellerName:  sellerName,
-		SellerURL:   sellerUrl,
-		ProductLink: productLink,
-	}, nil
+	return r.enquiry(ctx, n, enquiries.SetStatus(ctx, r.DB, n, enquiryStatuses[status]))
 }
 
-// Watches is the resolver for the watches field.
-func (r *queryResolver) Watches(ctx context.Context, brand *string, color *string, gender *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, limit *int, offset *int) ([]*model.Watch, error) {
-	query := `
-		SELECT id, brand, name, color, sale_price, market_price, images, link, seller_name, seller_url, gender
-		FROM watches
-		WHERE 1=1
-	`
-	if brand != nil && *brand != "" {
-		// Use case-insensitive brand comparison with normalization
-		normalizedBrand := strings.ToLower(strings.TrimSpace(*brand))
-		query += fmt.Sprintf(" AND LOWER(TRIM(brand)) = '%s'", normalizedBrand)
-	}
-	if color != nil && *color != "" {
-		query += fmt.Sprintf(" AND color ILIKE '%%%s%%'", *color)
-	}
-	if gender != nil && *gender != "" {
-		query += fmt.Sprintf(" AND gender ILIKE '%%%s%%'", *gender)
-	}
-	if search != nil && *search != "" {
-		query += fmt.Sprintf(" AND (name ILIKE '%%%s%%' OR brand ILIKE '%%%s%%')", *search, *search)
-	}
-	if minPrice != nil && *minPrice > 0 {
-		query += fmt.Sprintf(" AND sale_price >= %f", *minPrice)
+// AssignEnquiry is the resolver for the assignEnquiry field.
+func (r *mutationResolver) AssignEnquiry(ctx context.Context, id string, assignee *string) (*model.Enquiry, error) {
+	n, err := enquiryID(id)
+	if err != nil {
+		return nil, err
 	}
-	if maxPrice != nil && *maxPrice > 0 {
-		query += fmt.Sprintf(" AND sale_price <= %f", *maxPrice)
+	email := ""
+	if assignee != nil {
+		email = strings.TrimSpace(*assignee)
 	}
+	return r.enquiry(ctx, n, enquiries.Assign(ctx, r.DB, n, email))
+}
 
-	// Sorting
-	if sortOrder != nil && (*sortOrder == "asc" || *sortOrder == "desc") {
-		query += " ORDER BY sale_price " + *sortOrder
+// SetEnquiryFollowUp is the resolver for the setEnquiryFollowUp field.
+func (r *mutationResolver) SetEnquiryFollowUp(ctx context.Context, id string, on *string) (*model.Enquiry, error) {
+	n, err := enquiryID(id)
+	if err != nil {
+		return nil, err
+	}
+	var date *time.Time
+	if on != nil && *on != "" {
+		t, err := time.Parse(time.DateOnly, *on)
+		if err != nil {
+			return nil, fmt.Errorf("on must be a YYYY-MM-DD date, got %q", *on)
+		}
+		date = &t
 	}
+	return r.enquiry(ctx, n, enquiries.SetFollowUp(ctx, r.DB, n, date))
+}
 
-	// Pagination at database level
-	if limit != nil {
-		query += fmt.Sprintf(" LIMIT %d", *limit)
+// AddEnquiryNote is the resolver for the addEnquiryNote field.
+func (r *mutationResolver) AddEnquiryNote(ctx context.Context, id string, body string) (*model.EnquiryNote, error) {
+	user, err := auth.Require(ctx)
+	if err != nil {
+		return nil, err
 	}
-	if offset != nil {
-		query += fmt.Sprintf(" OFFSET %d", *offset)
+	n, err := enquiryID(id)
+	if err != nil {
+		return nil, err
 	}
-
-	rows, err := r.DB.Query(query)
+	note, err := enquiries.AddNote(ctx, r.DB, n, user, body)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return enquiryNoteModel(note), nil
+}
 
-	var watches []*model.Watch
-	for rows.Next() {
-		var w model.Watch
-		var sellerName, sellerUrl *string
-		var genderVal sql.NullString
-		if err := rows.Scan(&w.ID, &w.Brand, &w.Name, &w.Color, &w.SalePrice, &w.MarketPrice, pq.Array(&w.Images), &w.Link, &sellerName, &sellerUrl, &genderVal); err != nil {
-			return nil, err
-		}
-		w.SellerName = sellerName
-		w.SellerURL = sellerUrl
-		if genderVal.Valid {
-			w.Gender = &genderVal.String
-		}
-		watches = append(watches, &w)
+// Price is the resolver for the price field.
+func (r *perfumeResolver) Price(ctx context.Context, obj *model.Perfume, currency *string) (*model.Money, error) {
+	amount, ok := lowestVariantPrice(obj.Variants)
+	if !ok {
+		return nil, nil
 	}
+	return r.money(ctx, catalog.Perfumes, amount, currency)
+}
 
-	return watches, nil
+// PriceHistory is the resolver for the priceHistory field.
+func (r *perfumeResolver) PriceHistory(ctx context.Context, obj *model.Perfume, size *string, currency *string) ([]*model.PricePoint, error) {
+	return r.priceHistory(ctx, catalog.Perfumes, obj.ID, size, currency)
 }
 
-// Watch is the resolver for the watch field.
-func (r *queryResolver) Watch(ctx context.Context, id string) (*model.Watch, error) {
-	query := `SELECT id, brand, name, color, sale_price, market_price, images, link, seller_name, seller_url FROM watches WHERE id = $1`
-	row := r.DB.QueryRow(query, id)
-	var w model.Watch
-	var sellerName, sellerUrl *string
-	if err := row.Scan(&w.ID, &w.Brand, &w.Name, &w.Color, &w.SalePrice, &w.MarketPrice, pq.Array(&w.Images), &w.Link, &sellerName, &sellerUrl); err != nil {
-		return nil, err
+// Money is the resolver for the money field.
+func (r *perfumeVariantResolver) Money(ctx context.Context, obj *model.PerfumeVariant, currency *string) (*model.Money, error) {
+	if obj.Price == nil {
+		return nil, nil
 	}
-	w.SellerName = sellerName
-	w.SellerURL = sellerUrl
-	return &w, nil
+	return r.convert(ctx, enclosingCurrency(ctx), *obj.Price, currency)
 }
 
-// Perfumes is the resolver for the perfumes field.
-func (r *queryResolver) Perfumes(ctx context.Context, brand *string, fragranceFamily *string, concentration *string, subcategory *string, size *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, limit *int, offset *int) ([]*model.Perfume, error) {
-	query := `
-		SELECT id, brand, title, fragrance_family, concentration, subcategory, variants, images, url, seller_name, seller_url
-		FROM perfumes
-		WHERE 1=1
-	`
-	if brand != nil && *brand != "" {
-		// Enhanced brand comparison with multiple fallback strategies
-		normalizedBrand := strings.ToLower(strings.TrimSpace(*brand))
-
-		// Strategy 1: Exact case-insensitive match
-		query += fmt.Sprintf(" AND (LOWER(TRIM(brand)) = '%s'", normalizedBrand)
-
-		// Strategy 2: Handle ampersand variations
-		if strings.Contains(normalizedBrand, "&") {
-			// Try with "and" instead of "&"
-			andVersion := strings.ReplaceAll(normalizedBrand, "&", "and")
-			query += fmt.Sprintf(" OR LOWER(TRIM(brand)) = '%s'", andVersion)
-		} else if strings.Contains(normalizedBrand, "and") {
-			// Try with "&" instead of "and"
-			ampersandVersion := strings.ReplaceAll(normalizedBrand, "and", "&")
-			query += fmt.Sprintf(" OR LOWER(TRIM(brand)) = '%s'", ampersandVersion)
-		}
-
-		// Strategy 3: Handle period variations
-		if strings.Contains(normalizedBrand, ".") {
-			noPeriodVersion := strings.ReplaceAll(normalizedBrand, ".", "")
-			query += fmt.Sprintf(" OR LOWER(TRIM(brand)) = '%s'", noPeriodVersion)
-		}
+// Sneakers is the resolver for the sneakers field.
+func (r *queryResolver) Sneakers(ctx context.Context, brand *string, brandSlug *string, size *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, limit *int, offset *int) ([]*model.Sneaker, error) {
+	params, err := r.inStoredCurrency(ctx, r.Catalog.Sneakers.Category, catalog.ListParams{
+		Filters:   catalog.Filters{"brand": brand, "brandSlug": brandSlug, "size": size},
+		Search:    search,
+		SortOrder: sortOrder,
+		MinPrice:  minPrice,
+		MaxPrice:  maxPrice,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.Catalog.Sneakers.List(ctx, params)
+}
 
-		// Strategy 4: Handle accent normalization
-		accentNormalized := normalizeAccents(normalizedBrand)
-		if accentNormalized != normalizedBrand {
-			query += fmt.Sprintf(" OR LOWER(TRIM(brand)) = '%s'", accentNormalized)
-		}
+// Sneaker is the resolver for the sneaker field.
+func (r *queryResolver) Sneaker(ctx context.Context, id string) (*model.Sneaker, error) {
+	return r.Catalog.Sneakers.Get(ctx, id)
+}
 
-		query += ")"
-	}
-	if fragranceFamily != nil && *fragranceFamily != "" {
-		query += fmt.Sprintf(" AND fragrance_family ILIKE '%%%s%%'", *fragranceFamily)
-	}
-	if subcategory != nil && *subcategory != "" {
-		query += fmt.Sprintf(" AND subcategory ILIKE '%%%s%%'", *subcategory)
+// SneakersConnection is the resolver for the sneakersConnection field.
+func (r *queryResolver) SneakersConnection(ctx context.Context, brand *string, brandSlug *string, size *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, first *int, after *string) (*model.SneakerConnection, error) {
+	params, err := r.inStoredCurrency(ctx, r.Catalog.Sneakers.Category, catalog.ListParams{
+		Filters:   catalog.Filters{"brand": brand, "brandSlug": brandSlug, "size": size},
+		Search:    search,
+		SortOrder: sortOrder,
+		MinPrice:  minPrice,
+		MaxPrice:  maxPrice,
+	})
+	if err != nil {
+		return nil, err
 	}
-	if concentration != nil && *concentration != "" {
-		query += fmt.Sprintf(" AND concentration ILIKE '%%%s%%'", *concentration)
+	page, err := r.Catalog.Sneakers.Page(ctx, pageParams(ctx, params, first, after))
+	if err != nil {
+		return nil, err
 	}
-	if size != nil && *size != "" {
-		query += fmt.Sprintf(" AND variants::text ILIKE '%%%s%%'", *size)
+	facets, err := facetsFor(ctx, r.Resolver, r.Catalog.Sneakers, params)
+	if err != nil {
+		return nil, err
 	}
-	if search != nil && *search != "" {
-		query += fmt.Sprintf(" AND (title ILIKE '%%%s%%' OR brand ILIKE '%%%s%%')", *search, *search)
+	edges, pageInfo := connection(page, func(cursor string, node *model.Sneaker) *model.SneakerEdge {
+		return &model.SneakerEdge{Cursor: cursor, Node: node}
+	})
+	return &model.SneakerConnection{Edges: edges, PageInfo: pageInfo, TotalCount: page.TotalCount, Facets: facets}, nil
+}
+
+// Watches is the resolver for the watches field.
+func (r *queryResolver) Watches(ctx context.Context, brand *string, brandSlug *string, color *string, gender *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, limit *int, offset *int) ([]*model.Watch, error) {
+	params, err := r.inStoredCurrency(ctx, r.Catalog.Watches.Category, catalog.ListParams{
+		Filters:   catalog.Filters{"brand": brand, "brandSlug": brandSlug, "color": color, "gender": gender},
+		Search:    search,
+		SortOrder: sortOrder,
+		MinPrice:  minPrice,
+		MaxPrice:  maxPrice,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return r.Catalog.Watches.List(ctx, params)
+}
 
-	// Pagination at database level
-	if limit != nil {
-		query += fmt.Sprintf(" LIMIT %d", *limit)
+// Watch is the resolver for the watch field.
+func (r *queryResolver) Watch(ctx context.Context, id string) (*model.Watch, error) {
+	return r.Catalog.Watches.Get(ctx, id)
+}
+
+// WatchesConnection is the resolver for the watchesConnection field.
+func (r *queryResolver) WatchesConnection(ctx context.Context, brand *string, brandSlug *string, color *string, gender *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, first *int, after *string) (*model.WatchConnection, error) {
+	params, err := r.inStoredCurrency(ctx, r.Catalog.Watches.Category, catalog.ListParams{
+		Filters:   catalog.Filters{"brand": brand, "brandSlug": brandSlug, "color": color, "gender": gender},
+		Search:    search,
+		SortOrder: sortOrder,
+		MinPrice:  minPrice,
+		MaxPrice:  maxPrice,
+	})
+	if err != nil {
+		return nil, err
 	}
-	if offset != nil {
-		query += fmt.Sprintf(" OFFSET %d", *offset)
+	page, err := r.Catalog.Watches.Page(ctx, pageParams(ctx, params, first, after))
+	if err != nil {
+		return nil, err
 	}
-
-	rows, err := r.DB.Query(query)
+	facets, err := facetsFor(ctx, r.Resolver, r.Catalog.Watches, params)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var perfumes []*model.Perfume
-	for rows.Next() {
-		var p model.Perfume
-		var variantsRaw []byte
-		var sellerName, sellerUrl *string
-		var concentration *string
-		if err := rows.Scan(&p.ID, &p.Brand, &p.Title, &p.FragranceFamily, &concentration, &p.Subcategory, &variantsRaw, pq.Array(&p.Images), &p.URL, &sellerName, &sellerUrl); err != nil {
-			return nil, err
-		}
-
-		// Set concentration based on title if it's null
-		if concentration == nil || *concentration == "" {
-			title := strings.ToLower(p.Title)
-			fmt.Printf("Processing perfume: %s, concentration from DB: %v\n", p.Title, concentration)
-			if strings.Contains(title, "eau de parfum") || strings.Contains(title, "edp") {
-				concentrationStr := "EDP"
-				p.Concentration = &concentrationStr
-				fmt.Printf("Set concentration to EDP for: %s\n", p.Title)
-			} else if strings.Contains(title, "eau de toilette") || strings.Contains(title, "edt") {
-				concentrationStr := "EDT"
-				p.Concentration = &concentrationStr
-				fmt.Printf("Set concentration to EDT for: %s\n", p.Title)
-			} else if strings.Contains(title, "parfum") {
-				concentrationStr := "Parfum"
-				p.Concentration = &concentrationStr
-				fmt.Printf("Set concentration to Parfum for: %s\n", p.Title)
-			} else if strings.Contains(title, "cologne") {
-				concentrationStr := "Cologne"
-				p.Concentration = &concentrationStr
-				fmt.Printf("Set concentration to Cologne for: %s\n", p.Title)
-			} else {
-				concentrationStr := "EDT" // Default fallback
-				p.Concentration = &concentrationStr
-				fmt.Printf("Set concentration to EDT (default) for: %s\n", p.Title)
-			}
-		} else {
-			p.Concentration = concentration
-			fmt.Printf("Using existing concentration: %s for: %s\n", *concentration, p.Title)
-		}
+	edges, pageInfo := connection(page, func(cursor string, node *model.Watch) *model.WatchEdge {
+		return &model.WatchEdge{Cursor: cursor, Node: node}
+	})
+	return &model.WatchConnection{Edges: edges, PageInfo: pageInfo, TotalCount: page.TotalCount, Facets: facets}, nil
+}
 
-		if err := json.Unmarshal(variantsRaw, &p.Variants); err != nil {
-			return nil, err
-		}
-		p.SellerName = sellerName
-		p.SellerURL = sellerUrl
-		perfumes = append(perfumes, &p)
+// Perfumes is the resolver for the perfumes field.
+func (r *queryResolver) Perfumes(ctx context.Context, brand *string, brandSlug *string, fragranceFamily *string, concentration *string, subcategory *string, size *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, limit *int, offset *int) ([]*model.Perfume, error) {
+	params, err := r.inStoredCurrency(ctx, r.Catalog.Perfumes.Category, catalog.ListParams{
+		Filters: catalog.Filters{
+			"brand":           brand,
+			"brandSlug":       brandSlug,
+			"fragranceFamily": fragranceFamily,
+			"concentration":   concentration,
+			"subcategory":     subcategory,
+			"size":            size,
+		},
+		Search:    search,
+		SortOrder: sortOrder,
+		MinPrice:  minPrice,
+		MaxPrice:  maxPrice,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return perfumes, nil
+	return r.Catalog.Perfumes.List(ctx, params)
 }
 
 // Perfume is the resolver for the perfume field.
 func (r *queryResolver) Perfume(ctx context.Context, id string) (*model.Perfume, error) {
-	query := `SELECT id, brand, title, fragrance_family, concentration, subcategory, variants, images, url, seller_name, seller_url FROM perfumes WHERE id = $1`
-	row := r.DB.QueryRow(query, id)
-	var p model.Perfume
-	var variantsRaw []byte
-	var sellerName, sellerUrl *string
-	var concentration *string
-	if err := row.Scan(&p.ID, &p.Brand, &p.Title, &p.FragranceFamily, &concentration, &p.Subcategory, &variantsRaw, pq.Array(&p.Images), &p.URL, &sellerName, &sellerUrl); err != nil {
+	return r.Catalog.Perfumes.Get(ctx, id)
+}
+
+// PerfumesConnection is the resolver for the perfumesConnection field.
+func (r *queryResolver) PerfumesConnection(ctx context.Context, brand *string, brandSlug *string, fragranceFamily *string, concentration *string, subcategory *string, size *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, first *int, after *string) (*model.PerfumeConnection, error) {
+	params, err := r.inStoredCurrency(ctx, r.Catalog.Perfumes.Category, catalog.ListParams{
+		Filters: catalog.Filters{
+			"brand":           brand,
+			"brandSlug":       brandSlug,
+			"fragranceFamily": fragranceFamily,
+			"concentration":   concentration,
+			"subcategory":     subcategory,
+			"size":            size,
+		},
+		Search:    search,
+		SortOrder: sortOrder,
+		MinPrice:  minPrice,
+		MaxPrice:  maxPrice,
+	})
+	if err != nil {
 		return nil, err
 	}
-
-	// Set concentration based on title if it's null
-	if concentration == nil || *concentration == "" {
-		title := strings.ToLower(p.Title)
-		if strings.Contains(title, "eau de parfum") || strings.Contains(title, "edp") {
-			concentrationStr := "EDP"
-			p.Concentration = &concentrationStr
-		} else if strings.Contains(title, "eau de toilette") || strings.Contains(title, "edt") {
-			concentrationStr := "EDT"
-			p.Concentration = &concentrationStr
-		} else if strings.Contains(title, "parfum") {
-			concentrationStr := "Parfum"
-			p.Concentration = &concentrationStr
-		} else if strings.Contains(title, "cologne") {
-			concentrationStr := "Cologne"
-			p.Concentration = &concentrationStr
-		} else {
-			concentrationStr := "EDT" // Default fallback
-			p.Concentration = &concentrationStr
-		}
-	} else {
-		p.Concentration = concentration
+	page, err := r.Catalog.Perfumes.Page(ctx, pageParams(ctx, params, first, after))
+	if err != nil {
+		return nil, err
 	}
-
-	if err := json.Unmarshal(variantsRaw, &p.Variants); err != nil {
+	facets, err := facetsFor(ctx, r.Resolver, r.Catalog.Perfumes, params)
+	if err != nil {
 		return nil, err
 	}
-	p.SellerName = sellerName
-	p.SellerURL = sellerUrl
-	return &p, nil
+	edges, pageInfo := connection(page, func(cursor string, node *model.Perfume) *model.PerfumeEdge {
+		return &model.PerfumeEdge{Cursor: cursor, Node: node}
+	})
+	return &model.PerfumeConnection{Edges: edges, PageInfo: pageInfo, TotalCount: page.TotalCount, Facets: facets}, nil
 }
 
 // Accessories is the resolver for the accessories field.
-func (r *queryResolver) Accessories(ctx context.Context, brand *string, subcategory *string, gender *string, size *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, limit *int, offset *int) ([]*model.Accessory, error) {
-	query := `
-		SELECT id, brand, product_name, subcategory, gender, size_prices, images, in_stock, product_link, seller_name, seller_url
-		FROM accessories
-		WHERE 1=1
-	`
-	if brand != nil && *brand != "" {
-		// Use case-insensitive brand comparison with accent normalization
-		normalizedBrand := normalizeAccents(strings.ToLower(strings.TrimSpace(*brand)))
-		// Use multiple conditions to handle both with and without accents
-		query += fmt.Sprintf(" AND (LOWER(TRIM(brand)) = '%s' OR LOWER(TRIM(brand)) = '%s')",
-			strings.ToLower(strings.TrimSpace(*brand)), normalizedBrand)
-	}
-	if subcategory != nil && *subcategory != "" {
-		query += fmt.Sprintf(" AND subcategory ILIKE '%%%s%%'", *subcategory)
-	}
-	if gender != nil && *gender != "" {
-		query += fmt.Sprintf(" AND gender ILIKE '%%%s%%'", *gender)
-	}
-	if size != nil && *size != "" {
-		query += fmt.Sprintf(" AND size_prices::text ILIKE '%%%s%%'", *size)
-	}
-	if search != nil && *search != "" {
-		query += fmt.Sprintf(" AND (product_name ILIKE '%%%s%%' OR brand ILIKE '%%%s%%')", *search, *search)
-	}
-
-	// Pagination at database level
-	if limit != nil {
-		query += fmt.Sprintf(" LIMIT %d", *limit)
-	}
-	if offset != nil {
-		query += fmt.Sprintf(" OFFSET %d", *offset)
-	}
-
-	rows, err := r.DB.Query(query)
+func (r *queryResolver) Accessories(ctx context.Context, brand *string, brandSlug *string, subcategory *string, gender *string, size *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, limit *int, offset *int) ([]*model.Accessory, error) {
+	params, err := r.inStoredCurrency(ctx, r.Catalog.Accessories.Category, catalog.ListParams{
+		Filters:   catalog.Filters{"brand": brand, "brandSlug": brandSlug, "subcategory": subcategory, "gender": gender, "size": size},
+		Search:    search,
+		SortOrder: sortOrder,
+		MinPrice:  minPrice,
+		MaxPrice:  maxPrice,
+		Limit:     limit,
+		Offset:    offset,
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var accessories []*model.Accessory
-	for rows.Next() {
-		var id, brandVal, productName, subcategoryVal, genderVal, productLink string
-		var sizePricesRaw []byte
-		var images []string
-		var inStock bool
-		var sellerName, sellerUrl *string
-
-		if err := rows.Scan(&id, &brandVal, &productName, &subcategoryVal, &genderVal, &sizePricesRaw, pq.Array(&images), &inStock, &productLink, &sellerName, &sellerUrl); err != nil {
-			return nil, err
-		}
-
-		var sizePrices []model.SizePrice
-		if err := json.Unmarshal(sizePricesRaw, &sizePrices); err != nil {
-			return nil, err
-		}
-		var sizePricesPtr []*model.SizePrice
-		for _, sp := range sizePrices {
-			spCopy := sp
-			sizePricesPtr = append(sizePricesPtr, &spCopy)
-		}
-		accessories = append(accessories, &model.Accessory{
-			ID:          id,
-			Brand:       brandVal,
-			ProductName: productName,
-			Subcategory: subcategoryVal,
-			Gender:      genderVal,
-			SizePrices:  sizePricesPtr,
-			Images:      images,
-			InStock:     inStock,
-			ProductLink: productLink,
-			SellerName:  sellerName,
-			SellerURL:   sellerUrl,
-		})
-	}
-
-	return accessories, nil
+	return r.Catalog.Accessories.List(ctx, params)
 }
 
 // Accessory is the resolver for the accessory field.
 func (r *queryResolver) Accessory(ctx context.Context, id string) (*model.Accessory, error) {
-	query := `SELECT id, brand, product_name, subcategory, gender, size_prices, images, in_stock, product_link, seller_name, seller_url FROM accessories WHERE id = $1`
-	row := r.DB.QueryRow(query, id)
-	var idVal, brandVal, productName, subcategoryVal, genderVal, productLink string
-	var sizePricesRaw []byte
-	var images []string
-	var inStock bool
-	var sellerName, sellerUrl *string
-	if err := row.Scan(&idVal, &brandVal, &productName, &subcategoryVal, &genderVal, &sizePricesRaw, pq.Array(&images), &inStock, &productLink, &sellerName, &sellerUrl); err != nil {
+	return r.Catalog.Accessories.Get(ctx, id)
+}
+
+// AccessoriesConnection is the resolver for the accessoriesConnection field.
+func (r *queryResolver) AccessoriesConnection(ctx context.Context, brand *string, brandSlug *string, subcategory *string, gender *string, size *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, first *int, after *string) (*model.AccessoryConnection, error) {
+	params, err := r.inStoredCurrency(ctx, r.Catalog.Accessories.Category, catalog.ListParams{
+		Filters:   catalog.Filters{"brand": brand, "brandSlug": brandSlug, "subcategory": subcategory, "gender": gender, "size": size},
+		Search:    search,
+		SortOrder: sortOrder,
+		MinPrice:  minPrice,
+		MaxPrice:  maxPrice,
+	})
+	if err != nil {
+		return nil, err
+	}
+	page, err := r.Catalog.Accessories.Page(ctx, pageParams(ctx, params, first, after))
+	if err != nil {
 		return nil, err
 	}
-	var sizePrices []model.SizePrice
-	if err := json.Unmarshal(sizePricesRaw, &sizePrices); err != nil {
+	facets, err := facetsFor(ctx, r.Resolver, r.Catalog.Accessories, params)
+	if err != nil {
 		return nil, err
 	}
-	var sizePricesPtr []*model.SizePrice
-	for _, sp := range sizePrices {
-		spCopy := sp
-		sizePricesPtr = append(sizePricesPtr, &spCopy)
-	}
-	return &model.Accessory{
-		ID:          idVal,
-		Brand:       brandVal,
-		ProductName: productName,
-		Subcategory: subcategoryVal,
-		Gender:      genderVal,
-		SizePrices:  sizePricesPtr,
-		Images:      images,
-		InStock:     inStock,
-		ProductLink: productLink,
-		SellerName:  sellerName,
-		SellerURL:   sellerUrl,
-	}, nil
+	edges, pageInfo := connection(page, func(cursor string, node *model.Accessory) *model.AccessoryEdge {
+		return &model.AccessoryEdge{Cursor: cursor, Node: node}
+	})
+	return &model.AccessoryConnection{Edges: edges, PageInfo: pageInfo, TotalCount: page.TotalCount, Facets: facets}, nil
 }
 
 // Apparel is the resolver for the apparel field.
-func (r *queryResolver) Apparel(ctx context.Context, brand *string, subcategory *string, gender *string, size *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, limit *int, offset *int) ([]*model.Apparel, error) {
-	query := `
-		SELECT id, brand, product_name, subcategory, gender, size_prices, images, in_stock, product_link, seller_name, seller_url
-		FROM apparel
-		WHERE 1=1
-	`
-	if brand != nil && *brand != "" {
-		// Use case-insensitive brand comparison with accent normalization
-		normalizedBrand := normalizeAccents(strings.ToLower(strings.TrimSpace(*brand)))
-		// Use multiple conditions to handle both with and without accents
-		query += fmt.Sprintf(" AND (LOWER(TRIM(brand)) = '%s' OR LOWER(TRIM(brand)) = '%s')",
-			strings.ToLower(strings.TrimSpace(*brand)), normalizedBrand)
+func (r *queryResolver) Apparel(ctx context.Context, brand *string, brandSlug *string, subcategory *string, gender *string, size *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, limit *int, offset *int) ([]*model.Apparel, error) {
+	params, err := r.inStoredCurrency(ctx, r.Catalog.Apparel.Category, catalog.ListParams{
+		Filters:   catalog.Filters{"brand": brand, "brandSlug": brandSlug, "subcategory": subcategory, "gender": gender, "size": size},
+		Search:    search,
+		SortOrder: sortOrder,
+		MinPrice:  minPrice,
+		MaxPrice:  maxPrice,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		return nil, err
 	}
-	if subcategory != nil && *subcategory != "" {
-		query += fmt.Sprintf(" AND subcategory ILIKE '%%%s%%'", *subcategory)
+	return r.Catalog.Apparel.List(ctx, params)
+}
+
+// ApparelItem is the resolver for the apparelItem field.
+func (r *queryResolver) ApparelItem(ctx context.Context, id string) (*model.Apparel, error) {
+	return r.Catalog.Apparel.Get(ctx, id)
+}
+
+// ApparelConnection is the resolver for the apparelConnection field.
+func (r *queryResolver) ApparelConnection(ctx context.Context, brand *string, brandSlug *string, subcategory *string, gender *string, size *string, sortOrder *string, minPrice *float64, maxPrice *float64, search *string, first *int, after *string) (*model.ApparelConnection, error) {
+	params, err := r.inStoredCurrency(ctx, r.Catalog.Apparel.Category, catalog.ListParams{
+		Filters:   catalog.Filters{"brand": brand, "brandSlug": brandSlug, "subcategory": subcategory, "gender": gender, "size": size},
+		Search:    search,
+		SortOrder: sortOrder,
+		MinPrice:  minPrice,
+		MaxPrice:  maxPrice,
+	})
+	if err != nil {
+		return nil, err
 	}
-	if gender != nil && *gender != "" {
-		query += fmt.Sprintf(" AND gender ILIKE '%%%s%%'", *gender)
+	page, err := r.Catalog.Apparel.Page(ctx, pageParams(ctx, params, first, after))
+	if err != nil {
+		return nil, err
 	}
-	if size != nil && *size != "" {
-		query += fmt.Sprintf(" AND size_prices::text ILIKE '%%%s%%'", *size)
+	facets, err := facetsFor(ctx, r.Resolver, r.Catalog.Apparel, params)
+	if err != nil {
+		return nil, err
 	}
-	if search != nil && *search != "" {
-		query += fmt.Sprintf(" AND (product_name ILIKE '%%%s%%' OR brand ILIKE '%%%s%%')", *search, *search)
+	edges, pageInfo := connection(page, func(cursor string, node *model.Apparel) *model.ApparelEdge {
+		return &model.ApparelEdge{Cursor: cursor, Node: node}
+	})
+	return &model.ApparelConnection{Edges: edges, PageInfo: pageInfo, TotalCount: page.TotalCount, Facets: facets}, nil
+}
+
+// Search is the resolver for the search field.
+func (r *queryResolver) Search(ctx context.Context, query string, categories []string, first *int, after *string) (*model.SearchConnection, error) {
+	page, err := r.Searcher.Search(ctx, search.Params{
+		Query:      query,
+		Categories: categories,
+		First:      first,
+		After:      after,
+		WithCounts: fieldRequested(ctx, "totalCount") || fieldRequested(ctx, "categoryCounts"),
+	})
+	if err != nil {
+		return nil, err
 	}
+	return searchConnection(page), nil
+}
 
-	// Pagination at database level
+// Suggest is the resolver for the suggest field.
+func (r *queryResolver) Suggest(ctx context.Context, prefix string, limit *int) ([]*model.Suggestion, error) {
+	n := 0
 	if limit != nil {
-		query += fmt.Sprintf(" LIMIT %d", *limit)
+		n = *limit
+	}
+	return r.Suggester.Suggest(prefix, n), nil
+}
+
+// Brands is the resolver for the brands field.
+func (r *queryResolver) Brands(ctx context.Context, category *string) ([]*model.Brand, error) {
+	key := ""
+	if category != nil && *category != "" {
+		c, ok := catalog.Lookup(strings.ToLower(*category))
+		if !ok {
+			return nil, fmt.Errorf("unknown category %q", *category)
+		}
+		key = c.Key
 	}
-	if offset != nil {
-		query += fmt.Sprintf(" OFFSET %d", *offset)
+	found := r.BrandResolver.InCategory(key)
+	out := make([]*model.Brand, len(found))
+	for i, b := range found {
+		out[i] = brandModel(b)
 	}
+	return out, nil
+}
 
-	rows, err := r.DB.Query(query)
-	if err != nil {
-		return nil, err
+// Brand is the resolver for the brand field.
+func (r *queryResolver) Brand(ctx context.Context, slug string) (*model.Brand, error) {
+	b := r.BrandResolver.BySlug(slug)
+	if b == nil {
+		return nil, nil
 	}
-	defer rows.Close()
+	return brandModel(b), nil
+}
 
-	var apparels []*model.Apparel
-	for rows.Next() {
-		var id, brandVal, productName, subcategoryVal, genderVal, productLink string
-		var sizePricesRaw []byte
-		var images []string
-		var inStock bool
-		var sellerName, sellerUrl *string
+// ExchangeRates is the resolver for the exchangeRates field.
+func (r *queryResolver) ExchangeRates(ctx context.Context) ([]*model.ExchangeRate, error) {
+	rates := r.Rates.All()
+	out := make([]*model.ExchangeRate, len(rates))
+	for i, rate := range rates {
+		out[i] = &model.ExchangeRate{Currency: rate.Currency, Rate: rate.Rate, UpdatedAt: rate.UpdatedAt.Format(time.RFC3339)}
+	}
+	return out, nil
+}
 
-		if err := rows.Scan(&id, &brandVal, &productName, &subcategoryVal, &genderVal, &sizePricesRaw, pq.Array(&images), &inStock, &productLink, &sellerName, &sellerUrl); err != nil {
+// PriceDrops is the resolver for the priceDrops field.
+func (r *queryResolver) PriceDrops(ctx context.Context, category *string, since *string, minPercent *float64, first *int, currency *string) ([]*model.PriceDrop, error) {
+	p := prices.DropParams{}
+	if category != nil {
+		p.Category = *category
+	}
+	if since != nil && *since != "" {
+		t, err := parseTime("since", *since)
+		if err != nil {
 			return nil, err
 		}
-
-		var sizePrices []model.SizePrice
-		if err := json.Unmarshal(sizePricesRaw, &sizePrices); err != nil {
+		p.Since = t
+	}
+	if minPercent != nil {
+		p.MinPercent = *minPercent
+	}
+	if first != nil {
+		p.First = *first
+	}
+	drops, err := prices.Drops(ctx, r.Catalog, p)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*model.PriceDrop, len(drops))
+	for i, d := range drops {
+		if out[i], err = r.priceDrop(ctx, d, currency); err != nil {
 			return nil, err
 		}
-		var sizePricesPtr []*model.SizePrice
-		for _, sp := range sizePrices {
-			spCopy := sp
-			sizePricesPtr = append(sizePricesPtr, &spCopy)
-		}
-		apparels = append(apparels, &model.Apparel{
-			ID:          id,
-			Brand:       brandVal,
-			ProductName: productName,
-			Subcategory: subcategoryVal,
-			Gender:      genderVal,
-			SizePrices:  sizePricesPtr,
-			Images:      images,
-			InStock:     inStock,
-			ProductLink: productLink,
-			SellerName:  sellerName,
-			SellerURL:   sellerUrl,
-		})
 	}
+	return out, nil
+}
 
-	return apparels, nil
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
+	if u := auth.FromContext(ctx); u != nil {
+		return userModel(u), nil
+	}
+	return nil, nil
 }
 
-// ApparelItem is the resolver for the apparelItem field.
-func (r *queryResolver) ApparelItem(ctx context.Context, id string) (*model.Apparel, error) {
-	query := `SELECT id, brand, product_name, subcategory, gender, size_prices, images, in_stock, product_link, seller_name, seller_url FROM apparel WHERE id = $1`
-	row := r.DB.QueryRow(query, id)
-	var idVal, brandVal, productName, subcategoryVal, genderVal, productLink string
-	var sizePricesRaw []byte
-	var images []string
-	var inStock bool
-	var sellerName, sellerUrl *string
-	if err := row.Scan(&idVal, &brandVal, &productName, &subcategoryVal, &genderVal, &sizePricesRaw, pq.Array(&images), &inStock, &productLink, &sellerName, &sellerUrl); err != nil {
+// MyAlerts is the resolver for the myAlerts field.
+func (r *queryResolver) MyAlerts(ctx context.Context) ([]*model.Alert, error) {
+	user, err := auth.Require(ctx)
+	if err != nil {
 		return nil, err
 	}
-	var sizePrices []model.SizePrice
-	if err := json.Unmarshal(sizePricesRaw, &sizePrices); err != nil {
+	list, err := alerts.List(ctx, r.Catalog, user.ID)
+	if err != nil {
 		return nil, err
 	}
-	var sizePricesPtr []*model.SizePrice
-	for _, sp := range sizePrices {
-		spCopy := sp
-		sizePricesPtr = append(sizePricesPtr, &spCopy)
-	}
-	return &model.Apparel{
-		ID:          idVal,
-		Brand:       brandVal,
-		ProductName: productName,
-		Subcategory: subcategoryVal,
-		Gender:      genderVal,
-		SizePrices:  sizePricesPtr,
-		Images:      images,
-		InStock:     inStock,
-		ProductLink: productLink,
-		SellerName:  sellerName,
-		SellerURL:   sellerUrl,
-	}, nil
+	out := make([]*model.Alert, len(list))
+	for i, a := range list {
+		out[i] = alertModel(a)
+	}
+	return out, nil
 }
 
-// AllSneakerBrands is the resolver for the allSneakerBrands field.
-func (r *queryResolver) AllSneakerBrands(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT brand FROM sneakers")
+// MyStash is the resolver for the myStash field.
+func (r *queryResolver) MyStash(ctx context.Context) ([]*model.StashItem, error) {
+	user, err := auth.Require(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var brands []string
-	for rows.Next() {
-		var brand string
-		if err := rows.Scan(&brand); err != nil {
+	return r.myStash(ctx, user.ID)
+}
+
+// ArchivedProducts is the resolver for the archivedProducts field.
+func (r *queryResolver) ArchivedProducts(ctx context.Context, category *string) ([]*model.ArchivedProduct, error) {
+	var c *catalog.Category
+	if category != nil {
+		var err error
+		if c, err = findCategory(*category); err != nil {
 			return nil, err
 		}
-		brands = append(brands, brand)
 	}
-	return brands, nil
+	archived, err := r.Catalog.Archived(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*model.ArchivedProduct, len(archived))
+	for i, a := range archived {
+		out[i] = archivedProductModel(a)
+	}
+	return out, nil
 }
 
-// AllSneakerSizes is the resolver for the allSneakerSizes field.
-func (r *queryResolver) AllSneakerSizes(ctx context.Context, brand *string) ([]string, error) {
-	var rows *sql.Rows
-	var err error
-	if brand != nil && *brand != "" {
-		// Use a simpler brand comparison
-		rows, err = r.DB.Query(`SELECT size_prices FROM sneakers WHERE LOWER(brand) = LOWER($1)`, *brand)
-	} else {
-		rows, err = r.DB.Query(`SELECT size_prices FROM sneakers`)
-	}
+// Enquiries is the resolver for the enquiries field.
+func (r *queryResolver) Enquiries(ctx context.Context, filter *model.EnquiryFilter, limit *int, offset *int) (*model.EnquiryPage, error) {
+	f, err := enquiryFilter(filter, limit, offset)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	sizeSet := make(map[string]struct{})
-	for rows.Next() {
-		var sizePricesRaw []byte
-		if err := rows.Scan(&sizePricesRaw); err != nil {
-			return nil, err
-		}
-		var sizePrices []model.SizePrice
-		if err := json.Unmarshal(sizePricesRaw, &sizePrices); err != nil {
-			continue // skip bad rows
-		}
-		for _, sp := range sizePrices {
-			if sp.Size != "" {
-				sizeSet[sp.Size] = struct{}{}
-			}
-		}
+	list, total, err := enquiries.List(ctx, r.Catalog, f)
+	if err != nil {
+		return nil, err
 	}
-	var sizes []string
-	for s := range sizeSet {
-		sizes = append(sizes, s)
+	page := &model.EnquiryPage{Items: make([]*model.Enquiry, len(list)), TotalCount: total}
+	for i, e := range list {
+		page.Items[i] = enquiryModel(e)
 	}
-	sort.Strings(sizes)
-	return sizes, nil
+	return page, nil
 }
 
-// AllWatchBrands is the resolver for the allWatchBrands field.
-func (r *queryResolver) AllWatchBrands(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT brand FROM watches")
+// Enquiry is the resolver for the enquiry field.
+func (r *queryResolver) Enquiry(ctx context.Context, id string) (*model.Enquiry, error) {
+	n, err := enquiryID(id)
 	if err != nil {
-		return nil, err
+		return nil, nil
 	}
-	defer rows.Close()
-	var brands []string
-	for rows.Next() {
-		var brand string
-		if err := rows.Scan(&brand); err != nil {
-			return nil, err
-		}
-		brands = append(brands, brand)
+	e, err := enquiries.Get(ctx, r.Catalog, n)
+	if err == enquiries.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
-	return brands, nil
+	return enquiryModel(e), nil
+}
+
+// AllSneakerBrands is the resolver for the allSneakerBrands field.
+func (r *queryResolver) AllSneakerBrands(ctx context.Context) ([]string, error) {
+	return r.Catalog.Brands(ctx, catalog.Sneakers)
+}
+
+// AllSneakerSizes is the resolver for the allSneakerSizes field.
+func (r *queryResolver) AllSneakerSizes(ctx context.Context, brand *string) ([]string, error) {
+	return r.Catalog.Sizes(ctx, catalog.Sneakers, brand)
+}
+
+// AllWatchBrands is the resolver for the allWatchBrands field.
+func (r *queryResolver) AllWatchBrands(ctx context.Context) ([]string, error) {
+	return r.Catalog.Brands(ctx, catalog.Watches)
 }
 
 // AllPerfumeBrands is the resolver for the allPerfumeBrands field.
 func (r *queryResolver) AllPerfumeBrands(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT brand FROM perfumes")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var brands []string
-	for rows.Next() {
-		var brand string
-		if err := rows.Scan(&brand); err != nil {
-			return nil, err
-		}
-		brands = append(brands, brand)
-	}
-	return brands, nil
+	return r.Catalog.Brands(ctx, catalog.Perfumes)
 }
 
 // AllAccessoryBrands is the resolver for the allAccessoryBrands field.
 func (r *queryResolver) AllAccessoryBrands(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT brand FROM accessories")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var brands []string
-	for rows.Next() {
-		var brand string
-		if err := rows.Scan(&brand); err != nil {
-			return nil, err
-		}
-		brands = append(brands, brand)
-	}
-	return brands, nil
+	return r.Catalog.Brands(ctx, catalog.Accessories)
 }
 
 // AllApparelBrands is the resolver for the allApparelBrands field.
 func (r *queryResolver) AllApparelBrands(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT brand FROM apparel")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var brands []string
-	for rows.Next() {
-		var brand string
-		if err := rows.Scan(&brand); err != nil {
-			return nil, err
-		}
-		brands = append(brands, brand)
-	}
-	return brands, nil
+	return r.Catalog.Brands(ctx, catalog.Apparel)
 }
 
 // AllSneakerSubcategories is the resolver for the allSneakerSubcategories field.
 func (r *queryResolver) AllSneakerSubcategories(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT subcategory FROM sneakers")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var subcategories []string
-	for rows.Next() {
-		var subcategory sql.NullString
-		if err := rows.Scan(&subcategory); err != nil {
-			return nil, err
-		}
-		if subcategory.Valid {
-			subcategories = append(subcategories, subcategory.String)
-		}
-	}
-	return subcategories, nil
+	return r.Catalog.Distinct(ctx, catalog.Sneakers, "subcategory")
 }
 
 // AllApparelSubcategories is the resolver for the allApparelSubcategories field.
 func (r *queryResolver) AllApparelSubcategories(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT subcategory FROM apparel")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var subcategories []string
-	for rows.Next() {
-		var subcategory sql.NullString
-		if err := rows.Scan(&subcategory); err != nil {
-			return nil, err
-		}
-		if subcategory.Valid {
-			subcategories = append(subcategories, subcategory.String)
-		}
-	}
-	return subcategories, nil
+	return r.Catalog.Distinct(ctx, catalog.Apparel, "subcategory")
 }
 
 // AllAccessorySubcategories is the resolver for the allAccessorySubcategories field.
 func (r *queryResolver) AllAccessorySubcategories(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT subcategory FROM accessories")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var subcategories []string
-	for rows.Next() {
-		var subcategory sql.NullString
-		if err := rows.Scan(&subcategory); err != nil {
-			return nil, err
-		}
-		if subcategory.Valid {
-			subcategories = append(subcategories, subcategory.String)
-		}
-	}
-	return subcategories, nil
+	return r.Catalog.Distinct(ctx, catalog.Accessories, "subcategory")
 }
 
 // AllWatchSubcategories is the resolver for the allWatchSubcategories field.
 func (r *queryResolver) AllWatchSubcategories(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT subcategory FROM watches")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var subcategories []string
-	for rows.Next() {
-		var subcategory sql.NullString
-		if err := rows.Scan(&subcategory); err != nil {
-			return nil, err
-		}
-		if subcategory.Valid {
-			subcategories = append(subcategories, subcategory.String)
-		}
-	}
-	return subcategories, nil
+	return r.Catalog.Distinct(ctx, catalog.Watches, "subcategory")
 }
 
 // AllPerfumeSubcategories is the resolver for the allPerfumeSubcategories field.
 func (r *queryResolver) AllPerfumeSubcategories(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT subcategory FROM perfumes")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var subcategories []string
-	for rows.Next() {
-		var subcategory sql.NullString
-		if err := rows.Scan(&subcategory); err != nil {
-			return nil, err
-		}
-		if subcategory.Valid {
-			subcategories = append(subcategories, subcategory.String)
-		}
-	}
-	return subcategories, nil
+	return r.Catalog.Distinct(ctx, catalog.Perfumes, "subcategory")
 }
 
 // AllApparelGenders is the resolver for the allApparelGenders field.
 func (r *queryResolver) AllApparelGenders(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT gender FROM apparel")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var genders []string
-	for rows.Next() {
-		var gender sql.NullString
-		if err := rows.Scan(&gender); err != nil {
-			return nil, err
-		}
-		if gender.Valid {
-			genders = append(genders, gender.String)
-		}
-	}
-	return genders, nil
+	return r.Catalog.Distinct(ctx, catalog.Apparel, "gender")
 }
 
 // AllAccessoryGenders is the resolver for the allAccessoryGenders field.
 func (r *queryResolver) AllAccessoryGenders(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT gender FROM accessories")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var genders []string
-	for rows.Next() {
-		var gender sql.NullString
-		if err := rows.Scan(&gender); err != nil {
-			return nil, err
-		}
-		if gender.Valid {
-			genders = append(genders, gender.String)
-		}
-	}
-	return genders, nil
+	return r.Catalog.Distinct(ctx, catalog.Accessories, "gender")
 }
 
 // AllWatchGenders is the resolver for the allWatchGenders field.
 func (r *queryResolver) AllWatchGenders(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT gender FROM watches")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var genders []string
-	for rows.Next() {
-		var gender sql.NullString
-		if err := rows.Scan(&gender); err != nil {
-			return nil, err
-		}
-		if gender.Valid {
-			genders = append(genders, gender.String)
-		}
-	}
-	return genders, nil
+	return r.Catalog.Distinct(ctx, catalog.Watches, "gender")
 }
 
 // AllSneakerGenders is the resolver for the allSneakerGenders field.
 func (r *queryResolver) AllSneakerGenders(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT gender FROM sneakers")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var genders []string
-	for rows.Next() {
-		var gender sql.NullString
-		if err := rows.Scan(&gender); err != nil {
-			return nil, err
-		}
-		if gender.Valid {
-			genders = append(genders, gender.String)
-		}
-	}
-	return genders, nil
+	return r.Catalog.Distinct(ctx, catalog.Sneakers, "gender")
 }
 
 // AllPerfumeGenders is the resolver for the allPerfumeGenders field.
 func (r *queryResolver) AllPerfumeGenders(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT gender FROM perfumes")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var genders []string
-	for rows.Next() {
-		var gender sql.NullString
-		if err := rows.Scan(&gender); err != nil {
-			return nil, err
-		}
-		if gender.Valid {
-			genders = append(genders, gender.String)
-		}
-	}
-	return genders, nil
+	return r.Catalog.Distinct(ctx, catalog.Perfumes, "gender")
 }
 
 // AllPerfumeFragranceFamilies is the resolver for the allPerfumeFragranceFamilies field.
 func (r *queryResolver) AllPerfumeFragranceFamilies(ctx context.Context) ([]string, error) {
-	rows, err := r.DB.Query("SELECT DISTINCT fragrance_family FROM perfumes")
-	if err != nil {
-		return nil, err
+	return r.Catalog.Distinct(ctx, catalog.Perfumes, "fragrance_family")
+}
+
+// Money is the resolver for the money field.
+func (r *sizePriceResolver) Money(ctx context.Context, obj *model.SizePrice, currency *string) (*model.Money, error) {
+	return r.convert(ctx, enclosingCurrency(ctx), obj.Price, currency)
+}
+
+// Price is the resolver for the price field.
+func (r *sneakerResolver) Price(ctx context.Context, obj *model.Sneaker, currency *string) (*model.Money, error) {
+	amount, ok := lowestSizePrice(obj.SizePrices)
+	if !ok {
+		return nil, nil
 	}
-	defer rows.Close()
-	var families []string
-	for rows.Next() {
-		var family sql.NullString
-		if err := rows.Scan(&family); err != nil {
-			return nil, err
-		}
-		if family.Valid {
-			families = append(families, family.String)
-		}
+	return r.money(ctx, catalog.Sneakers, amount, currency)
+}
+
+// PriceHistory is the resolver for the priceHistory field.
+func (r *sneakerResolver) PriceHistory(ctx context.Context, obj *model.Sneaker, size *string, currency *string) ([]*model.PricePoint, error) {
+	return r.priceHistory(ctx, catalog.Sneakers, obj.ID, size, currency)
+}
+
+// Price is the resolver for the price field.
+func (r *watchResolver) Price(ctx context.Context, obj *model.Watch, currency *string) (*model.Money, error) {
+	return r.money(ctx, catalog.Watches, obj.SalePrice, currency)
+}
+
+// RetailPrice is the resolver for the retailPrice field.
+func (r *watchResolver) RetailPrice(ctx context.Context, obj *model.Watch, currency *string) (*model.Money, error) {
+	amount, ok := parseAmount(obj.MarketPrice)
+	if !ok {
+		return nil, nil
 	}
-	return families, nil
+	return r.money(ctx, catalog.Watches, amount, currency)
+}
+
+// PriceHistory is the resolver for the priceHistory field.
+func (r *watchResolver) PriceHistory(ctx context.Context, obj *model.Watch, size *string, currency *string) ([]*model.PricePoint, error) {
+	return r.priceHistory(ctx, catalog.Watches, obj.ID, size, currency)
 }
 
+// Accessory returns generated.AccessoryResolver implementation.
+func (r *Resolver) Accessory() generated.AccessoryResolver { return &accessoryResolver{r} }
+
+// Apparel returns generated.ApparelResolver implementation.
+func (r *Resolver) Apparel() generated.ApparelResolver { return &apparelResolver{r} }
+
+// Brand returns generated.BrandResolver implementation.
+func (r *Resolver) Brand() generated.BrandResolver { return &brandResolver{r} }
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
+// Perfume returns generated.PerfumeResolver implementation.
+func (r *Resolver) Perfume() generated.PerfumeResolver { return &perfumeResolver{r} }
+
+// PerfumeVariant returns generated.PerfumeVariantResolver implementation.
+func (r *Resolver) PerfumeVariant() generated.PerfumeVariantResolver {
+	return &perfumeVariantResolver{r}
+}
+
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+// SizePrice returns generated.SizePriceResolver implementation.
+func (r *Resolver) SizePrice() generated.SizePriceResolver { return &sizePriceResolver{r} }
+
+// Sneaker returns generated.SneakerResolver implementation.
+func (r *Resolver) Sneaker() generated.SneakerResolver { return &sneakerResolver{r} }
+
+// Watch returns generated.WatchResolver implementation.
+func (r *Resolver) Watch() generated.WatchResolver { return &watchResolver{r} }
+
+type accessoryResolver struct{ *Resolver }
+type apparelResolver struct{ *Resolver }
+type brandResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type perfumeResolver struct{ *Resolver }
+type perfumeVariantResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type sizePriceResolver struct{ *Resolver }
+type sneakerResolver struct{ *Resolver }
+type watchResolver struct{ *Resolver }
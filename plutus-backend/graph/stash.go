@@ -0,0 +1,31 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"plutus-backend/graph/model"
+	"plutus-backend/stash"
+)
+
+// stashItemModel converts a stashed product into its GraphQL form.
+func stashItemModel(item *stash.Item) *model.StashItem {
+	return &model.StashItem{
+		Category: item.Category,
+		Product:  item.Product,
+		AddedAt:  item.AddedAt.Format(time.RFC3339),
+	}
+}
+
+// myStash loads a user's stash in its GraphQL form.
+func (r *Resolver) myStash(ctx context.Context, userID int) ([]*model.StashItem, error) {
+	items, err := stash.List(ctx, r.Catalog, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*model.StashItem, len(items))
+	for i, item := range items {
+		out[i] = stashItemModel(item)
+	}
+	return out, nil
+}
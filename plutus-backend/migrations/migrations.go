@@ -0,0 +1,294 @@
+// Package migrations versions the database schema. Each migration is a pair
+// of SQL files embedded from sql/, NNNN_name.up.sql and NNNN_name.down.sql,
+// applied in version order, each in its own transaction, and recorded in
+// schema_migrations. plutusctl migrate applies and reverts them; the server
+// refuses to start while any is pending.
+//
+// The server used to create its tables on every start, so the first
+// migrations use IF NOT EXISTS and CREATE OR REPLACE throughout: applied to
+// a database it set up that way, they record the schema already there.
+// Later migrations don't need to.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// Migration is one step of the schema.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m *Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// All returns the embedded migrations in version order.
+var All = sync.OnceValues(func() ([]*Migration, error) {
+	sub, err := fs.Sub(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return parse(sub)
+})
+
+// parse reads the migrations in the top directory of fsys and returns them
+// in version order.
+func parse(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		match := fileName.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name isn't NNNN_name.up.sql or NNNN_name.down.sql", e.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %s: version %d is also %s", e.Name(), version, m)
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		half := &m.Down
+		if match[3] == "up" {
+			half = &m.Up
+		}
+		if *half != "" {
+			return nil, fmt.Errorf("migration %s: version %d has two %s files", e.Name(), version, match[3])
+		}
+		*half = string(body)
+	}
+	all := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %s: needs both an up and a down file", m)
+		}
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all, nil
+}
+
+// State is a migration's standing in a database.
+type State struct {
+	Version int
+	Name    string
+	// AppliedAt is nil while the migration is pending.
+	AppliedAt *time.Time
+	// Unknown reports a migration the database has applied that this build
+	// doesn't have, as after rolling back to an older release.
+	Unknown bool
+}
+
+// Status lists every migration, embedded or applied, in version order.
+func Status(ctx context.Context, db *sql.DB) ([]State, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	done, err := applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	var states []State
+	for _, m := range all {
+		s := State{Version: m.Version, Name: m.Name}
+		if a, ok := done[m.Version]; ok {
+			s.AppliedAt = &a.at
+			delete(done, m.Version)
+		}
+		states = append(states, s)
+	}
+	for version, a := range done {
+		states = append(states, State{Version: version, Name: a.name, AppliedAt: &a.at, Unknown: true})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, nil
+}
+
+// Pending returns the migrations the database hasn't applied, in version
+// order.
+func Pending(ctx context.Context, db *sql.DB) ([]*Migration, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	done, err := applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	return pending(all, done), nil
+}
+
+func pending(all []*Migration, done map[int]appliedMigration) []*Migration {
+	var out []*Migration
+	for _, m := range all {
+		if _, ok := done[m.Version]; !ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Up applies every pending migration, returning the ones it applied. It
+// stops at the first that fails, which leaves nothing of that migration
+// behind.
+func Up(ctx context.Context, db *sql.DB) ([]*Migration, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	var ran []*Migration
+	err = locked(ctx, db, func(conn *sql.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range pending(all, done) {
+			err := inTx(ctx, conn, m.Up, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name)
+			if err != nil {
+				return fmt.Errorf("migration %s: %w", m, err)
+			}
+			ran = append(ran, m)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down reverts the latest steps applied migrations, newest first,
+// returning the ones it reverted.
+func Down(ctx context.Context, db *sql.DB, steps int) ([]*Migration, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, m := range all {
+		byVersion[m.Version] = m
+	}
+	var reverted []*Migration
+	err = locked(ctx, db, func(conn *sql.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int, 0, len(done))
+		for v := range done {
+			versions = append(versions, v)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+		for _, v := range versions[:min(steps, len(versions))] {
+			m := byVersion[v]
+			if m == nil {
+				return fmt.Errorf("migration %04d_%s is applied but not in this build, so it can't be reverted", v, done[v].name)
+			}
+			if err := inTx(ctx, conn, m.Down, "DELETE FROM schema_migrations WHERE version = $1", m.Version); err != nil {
+				return fmt.Errorf("migration %s: %w", m, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// locked runs fn on a connection holding the migration lock, so that two
+// servers or plutusctl runs never migrate at once.
+func locked(ctx context.Context, db *sql.DB, fn func(*sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(hashtext('schema_migrations'))"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext('schema_migrations'))")
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("ensure schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+// inTx runs a migration's SQL and records it in one transaction. The SQL
+// holds several statements, so it is sent without arguments.
+func inTx(ctx context.Context, conn *sql.Conn, migration, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, migration); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type appliedMigration struct {
+	name string
+	at   time.Time
+}
+
+// applied returns the migrations recorded in schema_migrations, by version.
+// A database without the table has applied none.
+func applied(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}) (map[int]appliedMigration, error) {
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, err
+	}
+	done := map[int]appliedMigration{}
+	if !exists {
+		return done, nil
+	}
+	rows, err := q.QueryContext(ctx, "SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.at); err != nil {
+			return nil, err
+		}
+		done[version] = a
+	}
+	return done, rows.Err()
+}
@@ -0,0 +1,148 @@
+package migrations
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	names := map[string]int{}
+	for i, m := range all {
+		if m.Version != i+1 {
+			t.Fatalf("migration %s: want version %d, so that versions run 1, 2, 3... without gaps", m, i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %s: up or down file is blank", m)
+		}
+		if v, ok := names[m.Name]; ok {
+			t.Errorf("migration %s: name already used by version %d", m, v)
+		}
+		names[m.Name] = m.Version
+	}
+}
+
+func TestParse(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		versions []int
+		err      string
+	}{
+		{
+			name: "sorted by version, not file name",
+			fsys: fstest.MapFS{
+				"0010_ten.up.sql":   file("up 10"),
+				"0010_ten.down.sql": file("down 10"),
+				"0002_two.up.sql":   file("up 2"),
+				"0002_two.down.sql": file("down 2"),
+				"0001_one.up.sql":   file("up 1"),
+				"0001_one.down.sql": file("down 1"),
+			},
+			versions: []int{1, 2, 10},
+		},
+		{
+			name: "up without down",
+			fsys: fstest.MapFS{
+				"0001_one.up.sql": file("up 1"),
+			},
+			err: "needs both an up and a down file",
+		},
+		{
+			name: "down without up",
+			fsys: fstest.MapFS{
+				"0001_one.down.sql": file("down 1"),
+			},
+			err: "needs both an up and a down file",
+		},
+		{
+			name: "one version, two names",
+			fsys: fstest.MapFS{
+				"0001_one.up.sql":   file("up 1"),
+				"0001_uno.down.sql": file("down 1"),
+			},
+			err: "version 1 is also",
+		},
+		{
+			name: "one version written two ways",
+			fsys: fstest.MapFS{
+				"0001_one.up.sql":   file("up 1"),
+				"1_one.up.sql":      file("up 1 again"),
+				"0001_one.down.sql": file("down 1"),
+			},
+			err: "version 1 has two up files",
+		},
+		{
+			name: "badly named file",
+			fsys: fstest.MapFS{
+				"0001_one.sql": file("up 1"),
+			},
+			err: "name isn't",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all, err := parse(tt.fsys)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var versions []int
+			for _, m := range all {
+				versions = append(versions, m.Version)
+				if want := "up " + strconv.Itoa(m.Version); m.Up != want {
+					t.Errorf("migration %s: Up = %q, want %q", m, m.Up, want)
+				}
+				if want := "down " + strconv.Itoa(m.Version); m.Down != want {
+					t.Errorf("migration %s: Down = %q, want %q", m, m.Down, want)
+				}
+			}
+			if !slices.Equal(versions, tt.versions) {
+				t.Errorf("versions = %v, want %v", versions, tt.versions)
+			}
+		})
+	}
+}
+
+func TestPending(t *testing.T) {
+	all := []*Migration{{Version: 1, Name: "one"}, {Version: 2, Name: "two"}, {Version: 3, Name: "three"}, {Version: 4, Name: "four"}}
+	tests := []struct {
+		name string
+		done []int
+		want []int
+	}{
+		{"fresh database", nil, []int{1, 2, 3, 4}},
+		{"up to date", []int{1, 2, 3, 4}, nil},
+		{"behind", []int{1, 2}, []int{3, 4}},
+		{"gaps are filled in order", []int{1, 3}, []int{2, 4}},
+		{"applied migrations this build lacks are ignored", []int{1, 2, 3, 4, 5}, nil},
+	}
+	for _, tt := range tests {
+		done := map[int]appliedMigration{}
+		for _, v := range tt.done {
+			done[v] = appliedMigration{name: "m" + strconv.Itoa(v)}
+		}
+		var got []int
+		for _, m := range pending(all, done) {
+			got = append(got, m.Version)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: pending = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
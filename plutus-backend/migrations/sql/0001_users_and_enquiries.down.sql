@@ -0,0 +1,2 @@
+DROP TABLE enquiries;
+DROP TABLE users;
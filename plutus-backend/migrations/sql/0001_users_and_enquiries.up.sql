@@ -1,4 +1,3 @@
--- Create users table
 CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     user_id VARCHAR(50) UNIQUE NOT NULL,
@@ -10,7 +9,6 @@ CREATE TABLE IF NOT EXISTS users (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
--- Create enquiries table
 CREATE TABLE IF NOT EXISTS enquiries (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
@@ -23,9 +21,8 @@ CREATE TABLE IF NOT EXISTS enquiries (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
--- Create indexes for better performance
-CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
-CREATE INDEX IF NOT EXISTS idx_users_user_id ON users(user_id);
-CREATE INDEX IF NOT EXISTS idx_enquiries_email ON enquiries(email);
-CREATE INDEX IF NOT EXISTS idx_enquiries_product_id ON enquiries(product_id);
-CREATE INDEX IF NOT EXISTS idx_enquiries_created_at ON enquiries(created_at); 
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_user_id ON users (user_id);
+CREATE INDEX IF NOT EXISTS idx_enquiries_email ON enquiries (email);
+CREATE INDEX IF NOT EXISTS idx_enquiries_product_id ON enquiries (product_id);
+CREATE INDEX IF NOT EXISTS idx_enquiries_created_at ON enquiries (created_at);
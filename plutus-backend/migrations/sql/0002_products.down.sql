@@ -0,0 +1,5 @@
+DROP TABLE apparel;
+DROP TABLE accessories;
+DROP TABLE perfumes;
+DROP TABLE watches;
+DROP TABLE sneakers;
@@ -0,0 +1,81 @@
+-- The product tables, one per catalog category (see catalog/categories.go).
+-- The scrapers and the seeder fill them; prices are in the category's
+-- currency.
+
+CREATE TABLE IF NOT EXISTS sneakers (
+    id SERIAL PRIMARY KEY,
+    brand TEXT NOT NULL,
+    product_name TEXT NOT NULL,
+    size_prices JSONB NOT NULL,
+    images TEXT[] NOT NULL,
+    sold_out BOOLEAN NOT NULL DEFAULT false,
+    product_link TEXT NOT NULL,
+    seller_name TEXT,
+    seller_url TEXT
+);
+
+-- market_price is free text such as "AED6,500.00" or "N/A".
+CREATE TABLE IF NOT EXISTS watches (
+    id SERIAL PRIMARY KEY,
+    brand TEXT NOT NULL,
+    name TEXT NOT NULL,
+    color TEXT NOT NULL DEFAULT '',
+    sale_price DOUBLE PRECISION NOT NULL,
+    market_price TEXT NOT NULL DEFAULT '',
+    images TEXT[] NOT NULL,
+    link TEXT NOT NULL,
+    seller_name TEXT,
+    seller_url TEXT,
+    gender TEXT
+);
+
+-- variants is an array of {"size", "price"} objects; prices may be numbers
+-- or numeric strings.
+CREATE TABLE IF NOT EXISTS perfumes (
+    id SERIAL PRIMARY KEY,
+    brand TEXT NOT NULL,
+    title TEXT NOT NULL,
+    fragrance_family TEXT NOT NULL DEFAULT '',
+    concentration TEXT,
+    subcategory TEXT,
+    variants JSONB NOT NULL,
+    images TEXT[] NOT NULL,
+    url TEXT NOT NULL,
+    seller_name TEXT,
+    seller_url TEXT
+);
+
+CREATE TABLE IF NOT EXISTS accessories (
+    id SERIAL PRIMARY KEY,
+    brand TEXT NOT NULL,
+    product_name TEXT NOT NULL,
+    subcategory TEXT NOT NULL,
+    gender TEXT NOT NULL,
+    size_prices JSONB NOT NULL,
+    images TEXT[] NOT NULL,
+    in_stock BOOLEAN NOT NULL,
+    product_link TEXT NOT NULL,
+    seller_name TEXT,
+    seller_url TEXT
+);
+
+CREATE TABLE IF NOT EXISTS apparel (
+    id SERIAL PRIMARY KEY,
+    brand TEXT NOT NULL,
+    product_name TEXT NOT NULL,
+    subcategory TEXT NOT NULL,
+    gender TEXT NOT NULL,
+    size_prices JSONB NOT NULL,
+    images TEXT[] NOT NULL,
+    in_stock BOOLEAN NOT NULL,
+    product_link TEXT NOT NULL,
+    seller_name TEXT,
+    seller_url TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_sneakers_brand ON sneakers (LOWER(brand));
+CREATE INDEX IF NOT EXISTS idx_sneakers_brand_product_name ON sneakers (LOWER(brand), LOWER(product_name));
+CREATE INDEX IF NOT EXISTS idx_watches_brand ON watches (LOWER(brand));
+CREATE INDEX IF NOT EXISTS idx_perfumes_brand ON perfumes (LOWER(brand));
+CREATE INDEX IF NOT EXISTS idx_accessories_brand ON accessories (LOWER(brand));
+CREATE INDEX IF NOT EXISTS idx_apparel_brand ON apparel (LOWER(brand));
@@ -0,0 +1,17 @@
+DROP INDEX idx_watches_price_asc;
+DROP INDEX idx_watches_price_desc;
+DROP INDEX idx_watches_price;
+
+-- Dropping the columns drops their indexes too.
+DROP TRIGGER sneakers_price_range ON sneakers;
+ALTER TABLE sneakers DROP COLUMN min_price, DROP COLUMN max_price;
+DROP TRIGGER perfumes_price_range ON perfumes;
+ALTER TABLE perfumes DROP COLUMN min_price, DROP COLUMN max_price;
+DROP TRIGGER accessories_price_range ON accessories;
+ALTER TABLE accessories DROP COLUMN min_price, DROP COLUMN max_price;
+DROP TRIGGER apparel_price_range ON apparel;
+ALTER TABLE apparel DROP COLUMN min_price, DROP COLUMN max_price;
+
+DROP FUNCTION sync_price_range();
+DROP FUNCTION jsonb_max_price(jsonb);
+DROP FUNCTION jsonb_min_price(jsonb);
@@ -0,0 +1,70 @@
+-- min_price/max_price on every category priced from a JSONB column, kept
+-- current by a trigger, and the indexes behind price sorting and filtering.
+-- Rows written before the triggers existed keep NULL ranges until
+-- `plutusctl backfill-prices` is run.
+
+-- jsonb_min_price and jsonb_max_price derive a price range from a JSONB
+-- array of objects with a "price" field. Prices may be stored as numbers or
+-- numeric strings (see PerfumeVariant.UnmarshalJSON); anything else counts
+-- as unpriced, as does a value that isn't an array. sync_price_range is the
+-- trigger keeping min_price/max_price in step with the column named by its
+-- argument.
+CREATE OR REPLACE FUNCTION jsonb_min_price(prices jsonb) RETURNS double precision
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT MIN(CASE WHEN e->>'price' ~ '^\s*[0-9]*\.?[0-9]+\s*$' THEN (e->>'price')::float8 END)
+    FROM jsonb_array_elements(CASE WHEN jsonb_typeof(prices) = 'array' THEN prices END) e
+$$;
+
+CREATE OR REPLACE FUNCTION jsonb_max_price(prices jsonb) RETURNS double precision
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT MAX(CASE WHEN e->>'price' ~ '^\s*[0-9]*\.?[0-9]+\s*$' THEN (e->>'price')::float8 END)
+    FROM jsonb_array_elements(CASE WHEN jsonb_typeof(prices) = 'array' THEN prices END) e
+$$;
+
+CREATE OR REPLACE FUNCTION sync_price_range() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    prices jsonb := to_jsonb(NEW) -> TG_ARGV[0];
+BEGIN
+    NEW.min_price := jsonb_min_price(prices);
+    NEW.max_price := jsonb_max_price(prices);
+    RETURN NEW;
+END
+$$;
+
+ALTER TABLE sneakers ADD COLUMN IF NOT EXISTS min_price double precision, ADD COLUMN IF NOT EXISTS max_price double precision;
+ALTER TABLE perfumes ADD COLUMN IF NOT EXISTS min_price double precision, ADD COLUMN IF NOT EXISTS max_price double precision;
+ALTER TABLE accessories ADD COLUMN IF NOT EXISTS min_price double precision, ADD COLUMN IF NOT EXISTS max_price double precision;
+ALTER TABLE apparel ADD COLUMN IF NOT EXISTS min_price double precision, ADD COLUMN IF NOT EXISTS max_price double precision;
+
+DROP TRIGGER IF EXISTS sneakers_price_range ON sneakers;
+CREATE TRIGGER sneakers_price_range BEFORE INSERT OR UPDATE OF size_prices ON sneakers FOR EACH ROW EXECUTE FUNCTION sync_price_range('size_prices');
+DROP TRIGGER IF EXISTS perfumes_price_range ON perfumes;
+CREATE TRIGGER perfumes_price_range BEFORE INSERT OR UPDATE OF variants ON perfumes FOR EACH ROW EXECUTE FUNCTION sync_price_range('variants');
+DROP TRIGGER IF EXISTS accessories_price_range ON accessories;
+CREATE TRIGGER accessories_price_range BEFORE INSERT OR UPDATE OF size_prices ON accessories FOR EACH ROW EXECUTE FUNCTION sync_price_range('size_prices');
+DROP TRIGGER IF EXISTS apparel_price_range ON apparel;
+CREATE TRIGGER apparel_price_range BEFORE INSERT OR UPDATE OF size_prices ON apparel FOR EACH ROW EXECUTE FUNCTION sync_price_range('size_prices');
+
+-- The sort indexes are built from the same expressions as the sort keys
+-- (catalog.priceKey) so the planner can walk them for keyset pages in
+-- either direction.
+CREATE INDEX IF NOT EXISTS idx_sneakers_price_asc ON sneakers ((COALESCE((min_price)::float8, 'Infinity')), id);
+CREATE INDEX IF NOT EXISTS idx_sneakers_price_desc ON sneakers ((COALESCE((min_price)::float8, '-Infinity')), id);
+CREATE INDEX IF NOT EXISTS idx_sneakers_price ON sneakers (min_price);
+CREATE INDEX IF NOT EXISTS idx_sneakers_max_price ON sneakers (max_price);
+CREATE INDEX IF NOT EXISTS idx_watches_price_asc ON watches ((COALESCE((sale_price)::float8, 'Infinity')), id);
+CREATE INDEX IF NOT EXISTS idx_watches_price_desc ON watches ((COALESCE((sale_price)::float8, '-Infinity')), id);
+CREATE INDEX IF NOT EXISTS idx_watches_price ON watches (sale_price);
+CREATE INDEX IF NOT EXISTS idx_perfumes_price_asc ON perfumes ((COALESCE((min_price)::float8, 'Infinity')), id);
+CREATE INDEX IF NOT EXISTS idx_perfumes_price_desc ON perfumes ((COALESCE((min_price)::float8, '-Infinity')), id);
+CREATE INDEX IF NOT EXISTS idx_perfumes_price ON perfumes (min_price);
+CREATE INDEX IF NOT EXISTS idx_perfumes_max_price ON perfumes (max_price);
+CREATE INDEX IF NOT EXISTS idx_accessories_price_asc ON accessories ((COALESCE((min_price)::float8, 'Infinity')), id);
+CREATE INDEX IF NOT EXISTS idx_accessories_price_desc ON accessories ((COALESCE((min_price)::float8, '-Infinity')), id);
+CREATE INDEX IF NOT EXISTS idx_accessories_price ON accessories (min_price);
+CREATE INDEX IF NOT EXISTS idx_accessories_max_price ON accessories (max_price);
+CREATE INDEX IF NOT EXISTS idx_apparel_price_asc ON apparel ((COALESCE((min_price)::float8, 'Infinity')), id);
+CREATE INDEX IF NOT EXISTS idx_apparel_price_desc ON apparel ((COALESCE((min_price)::float8, '-Infinity')), id);
+CREATE INDEX IF NOT EXISTS idx_apparel_price ON apparel (min_price);
+CREATE INDEX IF NOT EXISTS idx_apparel_max_price ON apparel (max_price);
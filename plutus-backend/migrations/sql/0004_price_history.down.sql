@@ -0,0 +1,8 @@
+DROP TRIGGER apparel_price_history ON apparel;
+DROP TRIGGER accessories_price_history ON accessories;
+DROP TRIGGER perfumes_price_history ON perfumes;
+DROP TRIGGER watches_price_history ON watches;
+DROP TRIGGER sneakers_price_history ON sneakers;
+DROP FUNCTION record_price_history();
+DROP FUNCTION price_points(jsonb);
+DROP TABLE price_history;
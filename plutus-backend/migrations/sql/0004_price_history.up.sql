@@ -0,0 +1,63 @@
+-- price_history and the trigger recording into it on every product table.
+-- Products are identified by their link rather than their id, which a
+-- re-seed renumbers.
+CREATE TABLE IF NOT EXISTS price_history (
+    id BIGSERIAL PRIMARY KEY,
+    category TEXT NOT NULL,
+    product_link TEXT NOT NULL,
+    size TEXT NOT NULL DEFAULT '',
+    price DOUBLE PRECISION NOT NULL,
+    currency CHAR(3) NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_price_history_product ON price_history (category, product_link, size, recorded_at DESC);
+CREATE INDEX IF NOT EXISTS idx_price_history_recorded_at ON price_history (recorded_at);
+
+-- price_points expands a prices value into (size, price) rows: either a
+-- JSONB array of objects with "size" and "price" fields, priced the same
+-- way as jsonb_min_price, or a plain price for products without sizes.
+CREATE OR REPLACE FUNCTION price_points(prices jsonb) RETURNS TABLE (size text, price double precision)
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT COALESCE(TRIM(e->>'size'), ''), (e->>'price')::float8
+    FROM jsonb_array_elements(CASE WHEN jsonb_typeof(prices) = 'array' THEN prices END) e
+    WHERE e->>'price' ~ '^\s*[0-9]*\.?[0-9]+\s*$'
+    UNION ALL
+    SELECT '', (prices #>> '{}')::float8
+    WHERE jsonb_typeof(prices) IN ('number', 'string') AND prices #>> '{}' ~ '^\s*[0-9]*\.?[0-9]+\s*$'
+$$;
+
+-- record_price_history records the prices that differ from the last ones
+-- recorded. Its arguments are the category key, link column, prices column
+-- and currency.
+CREATE OR REPLACE FUNCTION record_price_history() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    product jsonb := to_jsonb(NEW);
+    link text := NULLIF(TRIM(product ->> TG_ARGV[1]), '');
+BEGIN
+    IF link IS NULL THEN
+        RETURN NULL;
+    END IF;
+    INSERT INTO price_history (category, product_link, size, price, currency)
+    SELECT DISTINCT ON (p.size) TG_ARGV[0], link, p.size, p.price, TG_ARGV[3]
+    FROM price_points(product -> TG_ARGV[2]) p
+    WHERE p.price IS DISTINCT FROM (
+        SELECT h.price FROM price_history h
+        WHERE h.category = TG_ARGV[0] AND h.product_link = link AND h.size = p.size
+        ORDER BY h.recorded_at DESC, h.id DESC LIMIT 1
+    )
+    ORDER BY p.size, p.price;
+    RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS sneakers_price_history ON sneakers;
+CREATE TRIGGER sneakers_price_history AFTER INSERT OR UPDATE OF size_prices ON sneakers FOR EACH ROW EXECUTE FUNCTION record_price_history('sneakers', 'product_link', 'size_prices', 'INR');
+DROP TRIGGER IF EXISTS watches_price_history ON watches;
+CREATE TRIGGER watches_price_history AFTER INSERT OR UPDATE OF sale_price ON watches FOR EACH ROW EXECUTE FUNCTION record_price_history('watches', 'link', 'sale_price', 'AED');
+DROP TRIGGER IF EXISTS perfumes_price_history ON perfumes;
+CREATE TRIGGER perfumes_price_history AFTER INSERT OR UPDATE OF variants ON perfumes FOR EACH ROW EXECUTE FUNCTION record_price_history('perfumes', 'url', 'variants', 'INR');
+DROP TRIGGER IF EXISTS accessories_price_history ON accessories;
+CREATE TRIGGER accessories_price_history AFTER INSERT OR UPDATE OF size_prices ON accessories FOR EACH ROW EXECUTE FUNCTION record_price_history('accessories', 'product_link', 'size_prices', 'INR');
+DROP TRIGGER IF EXISTS apparel_price_history ON apparel;
+CREATE TRIGGER apparel_price_history AFTER INSERT OR UPDATE OF size_prices ON apparel FOR EACH ROW EXECUTE FUNCTION record_price_history('apparel', 'product_link', 'size_prices', 'INR');
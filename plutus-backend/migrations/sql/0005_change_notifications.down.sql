@@ -0,0 +1,6 @@
+DROP TRIGGER apparel_changed ON apparel;
+DROP TRIGGER accessories_changed ON accessories;
+DROP TRIGGER perfumes_changed ON perfumes;
+DROP TRIGGER watches_changed ON watches;
+DROP TRIGGER sneakers_changed ON sneakers;
+DROP FUNCTION notify_catalog_changed();
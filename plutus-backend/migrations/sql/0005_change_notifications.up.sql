@@ -0,0 +1,21 @@
+-- Every insert, update, delete and truncate on the product tables is
+-- announced on the catalog_changed channel (catalog.ChangesChannel), with
+-- the table name as the payload. See catalog.WatchChanges.
+CREATE OR REPLACE FUNCTION notify_catalog_changed() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM pg_notify('catalog_changed', TG_TABLE_NAME);
+    RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS sneakers_changed ON sneakers;
+CREATE TRIGGER sneakers_changed AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON sneakers FOR EACH STATEMENT EXECUTE FUNCTION notify_catalog_changed();
+DROP TRIGGER IF EXISTS watches_changed ON watches;
+CREATE TRIGGER watches_changed AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON watches FOR EACH STATEMENT EXECUTE FUNCTION notify_catalog_changed();
+DROP TRIGGER IF EXISTS perfumes_changed ON perfumes;
+CREATE TRIGGER perfumes_changed AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON perfumes FOR EACH STATEMENT EXECUTE FUNCTION notify_catalog_changed();
+DROP TRIGGER IF EXISTS accessories_changed ON accessories;
+CREATE TRIGGER accessories_changed AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON accessories FOR EACH STATEMENT EXECUTE FUNCTION notify_catalog_changed();
+DROP TRIGGER IF EXISTS apparel_changed ON apparel;
+CREATE TRIGGER apparel_changed AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON apparel FOR EACH STATEMENT EXECUTE FUNCTION notify_catalog_changed();
@@ -0,0 +1,7 @@
+-- pg_trgm stays installed; other database objects may use it.
+DROP MATERIALIZED VIEW search_terms;
+ALTER TABLE apparel DROP COLUMN search_vector;
+ALTER TABLE accessories DROP COLUMN search_vector;
+ALTER TABLE perfumes DROP COLUMN search_vector;
+ALTER TABLE watches DROP COLUMN search_vector;
+ALTER TABLE sneakers DROP COLUMN search_vector;
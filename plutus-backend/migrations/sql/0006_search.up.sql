@@ -0,0 +1,39 @@
+-- Each product table carries its weighted search document as a generated
+-- tsvector, which Postgres fills for existing rows and keeps current on
+-- every write. The 'simple' configuration is catalog.TextSearchConfig;
+-- queries must use the same one.
+ALTER TABLE sneakers ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (setweight(to_tsvector('simple', COALESCE(brand, '')), 'A') || setweight(to_tsvector('simple', COALESCE(product_name, '')), 'A')) STORED;
+CREATE INDEX IF NOT EXISTS idx_sneakers_search ON sneakers USING GIN (search_vector);
+ALTER TABLE watches ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (setweight(to_tsvector('simple', COALESCE(brand, '')), 'A') || setweight(to_tsvector('simple', COALESCE(name, '')), 'A') || setweight(to_tsvector('simple', COALESCE(color, '')), 'C')) STORED;
+CREATE INDEX IF NOT EXISTS idx_watches_search ON watches USING GIN (search_vector);
+ALTER TABLE perfumes ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (setweight(to_tsvector('simple', COALESCE(brand, '')), 'A') || setweight(to_tsvector('simple', COALESCE(title, '')), 'A') || setweight(to_tsvector('simple', COALESCE(fragrance_family, '')), 'B') || setweight(to_tsvector('simple', COALESCE(subcategory, '')), 'C')) STORED;
+CREATE INDEX IF NOT EXISTS idx_perfumes_search ON perfumes USING GIN (search_vector);
+ALTER TABLE accessories ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (setweight(to_tsvector('simple', COALESCE(brand, '')), 'A') || setweight(to_tsvector('simple', COALESCE(product_name, '')), 'A') || setweight(to_tsvector('simple', COALESCE(subcategory, '')), 'B')) STORED;
+CREATE INDEX IF NOT EXISTS idx_accessories_search ON accessories USING GIN (search_vector);
+ALTER TABLE apparel ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (setweight(to_tsvector('simple', COALESCE(brand, '')), 'A') || setweight(to_tsvector('simple', COALESCE(product_name, '')), 'A') || setweight(to_tsvector('simple', COALESCE(subcategory, '')), 'B')) STORED;
+CREATE INDEX IF NOT EXISTS idx_apparel_search ON apparel USING GIN (search_vector);
+
+-- search_terms is the vocabulary typo correction draws from: every word of
+-- every search vector, and the distinct brand names with their most common
+-- spelling. search.RefreshTerms brings it up to date.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE MATERIALIZED VIEW IF NOT EXISTS search_terms AS
+    SELECT 'word' AS kind, word AS term, word AS display, SUM(ndoc)::int AS ndoc
+    FROM (
+        SELECT * FROM ts_stat('SELECT search_vector FROM sneakers')
+        UNION ALL SELECT * FROM ts_stat('SELECT search_vector FROM watches')
+        UNION ALL SELECT * FROM ts_stat('SELECT search_vector FROM perfumes')
+        UNION ALL SELECT * FROM ts_stat('SELECT search_vector FROM accessories')
+        UNION ALL SELECT * FROM ts_stat('SELECT search_vector FROM apparel')
+    ) w GROUP BY word
+    UNION ALL
+    SELECT 'brand', LOWER(TRIM(brand)), mode() WITHIN GROUP (ORDER BY TRIM(brand)), COUNT(*)::int
+    FROM (
+        SELECT brand FROM sneakers
+        UNION ALL SELECT brand FROM watches
+        UNION ALL SELECT brand FROM perfumes
+        UNION ALL SELECT brand FROM accessories
+        UNION ALL SELECT brand FROM apparel
+    ) b WHERE NULLIF(TRIM(brand), '') IS NOT NULL GROUP BY LOWER(TRIM(brand));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_search_terms_kind_term ON search_terms (kind, term);
+CREATE INDEX IF NOT EXISTS idx_search_terms_trgm ON search_terms USING GIN (term gin_trgm_ops);
@@ -0,0 +1,8 @@
+DROP INDEX idx_apparel_brand_key;
+DROP INDEX idx_accessories_brand_key;
+DROP INDEX idx_perfumes_brand_key;
+DROP INDEX idx_watches_brand_key;
+DROP INDEX idx_sneakers_brand_key;
+DROP FUNCTION brand_key(text);
+DROP TABLE brand_aliases;
+DROP TABLE brands;
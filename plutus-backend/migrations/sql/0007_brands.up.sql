@@ -0,0 +1,34 @@
+-- Canonical brands and the spellings that map to them. Brand filters and
+-- listings match products through brand_key, which must agree with
+-- brands.Key; changing it invalidates the brand_key indexes, so reindex
+-- them if it ever changes.
+CREATE TABLE brands (
+    id SERIAL PRIMARY KEY,
+    slug TEXT UNIQUE NOT NULL,
+    name TEXT NOT NULL,
+    logo_url TEXT,
+    description TEXT,
+    country TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE brand_aliases (
+    alias_key TEXT PRIMARY KEY,
+    alias TEXT NOT NULL,
+    brand_id INTEGER NOT NULL REFERENCES brands(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_brand_aliases_brand_id ON brand_aliases (brand_id);
+
+CREATE FUNCTION brand_key(name text) RETURNS text
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT btrim(regexp_replace(replace(lower(translate(name, 'ÀÁÂÃÄÅàáâãäåÇçÈÉÊËèéêëÌÍÎÏìíîïÑñÒÓÔÕÖØòóôõöøÙÚÛÜùúûüÝýÿ', 'AAAAAAaaaaaaCcEEEEeeeeIIIIiiiiNnOOOOOOooooooUUUUuuuuYyy')), '&', ' and '), '[^a-z0-9]+', ' ', 'g'))
+$$;
+
+CREATE INDEX idx_sneakers_brand_key ON sneakers (brand_key(brand));
+CREATE INDEX idx_watches_brand_key ON watches (brand_key(brand));
+CREATE INDEX idx_perfumes_brand_key ON perfumes (brand_key(brand));
+CREATE INDEX idx_accessories_brand_key ON accessories (brand_key(brand));
+CREATE INDEX idx_apparel_brand_key ON apparel (brand_key(brand));
+
+-- Edits to the brand tables are announced like product changes.
+CREATE TRIGGER brands_changed AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON brands FOR EACH STATEMENT EXECUTE FUNCTION notify_catalog_changed();
+CREATE TRIGGER brand_aliases_changed AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON brand_aliases FOR EACH STATEMENT EXECUTE FUNCTION notify_catalog_changed();
@@ -0,0 +1,11 @@
+-- A rate is how many units of the currency one INR (currency.Base) buys, so
+-- INR's own rate is 1. Edits are announced on catalog_changed so every
+-- server reloads them.
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency CHAR(3) PRIMARY KEY,
+    rate DOUBLE PRECISION NOT NULL CHECK (rate > 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO exchange_rates (currency, rate) VALUES ('INR', 1) ON CONFLICT (currency) DO NOTHING;
+DROP TRIGGER IF EXISTS exchange_rates_changed ON exchange_rates;
+CREATE TRIGGER exchange_rates_changed AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON exchange_rates FOR EACH STATEMENT EXECUTE FUNCTION notify_catalog_changed();
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS alerts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('price_drop', 'back_in_stock')),
+    category TEXT NOT NULL,
+    product_link TEXT NOT NULL,
+    size TEXT NOT NULL DEFAULT '',
+    target_price DOUBLE PRECISION CHECK (target_price > 0),
+    currency CHAR(3) NOT NULL,
+    reference_price DOUBLE PRECISION,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    triggered_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_alerts_user ON alerts (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_alerts_pending ON alerts (category, product_link) WHERE triggered_at IS NULL;
+-- A user has at most one pending alert of each kind per product and size;
+-- creating another updates it.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_unique_pending ON alerts (user_id, kind, category, product_link, size) WHERE triggered_at IS NULL;
@@ -0,0 +1,6 @@
+-- Existing users are customers; plutusctl users set-role promotes them.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'customer';
+DO $$ BEGIN
+    ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('customer', 'staff', 'admin'));
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
@@ -0,0 +1,10 @@
+-- Failed sign-ins, counted per account and per address (scope).
+CREATE TABLE IF NOT EXISTS login_failures (
+    scope TEXT NOT NULL,
+    key TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    last_failed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (scope, key)
+);
+CREATE INDEX IF NOT EXISTS idx_login_failures_last_failed ON login_failures (last_failed_at);
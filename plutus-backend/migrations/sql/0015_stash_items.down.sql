@@ -0,0 +1 @@
+DROP TABLE stash_items;
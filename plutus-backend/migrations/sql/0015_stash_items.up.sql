@@ -0,0 +1,9 @@
+-- Products are stored by link, so a re-seed renumbering them doesn't empty
+-- anyone's stash.
+CREATE TABLE IF NOT EXISTS stash_items (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category TEXT NOT NULL,
+    product_link TEXT NOT NULL,
+    added_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, category, product_link)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE apparel DROP COLUMN archived_at, DROP COLUMN archived_by;
+ALTER TABLE accessories DROP COLUMN archived_at, DROP COLUMN archived_by;
+ALTER TABLE perfumes DROP COLUMN archived_at, DROP COLUMN archived_by;
+ALTER TABLE watches DROP COLUMN archived_at, DROP COLUMN archived_by;
+ALTER TABLE sneakers DROP COLUMN archived_at, DROP COLUMN archived_by;
@@ -0,0 +1,8 @@
+-- Archiving marks a product's own row rather than deleting it, so the row
+-- keeps its id and every column, and restoring only clears the mark.
+-- Storefront queries leave out rows with archived_at set (catalog.Listed).
+ALTER TABLE sneakers ADD COLUMN archived_at TIMESTAMPTZ, ADD COLUMN archived_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE watches ADD COLUMN archived_at TIMESTAMPTZ, ADD COLUMN archived_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE perfumes ADD COLUMN archived_at TIMESTAMPTZ, ADD COLUMN archived_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE accessories ADD COLUMN archived_at TIMESTAMPTZ, ADD COLUMN archived_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE apparel ADD COLUMN archived_at TIMESTAMPTZ, ADD COLUMN archived_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+DROP TABLE enquiry_notes;
+ALTER TABLE enquiries DROP COLUMN status, DROP COLUMN assignee_id, DROP COLUMN follow_up_on, DROP COLUMN updated_at;
@@ -0,0 +1,19 @@
+-- Staff work enquiries through statuses, assign them to one another, set
+-- follow-up dates and keep notes.
+ALTER TABLE enquiries
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'new' CHECK (status IN ('new', 'contacted', 'quoted', 'won', 'lost')),
+    ADD COLUMN assignee_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN follow_up_on DATE,
+    ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+CREATE INDEX idx_enquiries_status ON enquiries (status, created_at DESC);
+CREATE INDEX idx_enquiries_assignee ON enquiries (assignee_id) WHERE assignee_id IS NOT NULL;
+CREATE INDEX idx_enquiries_follow_up ON enquiries (follow_up_on) WHERE follow_up_on IS NOT NULL;
+
+CREATE TABLE enquiry_notes (
+    id SERIAL PRIMARY KEY,
+    enquiry_id INTEGER NOT NULL REFERENCES enquiries(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_enquiry_notes_enquiry ON enquiry_notes (enquiry_id, created_at);
@@ -0,0 +1 @@
+DROP TABLE enquiry_outbox;
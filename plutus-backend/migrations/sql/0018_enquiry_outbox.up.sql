@@ -0,0 +1,18 @@
+-- Notifications about new enquiries, queued in the transaction that stores
+-- the enquiry and delivered with retries by enquiries.Outbox.
+CREATE TABLE IF NOT EXISTS enquiry_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    enquiry_id INTEGER NOT NULL REFERENCES enquiries(id) ON DELETE CASCADE,
+    channel TEXT NOT NULL CHECK (channel IN ('email', 'webhook')),
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT,
+    sent_at TIMESTAMPTZ,
+    failed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_enquiry_outbox_pending ON enquiry_outbox (next_attempt_at) WHERE sent_at IS NULL AND failed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_enquiry_outbox_enquiry ON enquiry_outbox (enquiry_id);
@@ -0,0 +1,2 @@
+DROP INDEX idx_enquiries_email_created;
+ALTER TABLE enquiries DROP COLUMN spam_score, DROP COLUMN ip, DROP COLUMN idempotency_key;
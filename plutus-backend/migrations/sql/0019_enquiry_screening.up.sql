@@ -0,0 +1,12 @@
+-- What enquiries.Service records to screen submissions: the spam score, the
+-- sender's address for per-IP throttling and the client's idempotency key.
+ALTER TABLE enquiries
+    ADD COLUMN spam_score INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN ip TEXT,
+    ADD COLUMN idempotency_key TEXT;
+-- Idempotency keys are chosen by clients, so two senders can pick the same
+-- one. Each key is scoped to the sender's email, so a retry only ever
+-- matches that sender's own earlier submission.
+CREATE UNIQUE INDEX idx_enquiries_email_idempotency_key ON enquiries (lower(email), idempotency_key) WHERE idempotency_key IS NOT NULL;
+CREATE INDEX idx_enquiries_email_created ON enquiries (lower(email), created_at);
+CREATE INDEX idx_enquiries_ip_created ON enquiries (ip, created_at) WHERE ip IS NOT NULL;
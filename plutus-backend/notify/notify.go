@@ -0,0 +1,243 @@
+// Package notify delivers plain-text messages to users. SMTP sends real
+// email; Maildir and File keep messages on disk, or in the log, so
+// development setups and tests can see what would have been sent without a
+// mail server. SMTPSink goes the other way: it is a mail server that
+// accepts everything, for exercising the SMTP path locally.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Message is one notification to one recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// FromEnv returns the notifier selected by NOTIFIER: "smtp", "maildir",
+// "file" or "log". Without NOTIFIER, SMTP is used when SMTP_HOST is set and
+// the log otherwise.
+//
+//	SMTP_HOST, SMTP_PORT (default 587), SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM
+//	NOTIFY_MAILDIR (default maildir)
+//	NOTIFY_FILE (default notifications.log)
+func FromEnv() (Notifier, error) {
+	kind := strings.ToLower(strings.TrimSpace(os.Getenv("NOTIFIER")))
+	if kind == "" {
+		kind = "log"
+		if os.Getenv("SMTP_HOST") != "" {
+			kind = "smtp"
+		}
+	}
+	switch kind {
+	case "smtp":
+		s := &SMTP{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}
+		if s.Host == "" || s.From == "" {
+			return nil, fmt.Errorf("NOTIFIER=smtp needs SMTP_HOST and SMTP_FROM")
+		}
+		return s, nil
+	case "maildir":
+		dir := os.Getenv("NOTIFY_MAILDIR")
+		if dir == "" {
+			dir = "maildir"
+		}
+		return &Maildir{Dir: dir, From: os.Getenv("SMTP_FROM")}, nil
+	case "file":
+		path := os.Getenv("NOTIFY_FILE")
+		if path == "" {
+			path = "notifications.log"
+		}
+		return &File{Path: path}, nil
+	case "log":
+		return &File{}, nil
+	}
+	return nil, fmt.Errorf("unknown NOTIFIER %q", kind)
+}
+
+// SMTP sends messages as email. Port defaults to 587; STARTTLS is used when
+// the server offers it, and authentication when Username is set.
+type SMTP struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Notify sends m, giving up when ctx is done or after a minute.
+func (s *SMTP) Notify(ctx context.Context, m Message) error {
+	msg, err := format(s.From, m)
+	if err != nil {
+		return err
+	}
+	port := s.Port
+	if port == "" {
+		port = "587"
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(s.Host, port))
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return err
+		}
+	}
+	from, _ := mail.ParseAddress(s.From)
+	to, _ := mail.ParseAddress(m.To)
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// format renders m from sender as an RFC 5322 message. Addresses are parsed
+// so a value can't smuggle in extra headers.
+func format(sender string, m Message) ([]byte, error) {
+	from, err := mail.ParseAddress(sender)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", sender, err)
+	}
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", m.To, err)
+	}
+	subject := strings.Join(strings.Fields(m.Subject), " ")
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes(), nil
+}
+
+// DefaultFrom is the sender of messages written by Maildir when From is
+// empty.
+const DefaultFrom = "House of Plutus <noreply@localhost>"
+
+// Maildir writes each message as a file to the new/ subdirectory of Dir, in
+// the maildir layout mail clients and tests can read. It is meant for
+// development and tests.
+type Maildir struct {
+	Dir  string
+	From string
+}
+
+var maildirSeq atomic.Int64
+
+// Notify writes m to Dir/new, going through Dir/tmp so readers never see a
+// partly written message.
+func (d *Maildir) Notify(ctx context.Context, m Message) error {
+	from := d.From
+	if from == "" {
+		from = DefaultFrom
+	}
+	msg, err := format(from, m)
+	if err != nil {
+		return err
+	}
+	return d.Write(msg)
+}
+
+// Write stores msg, a complete RFC 5322 message, as it is.
+func (d *Maildir) Write(msg []byte) error {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(d.Dir, sub), 0o700); err != nil {
+			return err
+		}
+	}
+	host, _ := os.Hostname()
+	name := fmt.Sprintf("%d.%d_%d.%s", time.Now().Unix(), os.Getpid(), maildirSeq.Add(1), strings.ReplaceAll(host, "/", "_"))
+	tmp := filepath.Join(d.Dir, "tmp", name)
+	if err := os.WriteFile(tmp, msg, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(d.Dir, "new", name))
+}
+
+// File appends each message to the file at Path, or writes it to the log
+// when Path is empty. It is meant for development.
+type File struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Notify records m.
+func (f *File) Notify(ctx context.Context, m Message) error {
+	if f.Path == "" {
+		log.Printf("📧 To: %s | %s\n%s", m.To, m.Subject, m.Body)
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC1123Z), m.To, m.Subject, m.Body)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/mail"
+	"slices"
+	"testing"
+)
+
+type delivery struct {
+	from string
+	to   []string
+	msg  []byte
+}
+
+// startSink runs an SMTPSink on a free local port and returns its port and
+// the messages it receives.
+func startSink(t *testing.T) (string, <-chan delivery) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan delivery, 1)
+	sink := &SMTPSink{Deliver: func(from string, to []string, msg []byte) error {
+		got <- delivery{from, to, msg}
+		return nil
+	}}
+	go sink.Serve(l)
+	t.Cleanup(func() { l.Close() })
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port, got
+}
+
+func TestSMTPToSink(t *testing.T) {
+	port, got := startSink(t)
+	s := &SMTP{Host: "127.0.0.1", Port: port, From: "House of Plutus <noreply@example.com>"}
+	err := s.Notify(context.Background(), Message{
+		To:      "Ada Lovelace <ada@example.com>",
+		Subject: "Your enquiry\r\nBcc: everyone@example.com",
+		Body:    "Hello Ada,\n\nThanks for writing.\n.\nHouse of Plutus",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := <-got
+	if d.from != "noreply@example.com" {
+		t.Errorf("envelope sender = %q, want noreply@example.com", d.from)
+	}
+	if !slices.Equal(d.to, []string{"ada@example.com"}) {
+		t.Errorf("envelope recipients = %q, want [ada@example.com]", d.to)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(d.msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to, err := msg.Header.AddressList("To"); err != nil || len(to) != 1 || to[0].Address != "ada@example.com" {
+		t.Errorf("To = %v (%v), want ada@example.com", to, err)
+	}
+	// The line break in the subject mustn't start a header of its own.
+	if subject := msg.Header.Get("Subject"); subject != "Your enquiry Bcc: everyone@example.com" {
+		t.Errorf("Subject = %q, want the subject on one line", subject)
+	}
+	if bcc := msg.Header.Get("Bcc"); bcc != "" {
+		t.Errorf("Bcc = %q, want none", bcc)
+	}
+	body, err := io.ReadAll(msg.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The sink reads the data as text, so lines end in \n again; the lone
+	// dot survives the dot-stuffing.
+	if want := "Hello Ada,\n\nThanks for writing.\n.\nHouse of Plutus\n"; string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestSMTPRejectsBadRecipient(t *testing.T) {
+	port, got := startSink(t)
+	s := &SMTP{Host: "127.0.0.1", Port: port, From: "noreply@example.com"}
+	if err := s.Notify(context.Background(), Message{To: "ada@example.com\r\nBcc: x@example.com", Subject: "Hi", Body: "Hi"}); err == nil {
+		t.Fatal("err = nil, want the recipient rejected")
+	}
+	select {
+	case d := <-got:
+		t.Errorf("sink received a message to %q", d.to)
+	default:
+	}
+}
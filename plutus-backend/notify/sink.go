@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// SMTPSink is an SMTP server that accepts every message and hands it to
+// Deliver instead of relaying it. It is a local stand-in for a mail server:
+// point SMTP_HOST and SMTP_PORT at it, without SMTP_USERNAME, and the real
+// SMTP notifier can be tried out without sending anything. It speaks just
+// enough SMTP for Go's net/smtp client and command-line tools such as swaks.
+type SMTPSink struct {
+	// Deliver receives each message: the envelope sender, the recipients
+	// and the message as sent, headers included.
+	Deliver func(from string, to []string, msg []byte) error
+	// Hostname is announced in the greeting. It defaults to "localhost".
+	Hostname string
+}
+
+// MaxSinkMessage bounds a message SMTPSink accepts.
+const MaxSinkMessage = 10 << 20
+
+// ListenAndServe accepts connections on addr until listening fails.
+func (s *SMTPSink) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until l is closed.
+func (s *SMTPSink) Serve(l net.Listener) error {
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *SMTPSink) serve(conn net.Conn) {
+	defer conn.Close()
+	host := s.Hostname
+	if host == "" {
+		host = "localhost"
+	}
+	tp := textproto.NewConn(conn)
+	reply := func(code int, text string) error {
+		conn.SetDeadline(time.Now().Add(5 * time.Minute))
+		return tp.PrintfLine("%d %s", code, text)
+	}
+	if reply(220, host+" ESMTP plutus sink") != nil {
+		return
+	}
+	var from string
+	var to []string
+	for {
+		conn.SetDeadline(time.Now().Add(5 * time.Minute))
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			err = reply(250, host)
+		case "EHLO":
+			if err = tp.PrintfLine("250-%s", host); err == nil {
+				err = tp.PrintfLine("250 SIZE %d", MaxSinkMessage)
+			}
+		case "MAIL":
+			from, to = address(arg), nil
+			err = reply(250, "OK")
+		case "RCPT":
+			to = append(to, address(arg))
+			err = reply(250, "OK")
+		case "DATA":
+			if len(to) == 0 {
+				err = reply(503, "RCPT first")
+				break
+			}
+			if err = reply(354, "End data with <CR><LF>.<CR><LF>"); err != nil {
+				return
+			}
+			dot := tp.DotReader()
+			msg, rerr := io.ReadAll(io.LimitReader(dot, MaxSinkMessage+1))
+			switch {
+			case rerr != nil:
+				return
+			case len(msg) > MaxSinkMessage:
+				if _, err := io.Copy(io.Discard, dot); err != nil {
+					return
+				}
+				err = reply(552, "message too big")
+			case s.Deliver(from, to, msg) != nil:
+				err = reply(451, "could not store the message")
+			default:
+				err = reply(250, "OK")
+			}
+			from, to = "", nil
+		case "RSET":
+			from, to = "", nil
+			err = reply(250, "OK")
+		case "NOOP":
+			err = reply(250, "OK")
+		case "QUIT":
+			reply(221, "Bye")
+			return
+		default:
+			err = reply(502, "command not implemented")
+		}
+		if err != nil {
+			log.Printf("⚠️ SMTP sink: %v", err)
+			return
+		}
+	}
+}
+
+// address reads the address out of a MAIL FROM:<...> or RCPT TO:<...>
+// argument.
+func address(arg string) string {
+	if _, rest, ok := strings.Cut(arg, ":"); ok {
+		arg = rest
+	}
+	arg = strings.TrimSpace(arg)
+	if i := strings.Index(arg, ">"); strings.HasPrefix(arg, "<") && i > 0 {
+		return arg[1:i]
+	}
+	return arg
+}
@@ -0,0 +1,218 @@
+// Package prices records how product prices change over time. A trigger on
+// every product table (see migration 0004_price_history) appends to
+// price_history whenever a write changes the price of a product, size or
+// variant, so any ingestion path is tracked, including a re-seed. Products are identified by
+// their link rather than their id, which a re-seed renumbers.
+package prices
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"plutus-backend/catalog"
+	"plutus-backend/graph/model"
+	"plutus-backend/querybuilder"
+)
+
+const (
+	// DefaultDropWindow is how far back Drops looks when no Since is
+	// given.
+	DefaultDropWindow = 30 * 24 * time.Hour
+	// DefaultDrops and MaxDrops bound the number of drops returned.
+	DefaultDrops = 50
+	MaxDrops     = 200
+)
+
+// Point is one recorded price. Size is empty for products without sizes.
+type Point struct {
+	Size       string
+	Price      float64
+	Currency   string
+	RecordedAt time.Time
+}
+
+// source is the column a category's prices are recorded from: its JSONB
+// prices column, or for categories priced by a plain column, PriceExpr.
+func source(c *catalog.Category) string {
+	if c.PricesColumn != "" {
+		return c.PricesColumn
+	}
+	return c.PriceExpr
+}
+
+// Snapshot records the current price of every product, size and variant
+// whose price differs from the last recorded one, such as products written
+// before the triggers existed. It returns the number of prices recorded per
+// category key.
+func Snapshot(ctx context.Context, db *sql.DB) (map[string]int64, error) {
+	recorded := map[string]int64{}
+	for _, c := range catalog.Categories() {
+		res, err := db.ExecContext(ctx, "INSERT INTO price_history (category, product_link, size, price, currency)"+
+			" SELECT DISTINCT ON (t.link, p.size) $1, t.link, p.size, p.price, $2"+
+			" FROM (SELECT NULLIF(TRIM("+c.LinkColumn+"), '') AS link, to_jsonb("+source(c)+") AS prices FROM "+c.Table+") t"+
+			" CROSS JOIN LATERAL price_points(t.prices) p"+
+			" WHERE t.link IS NOT NULL AND p.price IS DISTINCT FROM ("+
+			"SELECT h.price FROM price_history h WHERE h.category = $1 AND h.product_link = t.link AND h.size = p.size"+
+			" ORDER BY h.recorded_at DESC, h.id DESC LIMIT 1)"+
+			" ORDER BY t.link, p.size, p.price",
+			c.Key, c.PriceCurrency())
+		if err != nil {
+			return recorded, fmt.Errorf("snapshot %s: %w", c.Key, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return recorded, err
+		}
+		recorded[c.Key] = n
+	}
+	return recorded, nil
+}
+
+// History returns the recorded prices of one product, oldest first within
+// each size. A non-empty size restricts it to that size.
+func History(ctx context.Context, db *sql.DB, c *catalog.Category, id, size string) ([]Point, error) {
+	qb := querybuilder.New("SELECT h.size, h.price, h.currency, h.recorded_at FROM price_history h" +
+		" JOIN " + c.Table + " p ON NULLIF(TRIM(p." + c.LinkColumn + "), '') = h.product_link")
+	qb.Where("h.category = ?", c.Key)
+	qb.Where("p.id = ?", id)
+	if size != "" {
+		qb.Where("h.size = ?", strings.TrimSpace(size))
+	}
+	qb.OrderBy("h.size").OrderBy("h.recorded_at").OrderBy("h.id")
+	query, args := qb.Build()
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	points := []Point{}
+	for rows.Next() {
+		var p Point
+		if err := rows.Scan(&p.Size, &p.Price, &p.Currency, &p.RecordedAt); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// DropParams selects price drops.
+type DropParams struct {
+	// Category is a category key. Empty means every category.
+	Category string
+	// Since is the start of the window; the price in effect then is
+	// compared with the current one. Zero means DefaultDropWindow ago.
+	Since time.Time
+	// MinPercent is the smallest drop returned, in percent.
+	MinPercent float64
+	First      int
+}
+
+// Drop is a product, size or variant whose price fell during the window.
+type Drop struct {
+	Category      string
+	ID            string
+	Product       model.Product
+	Size          string
+	PreviousPrice float64
+	Price         float64
+	Currency      string
+	Percent       float64
+	DroppedAt     time.Time
+}
+
+// ErrUnknownCategory is returned when DropParams names a category that
+// isn't registered.
+var ErrUnknownCategory = errors.New("unknown category")
+
+// Drops returns the biggest price drops since p.Since, largest first. A
+// drop compares the price in effect at Since with the current price, so a
+// price that fell and recovered within the window isn't reported.
+func Drops(ctx context.Context, cat *catalog.Catalog, p DropParams) ([]Drop, error) {
+	cats := catalog.Categories()
+	if p.Category != "" {
+		c, ok := catalog.Lookup(strings.ToLower(strings.TrimSpace(p.Category)))
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownCategory, p.Category)
+		}
+		cats = []*catalog.Category{c}
+	}
+	since := p.Since
+	if since.IsZero() {
+		since = time.Now().Add(-DefaultDropWindow)
+	}
+	first := p.First
+	if first <= 0 {
+		first = DefaultDrops
+	}
+	if first > MaxDrops {
+		first = MaxDrops
+	}
+
+	keys := make([]string, len(cats))
+	branches := make([]string, len(cats))
+	for i, c := range cats {
+		keys[i] = "'" + c.Key + "'"
+		branches[i] = "SELECT '" + c.Key + "' AS category, id::text AS id, NULLIF(TRIM(" + c.LinkColumn + "), '') AS link FROM " + c.Table + " WHERE " + catalog.Listed
+	}
+	scope := "category IN (" + strings.Join(keys, ", ") + ")"
+	// l is the current price of each product and size, b the price that was
+	// in effect at $1.
+	qb := querybuilder.New("SELECT l.category, p.id, l.size, b.price, l.price, l.currency, l.recorded_at," +
+		" 100 * (b.price - l.price) / b.price AS percent" +
+		" FROM (SELECT DISTINCT ON (category, product_link, size) category, product_link, size, price, currency, recorded_at" +
+		" FROM price_history WHERE " + scope + " ORDER BY category, product_link, size, recorded_at DESC, id DESC) l" +
+		" JOIN (SELECT DISTINCT ON (category, product_link, size) category, product_link, size, price, currency" +
+		" FROM price_history WHERE " + scope + " AND recorded_at <= $1 ORDER BY category, product_link, size, recorded_at DESC, id DESC) b" +
+		" ON b.category = l.category AND b.product_link = l.product_link AND b.size = l.size AND b.currency = l.currency" +
+		" JOIN (" + strings.Join(branches, " UNION ALL ") + ") p ON p.category = l.category AND p.link = l.product_link")
+	qb.Arg(since)
+	qb.Where("l.recorded_at > $1")
+	qb.Where("b.price > 0 AND l.price < b.price")
+	qb.Where("100 * (b.price - l.price) / b.price >= ?", p.MinPercent)
+	qb.OrderBy("percent DESC").OrderBy("l.recorded_at DESC").OrderBy("l.category").OrderBy("p.id")
+	qb.Limit(first)
+
+	query, args := qb.Build()
+	rows, err := cat.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var drops []Drop
+	for rows.Next() {
+		var d Drop
+		if err := rows.Scan(&d.Category, &d.ID, &d.Size, &d.PreviousPrice, &d.Price, &d.Currency, &d.DroppedAt, &d.Percent); err != nil {
+			return nil, err
+		}
+		drops = append(drops, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	ids := map[string][]string{}
+	for _, d := range drops {
+		ids[d.Category] = append(ids[d.Category], d.ID)
+	}
+	products := map[string]map[string]model.Product{}
+	for category, categoryIDs := range ids {
+		found, err := cat.Products(ctx, category, categoryIDs)
+		if err != nil {
+			return nil, err
+		}
+		products[category] = found
+	}
+	out := []Drop{}
+	for _, d := range drops {
+		if d.Product = products[d.Category][d.ID]; d.Product != nil {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,146 @@
+// Package querybuilder assembles catalog SELECT statements without ever
+// interpolating user input into the SQL text. Every filter value, LIMIT and
+// OFFSET is bound as a $n placeholder.
+package querybuilder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Builder accumulates WHERE conditions, ordering and pagination for a base
+// SELECT statement.
+type Builder struct {
+	base    string
+	where   []string
+	groupBy []string
+	orderBy []string
+	limit   string
+	offset  string
+	args    []interface{}
+}
+
+// New starts a query from a base statement such as "SELECT ... FROM sneakers".
+// The base must not contain a WHERE clause; use Where instead.
+func New(base string) *Builder {
+	return &Builder{base: strings.TrimSpace(base)}
+}
+
+// Arg binds v and returns its placeholder ($1, $2, ...).
+func (b *Builder) Arg(v interface{}) string {
+	b.args = append(b.args, v)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+// Where adds a condition that is ANDed with the others. Each "?" in cond is
+// replaced, in order, by the placeholder of the matching arg. Conditions
+// without args are added verbatim, so SQL that contains its own "?" (JSONB
+// operators, regex quantifiers) must bind values with Arg instead.
+func (b *Builder) Where(cond string, args ...interface{}) *Builder {
+	if len(args) > 0 {
+		cond = b.bind(cond, args)
+	}
+	b.where = append(b.where, cond)
+	return b
+}
+
+// GroupBy appends a GROUP BY term. Like OrderBy, expr is trusted SQL.
+func (b *Builder) GroupBy(expr string) *Builder {
+	b.groupBy = append(b.groupBy, expr)
+	return b
+}
+
+// OrderBy appends an ORDER BY term. expr is trusted SQL and must never
+// contain user input; map sort options through an allow-list first.
+func (b *Builder) OrderBy(expr string) *Builder {
+	b.orderBy = append(b.orderBy, expr)
+	return b
+}
+
+// Limit binds a LIMIT value.
+func (b *Builder) Limit(n int) *Builder {
+	b.limit = b.Arg(n)
+	return b
+}
+
+// Offset binds an OFFSET value.
+func (b *Builder) Offset(n int) *Builder {
+	b.offset = b.Arg(n)
+	return b
+}
+
+// Build returns the SQL text and its arguments.
+func (b *Builder) Build() (string, []interface{}) {
+	var sb strings.Builder
+	sb.WriteString(b.base)
+	if len(b.where) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(b.where, " AND "))
+	}
+	if len(b.groupBy) > 0 {
+		sb.WriteString(" GROUP BY ")
+		sb.WriteString(strings.Join(b.groupBy, ", "))
+	}
+	if len(b.orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(b.orderBy, ", "))
+	}
+	if b.limit != "" {
+		sb.WriteString(" LIMIT " + b.limit)
+	}
+	if b.offset != "" {
+		sb.WriteString(" OFFSET " + b.offset)
+	}
+	return sb.String(), b.args
+}
+
+func (b *Builder) bind(cond string, args []interface{}) string {
+	var sb strings.Builder
+	i := 0
+	for _, r := range cond {
+		if r == '?' && i < len(args) {
+			sb.WriteString(b.Arg(args[i]))
+			i++
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	if i != len(args) {
+		panic(fmt.Sprintf("querybuilder: %d args for %d placeholders in %q", len(args), i, cond))
+	}
+	return sb.String()
+}
+
+// Contains returns an ILIKE pattern matching s anywhere in a value. LIKE
+// metacharacters in s are escaped so they match literally.
+func Contains(s string) string {
+	return "%" + EscapeLike(s) + "%"
+}
+
+// EscapeLike escapes the LIKE metacharacters %, _ and the default escape
+// character backslash.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Words splits free text into lower-cased words. Anything other than letters
+// and digits separates words.
+func Words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// PrefixTSQuery turns free text into to_tsquery input matching every word as
+// a prefix, so "air jor" becomes "air:* & jor:*". Words are split as by
+// Words, so the result never carries tsquery operators from the input. It
+// returns "" when s has no words.
+func PrefixTSQuery(s string) string {
+	words := Words(s)
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " & ")
+}
@@ -0,0 +1,157 @@
+package querybuilder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func() *Builder
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:    "base only",
+			build:   func() *Builder { return New("  SELECT id FROM sneakers  ") },
+			wantSQL: "SELECT id FROM sneakers",
+		},
+		{
+			name: "conditions are ANDed and numbered in order",
+			build: func() *Builder {
+				return New("SELECT id FROM sneakers").
+					Where("brand ILIKE ?", "%nike%").
+					Where("sold_out = false").
+					Where("min_price BETWEEN ? AND ?", 100.0, 500.0)
+			},
+			wantSQL:  "SELECT id FROM sneakers WHERE brand ILIKE $1 AND sold_out = false AND min_price BETWEEN $2 AND $3",
+			wantArgs: []interface{}{"%nike%", 100.0, 500.0},
+		},
+		{
+			name: "IN list binds one placeholder per value",
+			build: func() *Builder {
+				return New("SELECT id FROM enquiries").
+					Where("status IN (?, ?, ?)", "new", "contacted", "quoted").
+					Where("email = ?", "a@example.com")
+			},
+			wantSQL:  "SELECT id FROM enquiries WHERE status IN ($1, $2, $3) AND email = $4",
+			wantArgs: []interface{}{"new", "contacted", "quoted", "a@example.com"},
+		},
+		{
+			name: "Arg and Where share one numbering",
+			build: func() *Builder {
+				b := New("SELECT id FROM watches")
+				b.Where(b.Arg("rolex") + " = brand_key(brand)")
+				return b.Where("sale_price < ?", 1000)
+			},
+			wantSQL:  "SELECT id FROM watches WHERE $1 = brand_key(brand) AND sale_price < $2",
+			wantArgs: []interface{}{"rolex", 1000},
+		},
+		{
+			name: "conditions without args keep their question marks",
+			build: func() *Builder {
+				return New("SELECT id FROM perfumes").Where("variants ? 'size'")
+			},
+			wantSQL: "SELECT id FROM perfumes WHERE variants ? 'size'",
+		},
+		{
+			name: "clauses come out in SQL order and LIMIT and OFFSET are bound last",
+			build: func() *Builder {
+				return New("SELECT brand, COUNT(*) FROM apparel").
+					Offset(40).
+					OrderBy("COUNT(*) DESC").
+					GroupBy("brand").
+					Where("gender = ?", "women").
+					OrderBy("brand").
+					Limit(20)
+			},
+			wantSQL:  "SELECT brand, COUNT(*) FROM apparel WHERE gender = $2 GROUP BY brand ORDER BY COUNT(*) DESC, brand LIMIT $3 OFFSET $1",
+			wantArgs: []interface{}{40, "women", 20},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := tt.build().Build()
+			if sql != tt.wantSQL {
+				t.Errorf("sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if len(args) != 0 || len(tt.wantArgs) != 0 {
+				if !reflect.DeepEqual(args, tt.wantArgs) {
+					t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+				}
+			}
+		})
+	}
+}
+
+func TestWhereArgCountMismatchPanics(t *testing.T) {
+	for _, tt := range []struct {
+		cond string
+		args []interface{}
+	}{
+		{"a = ? AND b = ?", []interface{}{1, 2, 3}},
+		{"a = 1", []interface{}{1}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Where(%q, %v) didn't panic", tt.cond, tt.args)
+				}
+			}()
+			New("SELECT 1").Where(tt.cond, tt.args...)
+		}()
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"Air Jordan 1", []string{"air", "jordan", "1"}},
+		{"Yves-Saint_Laurent", []string{"yves", "saint", "laurent"}},
+		{"  Hermès  TERRE d'Hermès ", []string{"hermès", "terre", "d", "hermès"}},
+		{"nike & (adidas | !puma):*", []string{"nike", "adidas", "puma"}},
+		{"100ml,EDP", []string{"100ml", "edp"}},
+	}
+	for _, tt := range tests {
+		got := Words(tt.in)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Words(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPrefixTSQuery(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"!&|", ""},
+		{"air jor", "air:* & jor:*"},
+		{"Dior:* | sauvage", "dior:* & sauvage:*"},
+	}
+	for _, tt := range tests {
+		if got := PrefixTSQuery(tt.in); got != tt.want {
+			t.Errorf("PrefixTSQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"nike", "%nike%"},
+		{"100%", `%100\%%`},
+		{"a_b", `%a\_b%`},
+		{`c:\d`, `%c:\\d%`},
+	}
+	for _, tt := range tests {
+		if got := Contains(tt.in); got != tt.want {
+			t.Errorf("Contains(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"plutus-backend/brands"
+	"plutus-backend/catalog"
+	"plutus-backend/querybuilder"
+)
+
+const (
+	// fewHits is the number of exact matches below which a query is checked
+	// for typos and widened with its correction.
+	fewHits = 3
+	// brandSimilarity is how close a whole query must be to a brand name
+	// for the brand to be suggested, e.g. "yves saint laurant".
+	brandSimilarity = 0.5
+)
+
+// RefreshTerms rebuilds the search_terms vocabulary from the catalog without
+// blocking searches that read it.
+func RefreshTerms(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, "REFRESH MATERIALIZED VIEW CONCURRENTLY search_terms"); err != nil {
+		return fmt.Errorf("refresh search terms: %w", err)
+	}
+	return nil
+}
+
+// fewMatches reports whether tsquery matches fewer than fewHits products.
+func (e *Engine) fewMatches(ctx context.Context, cats []*catalog.Category, tsquery string, brand *brands.Brand) (bool, error) {
+	query, args := matches(cats, tsquery, brand, "1").Limit(fewHits).Build()
+	var n int
+	if err := e.Catalog.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+query+") m", args...).Scan(&n); err != nil {
+		return false, err
+	}
+	return n < fewHits, nil
+}
+
+// correct returns the query with misspellings fixed, or "" when there is
+// nothing to suggest. A query that is close to a whole brand name becomes
+// that brand. Otherwise each word is kept if some indexed word starts with
+// it, replaced by the most similar indexed word, or dropped when nothing is
+// similar enough.
+func (e *Engine) correct(ctx context.Context, query string) (string, error) {
+	words := querybuilder.Words(query)
+	if len(words) == 0 {
+		return "", nil
+	}
+	normalized := strings.Join(words, " ")
+
+	var brand string
+	err := e.Catalog.DB.QueryRowContext(ctx,
+		"SELECT display FROM search_terms WHERE kind = 'brand' AND term % $1 AND similarity(term, $1) >= $2"+
+			" ORDER BY similarity(term, $1) DESC, ndoc DESC LIMIT 1",
+		normalized, brandSimilarity).Scan(&brand)
+	switch {
+	case err == nil:
+		if strings.ToLower(brand) == normalized {
+			return "", nil
+		}
+		return brand, nil
+	case err != sql.ErrNoRows:
+		return "", err
+	}
+
+	rows, err := e.Catalog.DB.QueryContext(ctx,
+		"SELECT u.w, (SELECT t.term FROM search_terms t WHERE t.kind = 'word' AND (t.term LIKE u.w || '%' OR t.term % u.w)"+
+			" ORDER BY t.term LIKE u.w || '%' DESC, similarity(t.term, u.w) DESC, t.ndoc DESC LIMIT 1)"+
+			" FROM unnest($1::text[]) WITH ORDINALITY AS u(w, i) ORDER BY u.i",
+		pq.Array(words))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	var corrected []string
+	changed := false
+	for rows.Next() {
+		var word string
+		var term sql.NullString
+		if err := rows.Scan(&word, &term); err != nil {
+			return "", err
+		}
+		switch {
+		case !term.Valid:
+			changed = true
+		case strings.HasPrefix(term.String, word):
+			corrected = append(corrected, word)
+		default:
+			corrected = append(corrected, term.String)
+			changed = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if !changed || len(corrected) == 0 {
+		return "", nil
+	}
+	return strings.Join(corrected, " "), nil
+}
@@ -0,0 +1,310 @@
+// Package search runs ranked full-text search across every catalog
+// category. Each category table carries a generated tsvector column (see
+// migration 0006_search); matches from all requested tables are ranked
+// together with ts_rank and loaded back as their typed product models.
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"plutus-backend/brands"
+	"plutus-backend/catalog"
+	"plutus-backend/graph/model"
+	"plutus-backend/querybuilder"
+)
+
+// ErrUnknownCategory is returned when Params names a category that isn't
+// registered.
+var ErrUnknownCategory = errors.New("unknown category")
+
+// Params selects one page of search results.
+type Params struct {
+	// Query is free text; each word matches as a prefix. With no words every
+	// product in the requested categories matches with rank 0.
+	Query string
+	// Categories are category keys to search. Empty means all of them.
+	Categories []string
+	First      *int
+	After      *string
+	// Offset skips results when After is empty, for clients that page by
+	// number rather than by cursor.
+	Offset int
+	// WithCounts also counts the matches in each category.
+	WithCounts bool
+	// Brand restricts results to one brand's products. Nil means any brand.
+	Brand *brands.Brand
+}
+
+// Hit is one ranked result.
+type Hit struct {
+	Category string        `json:"category"`
+	ID       string        `json:"id"`
+	Rank     float64       `json:"rank"`
+	Product  model.Product `json:"product"`
+	Cursor   string        `json:"cursor"`
+}
+
+// Page is a window of ranked results.
+type Page struct {
+	Hits            []Hit
+	HasNextPage     bool
+	HasPreviousPage bool
+	// TotalCount and CategoryCounts are only filled when Params.WithCounts
+	// is set. CategoryCounts follows registry order.
+	TotalCount     int
+	CategoryCounts []*model.CategoryCount
+	// DidYouMean is a corrected query when the original matched only a
+	// few products. The results then include the correction's matches.
+	DidYouMean string
+}
+
+// Engine searches the catalog's tables.
+type Engine struct {
+	Catalog *catalog.Catalog
+}
+
+// New returns an Engine over c.
+func New(c *catalog.Catalog) *Engine {
+	return &Engine{Catalog: c}
+}
+
+// cursor pins the rank, category and id of the last result on a page.
+type cursor struct {
+	Rank     float64 `json:"r"`
+	Category string  `json:"c"`
+	ID       string  `json:"id"`
+}
+
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, catalog.ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, catalog.ErrInvalidCursor
+	}
+	// The id is compared as an integer; anything else would only fail in
+	// the query.
+	if _, err := strconv.Atoi(c.ID); err != nil {
+		return c, catalog.ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Search returns the page of results after p.After, best match first. Ties
+// are broken by category key and then id, so paging is stable. A query with
+// only a few exact matches is also run with its typos corrected.
+func (e *Engine) Search(ctx context.Context, p Params) (*Page, error) {
+	cats, err := categories(p.Categories)
+	if err != nil {
+		return nil, err
+	}
+	first := catalog.DefaultPageSize
+	if p.First != nil {
+		if *p.First < 0 {
+			return nil, fmt.Errorf("first must not be negative")
+		}
+		first = *p.First
+	}
+	if first > catalog.MaxPageSize {
+		first = catalog.MaxPageSize
+	}
+	tsquery := querybuilder.PrefixTSQuery(p.Query)
+	didYouMean := ""
+	if tsquery != "" {
+		few, err := e.fewMatches(ctx, cats, tsquery, p.Brand)
+		if err != nil {
+			return nil, err
+		}
+		if few {
+			if didYouMean, err = e.correct(ctx, p.Query); err != nil {
+				return nil, err
+			}
+		}
+		if didYouMean != "" {
+			tsquery = "(" + tsquery + ") | (" + querybuilder.PrefixTSQuery(didYouMean) + ")"
+		}
+	}
+
+	qb := matches(cats, tsquery, p.Brand, "category, id, rank")
+	if p.After != nil && *p.After != "" {
+		after, err := decodeCursor(*p.After)
+		if err != nil {
+			return nil, err
+		}
+		rank := qb.Arg(after.Rank)
+		qb.Where("(rank < " + rank + " OR (rank = " + rank + " AND (category, id) > (" + qb.Arg(after.Category) + ", " + qb.Arg(after.ID) + "::integer)))")
+	}
+	qb.OrderBy("rank DESC").OrderBy("category").OrderBy("id")
+	// Fetch one extra row to learn whether another page follows.
+	qb.Limit(first + 1)
+	if (p.After == nil || *p.After == "") && p.Offset > 0 {
+		qb.Offset(p.Offset)
+	}
+
+	query, args := qb.Build()
+	rows, err := e.Catalog.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	page := &Page{HasPreviousPage: (p.After != nil && *p.After != "") || p.Offset > 0, DidYouMean: didYouMean}
+	for rows.Next() {
+		if len(page.Hits) == first {
+			page.HasNextPage = true
+			break
+		}
+		var h Hit
+		if err := rows.Scan(&h.Category, &h.ID, &h.Rank); err != nil {
+			return nil, err
+		}
+		h.Cursor = cursor{Rank: h.Rank, Category: h.Category, ID: h.ID}.encode()
+		page.Hits = append(page.Hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Release the connection before loading the products.
+	rows.Close()
+
+	if err := e.load(ctx, page); err != nil {
+		return nil, err
+	}
+	if p.WithCounts {
+		if err := e.count(ctx, page, cats, tsquery, p.Brand); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// matches selects columns from the union of every category's matches. The
+// inner query exposes category, id and a float8 rank. A non-nil brand keeps
+// only products whose brand key is one of the brand's aliases.
+func matches(cats []*catalog.Category, tsquery string, brand *brands.Brand, columns string) *querybuilder.Builder {
+	branches := make([]string, len(cats))
+	for i, c := range cats {
+		branches[i] = "SELECT '" + c.Key + "' AS category, id, brand, " + catalog.SearchVectorColumn + " AS doc FROM " + c.Table + " WHERE " + catalog.Listed
+	}
+	union := strings.Join(branches, " UNION ALL ")
+	// The inner query's placeholders are numbered by hand, so they must be
+	// the first values bound and in this order.
+	var conds []string
+	var args []interface{}
+	rank := "0::float8"
+	if tsquery != "" {
+		args = append(args, tsquery)
+		q := "to_tsquery('" + catalog.TextSearchConfig + "', $" + strconv.Itoa(len(args)) + ")"
+		rank = "ts_rank(doc, " + q + ")::float8"
+		conds = append(conds, "doc @@ "+q)
+	}
+	if brand != nil {
+		args = append(args, pq.Array(brand.Aliases))
+		conds = append(conds, "brand_key(brand) = ANY($"+strconv.Itoa(len(args))+"::text[])")
+	}
+	inner := "SELECT category, id, " + rank + " AS rank FROM (" + union + ") d"
+	if len(conds) > 0 {
+		inner += " WHERE " + strings.Join(conds, " AND ")
+	}
+	qb := querybuilder.New("SELECT " + columns + " FROM (" + inner + ") r")
+	for _, arg := range args {
+		qb.Arg(arg)
+	}
+	return qb
+}
+
+// load fills in each hit's product, one query per category. Hits whose
+// product was deleted since they were ranked are dropped.
+func (e *Engine) load(ctx context.Context, page *Page) error {
+	hits := page.Hits
+	ids := map[string][]string{}
+	for _, h := range hits {
+		ids[h.Category] = append(ids[h.Category], h.ID)
+	}
+	products := map[string]map[string]model.Product{}
+	for category, categoryIDs := range ids {
+		found, err := e.Catalog.Products(ctx, category, categoryIDs)
+		if err != nil {
+			return err
+		}
+		products[category] = found
+	}
+	page.Hits = hits[:0]
+	for _, h := range hits {
+		if h.Product = products[h.Category][h.ID]; h.Product != nil {
+			page.Hits = append(page.Hits, h)
+		}
+	}
+	return nil
+}
+
+func (e *Engine) count(ctx context.Context, page *Page, cats []*catalog.Category, tsquery string, brand *brands.Brand) error {
+	qb := matches(cats, tsquery, brand, "category, COUNT(*)")
+	qb.GroupBy("category")
+	query, args := qb.Build()
+	rows, err := e.Catalog.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var category string
+		var n int
+		if err := rows.Scan(&category, &n); err != nil {
+			return err
+		}
+		counts[category] = n
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	page.CategoryCounts = make([]*model.CategoryCount, len(cats))
+	for i, c := range cats {
+		page.CategoryCounts[i] = &model.CategoryCount{Category: c.Key, Count: counts[c.Key]}
+		page.TotalCount += counts[c.Key]
+	}
+	return nil
+}
+
+// categories resolves keys against the registry, keeping registry order.
+// No keys means every category.
+func categories(keys []string) ([]*catalog.Category, error) {
+	if len(keys) == 0 {
+		return catalog.Categories(), nil
+	}
+	index := map[string]int{}
+	for i, c := range catalog.Categories() {
+		index[c.Key] = i
+	}
+	seen := map[string]bool{}
+	var cats []*catalog.Category
+	for _, key := range keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		c, ok := catalog.Lookup(key)
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownCategory, key)
+		}
+		if !seen[key] {
+			seen[key] = true
+			cats = append(cats, c)
+		}
+	}
+	sort.Slice(cats, func(i, j int) bool { return index[cats[i].Key] < index[cats[j].Key] })
+	return cats, nil
+}
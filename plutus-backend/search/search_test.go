@@ -0,0 +1,37 @@
+package search
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"plutus-backend/catalog"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	valid := cursor{Rank: 0.5, Category: "watches", ID: "42"}
+	tests := []struct {
+		name   string
+		cursor string
+		ok     bool
+	}{
+		{"issued", valid.encode(), true},
+		{"not base64", "not a cursor!", false},
+		{"not JSON", base64.RawURLEncoding.EncodeToString([]byte("watches:42")), false},
+		{"id not a number", cursor{Rank: 0.5, Category: "watches", ID: "forty-two"}.encode(), false},
+		{"id missing", cursor{Rank: 0.5, Category: "watches"}.encode(), false},
+		{"id with SQL", cursor{Rank: 0.5, Category: "watches", ID: "1) OR (1=1"}.encode(), false},
+	}
+	for _, tt := range tests {
+		got, err := decodeCursor(tt.cursor)
+		if tt.ok {
+			if err != nil || got != valid {
+				t.Errorf("%s: decodeCursor = %+v, %v; want %+v", tt.name, got, err, valid)
+			}
+			continue
+		}
+		if !errors.Is(err, catalog.ErrInvalidCursor) {
+			t.Errorf("%s: err = %v, want ErrInvalidCursor", tt.name, err)
+		}
+	}
+}
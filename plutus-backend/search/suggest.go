@@ -0,0 +1,187 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"plutus-backend/catalog"
+	"plutus-backend/graph/model"
+	"plutus-backend/querybuilder"
+)
+
+const (
+	// DefaultSuggestions is how many suggestions Suggest returns when no
+	// limit is given.
+	DefaultSuggestions = 10
+	// MaxSuggestions caps the limit.
+	MaxSuggestions = 50
+)
+
+// kindOrder ranks suggestion kinds: brands first, then subcategories, then
+// individual products.
+var kindOrder = map[model.SuggestionKind]int{
+	model.SuggestionKindBrand:       0,
+	model.SuggestionKindSubcategory: 1,
+	model.SuggestionKindProduct:     2,
+}
+
+// Suggester answers search-as-you-type lookups from an in-memory prefix
+// index of brand names, product names and subcategories. The index is built
+// by Rebuild and replaced wholesale, so lookups never wait on a rebuild.
+type Suggester struct {
+	DB    *sql.DB
+	index atomic.Pointer[prefixIndex]
+}
+
+// NewSuggester returns a Suggester over db with an empty index.
+func NewSuggester(db *sql.DB) *Suggester {
+	s := &Suggester{DB: db}
+	s.index.Store(&prefixIndex{})
+	return s
+}
+
+// prefixIndex holds one entry per word position of every suggestion, sorted
+// by key, so a prefix lookup is a binary search followed by a scan.
+type prefixIndex struct {
+	entries []indexEntry
+}
+
+type indexEntry struct {
+	// key is the suggestion's normalized text from word pos onwards.
+	key        string
+	pos        int
+	suggestion *model.Suggestion
+}
+
+// suggestionSource is a column whose distinct values become suggestions.
+type suggestionSource struct {
+	kind   model.SuggestionKind
+	column string
+}
+
+// Rebuild reloads the index from the catalog.
+func (s *Suggester) Rebuild(ctx context.Context) error {
+	var suggestions []*model.Suggestion
+	for _, c := range catalog.Categories() {
+		sources := []suggestionSource{
+			{model.SuggestionKindBrand, catalog.CanonicalBrandExpr},
+			{model.SuggestionKindProduct, c.NameColumn},
+		}
+		if f, ok := c.Filters["subcategory"]; ok {
+			sources = append(sources, suggestionSource{model.SuggestionKindSubcategory, f.Column})
+		}
+		for _, src := range sources {
+			found, err := s.load(ctx, c, src.kind, src.column)
+			if err != nil {
+				return err
+			}
+			suggestions = append(suggestions, found...)
+		}
+	}
+
+	idx := &prefixIndex{}
+	for _, sg := range suggestions {
+		words := querybuilder.Words(sg.Text)
+		for pos := range words {
+			idx.entries = append(idx.entries, indexEntry{key: strings.Join(words[pos:], " "), pos: pos, suggestion: sg})
+		}
+	}
+	sort.Slice(idx.entries, func(i, j int) bool { return idx.entries[i].key < idx.entries[j].key })
+	s.index.Store(idx)
+	return nil
+}
+
+// load counts the distinct values of column in one category.
+func (s *Suggester) load(ctx context.Context, c *catalog.Category, kind model.SuggestionKind, column string) ([]*model.Suggestion, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT TRIM("+column+"), COUNT(*) FROM "+c.Table+
+		" WHERE "+catalog.Listed+" AND NULLIF(TRIM("+column+"), '') IS NOT NULL GROUP BY 1")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*model.Suggestion
+	for rows.Next() {
+		sg := &model.Suggestion{Kind: kind, Category: c.Key}
+		if err := rows.Scan(&sg.Text, &sg.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, sg)
+	}
+	return out, rows.Err()
+}
+
+// Suggest returns up to limit suggestions with a word starting with prefix.
+// The last word of prefix may be partial, so "air jo" matches "Air Jordan
+// 1". Matches at the start of the text come first, then brands before
+// subcategories before products, then the most common.
+func (s *Suggester) Suggest(prefix string, limit int) []*model.Suggestion {
+	if limit <= 0 {
+		limit = DefaultSuggestions
+	}
+	if limit > MaxSuggestions {
+		limit = MaxSuggestions
+	}
+	key := strings.Join(querybuilder.Words(prefix), " ")
+	if key == "" {
+		return []*model.Suggestion{}
+	}
+
+	entries := s.index.Load().entries
+	// Keep the best limit matches in order during a single scan. A
+	// suggestion can match at several word positions but appears once.
+	top := make([]indexEntry, 0, limit+1)
+	for i := sort.Search(len(entries), func(i int) bool { return entries[i].key >= key }); i < len(entries); i++ {
+		e := entries[i]
+		if !strings.HasPrefix(e.key, key) {
+			break
+		}
+		if j := slices.IndexFunc(top, func(t indexEntry) bool { return t.suggestion == e.suggestion }); j >= 0 {
+			if !e.before(top[j]) {
+				continue
+			}
+			top = slices.Delete(top, j, j+1)
+		}
+		if len(top) == limit && !e.before(top[limit-1]) {
+			continue
+		}
+		at, _ := slices.BinarySearchFunc(top, e, func(t, e indexEntry) int {
+			if t.before(e) {
+				return -1
+			}
+			return 1
+		})
+		top = slices.Insert(top, at, e)
+		if len(top) > limit {
+			top = top[:limit]
+		}
+	}
+
+	out := make([]*model.Suggestion, len(top))
+	for i, e := range top {
+		out[i] = e.suggestion
+	}
+	return out
+}
+
+// before reports whether e ranks ahead of o: matches at the start of the
+// text first, then by kind, then the most common.
+func (e indexEntry) before(o indexEntry) bool {
+	if (e.pos == 0) != (o.pos == 0) {
+		return e.pos == 0
+	}
+	a, b := e.suggestion, o.suggestion
+	if kindOrder[a.Kind] != kindOrder[b.Kind] {
+		return kindOrder[a.Kind] < kindOrder[b.Kind]
+	}
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	if a.Text != b.Text {
+		return a.Text < b.Text
+	}
+	return a.Category < b.Category
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,11 @@ import (
 
 	"github.com/lib/pq"
 	_ "github.com/lib/pq"
+
+	"plutus-backend/brands"
+	"plutus-backend/currency"
+	"plutus-backend/migrations"
+	"plutus-backend/search"
 )
 
 type SizePrice struct {
@@ -76,48 +82,18 @@ func main() {
 	}
 	defer db.Close()
 
-	// Drop and recreate accessories table
-	_, err = db.Exec(`DROP TABLE IF EXISTS accessories;`)
-	if err != nil {
-		log.Fatal("❌ Failed to drop accessories table:", err)
-	}
-	_, err = db.Exec(`CREATE TABLE accessories (
-		id SERIAL PRIMARY KEY,
-		brand TEXT NOT NULL,
-		product_name TEXT NOT NULL,
-		subcategory TEXT NOT NULL,
-		gender TEXT NOT NULL,
-		size_prices JSONB NOT NULL,
-		images TEXT[] NOT NULL,
-		in_stock BOOLEAN NOT NULL,
-		product_link TEXT NOT NULL,
-		seller_name TEXT,
-		seller_url TEXT
-	);`)
+	// The schema comes from the migrations; reseeding only replaces the
+	// rows. Price history is keyed by product link and survives it.
+	pending, err := migrations.Pending(context.Background(), db)
 	if err != nil {
-		log.Fatal("❌ Failed to create accessories table:", err)
+		log.Fatal("❌ Failed to check migrations:", err)
 	}
-
-	// Drop and recreate apparel table
-	_, err = db.Exec(`DROP TABLE IF EXISTS apparel;`)
-	if err != nil {
-		log.Fatal("❌ Failed to drop apparel table:", err)
+	if len(pending) > 0 {
+		log.Fatalf("❌ %d pending migrations, starting with %s; run `plutusctl migrate up` first", len(pending), pending[0])
 	}
-	_, err = db.Exec(`CREATE TABLE apparel (
-		id SERIAL PRIMARY KEY,
-		brand TEXT NOT NULL,
-		product_name TEXT NOT NULL,
-		subcategory TEXT NOT NULL,
-		gender TEXT NOT NULL,
-		size_prices JSONB NOT NULL,
-		images TEXT[] NOT NULL,
-		in_stock BOOLEAN NOT NULL,
-		product_link TEXT NOT NULL,
-		seller_name TEXT,
-		seller_url TEXT
-	);`)
+	_, err = db.Exec(`TRUNCATE accessories, apparel RESTART IDENTITY;`)
 	if err != nil {
-		log.Fatal("❌ Failed to create apparel table:", err)
+		log.Fatal("❌ Failed to empty accessories and apparel:", err)
 	}
 
 	// Seed accessories
@@ -150,5 +126,23 @@ func main() {
 	}
 	fmt.Println("✅ All apparel seeded.")
 
+	// Register any new brands
+	if _, err := brands.Sync(context.Background(), db); err != nil {
+		log.Fatal("❌ Failed to register brands:", err)
+	}
+	ratesFile, err := os.Open("seeding/data/exchange_rates.csv")
+	if err != nil {
+		log.Fatal("❌ Failed to open exchange rates:", err)
+	}
+	n, err := currency.Import(context.Background(), db, ratesFile)
+	ratesFile.Close()
+	if err != nil {
+		log.Fatal("❌ Failed to import exchange rates:", err)
+	}
+	fmt.Printf("✅ Imported %d exchange rates.\n", n)
+	if err := search.RefreshTerms(context.Background(), db); err != nil {
+		log.Fatal("❌ Failed to rebuild search vocabulary:", err)
+	}
+
 	fmt.Println("✅ All data seeded successfully!")
 }
@@ -1,12 +1,20 @@
 package main
 
 import (
-	"crypto/rand"
+	"context"
 	"database/sql"
-	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"maps"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"runtime"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
@@ -15,8 +23,18 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/rs/cors" // ✅ Make sure this is imported
 
+	"plutus-backend/alerts"
+	"plutus-backend/auth"
+	"plutus-backend/brands"
+	"plutus-backend/catalog"
+	"plutus-backend/currency"
+	"plutus-backend/enquiries"
 	"plutus-backend/graph"
 	"plutus-backend/graph/generated"
+	"plutus-backend/migrations"
+	"plutus-backend/notify"
+	"plutus-backend/search"
+	"plutus-backend/stash"
 
 	"encoding/json"
 	"strings"
@@ -34,35 +52,32 @@ var (
 	menuCacheTime  time.Time
 	menuCacheTTL   = 10 * time.Minute
 
-	// Search cache
-	searchCache      map[string][]byte
+	// Search cache, emptied whenever the catalog changes
+	searchCache      = make(map[string]searchCacheEntry)
 	searchCacheMutex sync.RWMutex
-	searchCacheTime  time.Time
 	searchCacheTTL   = 5 * time.Minute
-
-	// Rate limiting
-	rateLimitMap   = make(map[string][]time.Time)
-	rateLimitMutex sync.RWMutex
+	// searchCacheMax bounds the entries kept, since every distinct query
+	// string makes one.
+	searchCacheMax = 1000
+
+	// Rate limiting, per client address. Suggestions are fetched on every
+	// keystroke, so they have a larger budget of their own.
+	apiLimiter     = &rateLimiter{limit: 100, requests: make(map[string][]time.Time)}
+	suggestLimiter = &rateLimiter{limit: 600, requests: make(map[string][]time.Time)}
 )
 
-func getAllBrands(db *sql.DB, table string) []string {
-	rows, err := db.Query("SELECT DISTINCT brand FROM " + table)
-	if err != nil {
-		return nil
-	}
-	defer rows.Close()
-	var brands []string
-	for rows.Next() {
-		var brand string
-		if err := rows.Scan(&brand); err == nil {
-			brands = append(brands, brand)
-		}
+// getAllBrands lists the canonical names of the brands with products in a
+// category.
+func getAllBrands(category string) []string {
+	var names []string
+	for _, b := range brandResolver.InCategory(category) {
+		names = append(names, b.Name)
 	}
-	return brands
+	return names
 }
 
 func getAllSubcategories(db *sql.DB, table string) []string {
-	rows, err := db.Query("SELECT DISTINCT subcategory FROM " + table)
+	rows, err := db.Query("SELECT DISTINCT subcategory FROM " + table + " WHERE " + catalog.Listed)
 	if err != nil {
 		return nil
 	}
@@ -78,7 +93,7 @@ func getAllSubcategories(db *sql.DB, table string) []string {
 }
 
 func getAllGenders(db *sql.DB, table string) []string {
-	rows, err := db.Query("SELECT DISTINCT gender FROM " + table)
+	rows, err := db.Query("SELECT DISTINCT gender FROM " + table + " WHERE " + catalog.Listed)
 	if err != nil {
 		return nil
 	}
@@ -94,7 +109,7 @@ func getAllGenders(db *sql.DB, table string) []string {
 }
 
 func getAllFragranceFamilies(db *sql.DB) []string {
-	rows, err := db.Query("SELECT DISTINCT fragrance_family FROM perfumes")
+	rows, err := db.Query("SELECT DISTINCT fragrance_family FROM perfumes WHERE " + catalog.Listed)
 	if err != nil {
 		return nil
 	}
@@ -110,7 +125,7 @@ func getAllFragranceFamilies(db *sql.DB) []string {
 }
 
 func getProducts(db *sql.DB, table string, fields string, limit int) []map[string]interface{} {
-	query := "SELECT " + fields + " FROM " + table + " LIMIT $1"
+	query := "SELECT " + fields + " FROM " + table + " WHERE " + catalog.Listed + " LIMIT $1"
 	rows, err := db.Query(query, limit)
 	if err != nil {
 		return nil
@@ -154,7 +169,7 @@ func getProducts(db *sql.DB, table string, fields string, limit int) []map[strin
 }
 
 func getProductsByIndexes(db *sql.DB, table string, fields string, indexes []int) []map[string]interface{} {
-	query := "SELECT " + fields + " FROM " + table
+	query := "SELECT " + fields + " FROM " + table + " WHERE " + catalog.Listed
 	rows, err := db.Query(query)
 	if err != nil {
 		return nil
@@ -217,28 +232,28 @@ func menuHandler(w http.ResponseWriter, r *http.Request) {
 
 	menuData := map[string]interface{}{
 		"sneaker": map[string]interface{}{
-			"brands":   getAllBrands(db, "sneakers"),
+			"brands":   getAllBrands("sneakers"),
 			"products": getProductsByIndexes(db, "sneakers", "id, brand, product_name, images, product_link", []int{2, 4, 6, 8, 10, 13, 15, 16, 20}),
 		},
 		"apparel": map[string]interface{}{
-			"brands":        getAllBrands(db, "apparel"),
+			"brands":        getAllBrands("apparel"),
 			"subcategories": getAllSubcategories(db, "apparel"),
 			"genders":       getAllGenders(db, "apparel"),
 			"products":      getProductsByIndexes(db, "apparel", "id, brand, product_name, images, product_link, gender, subcategory", []int{1, 2, 3, 4, 5, 6}),
 		},
 		"watch": map[string]interface{}{
-			"brands":   getAllBrands(db, "watches"),
+			"brands":   getAllBrands("watches"),
 			"genders":  getAllGenders(db, "watches"),
 			"products": getProductsByIndexes(db, "watches", "id, brand, name, images, link, gender", []int{1, 2, 3, 4, 5, 6}),
 		},
 		"perfume": map[string]interface{}{
-			"brands":            getAllBrands(db, "perfumes"),
+			"brands":            getAllBrands("perfumes"),
 			"subcategories":     getAllSubcategories(db, "perfumes"),
 			"fragranceFamilies": getAllFragranceFamilies(db),
 			"products":          getProductsByIndexes(db, "perfumes", "id, brand, title, images, url, fragrance_family, subcategory", []int{1, 2, 3, 4, 5, 6}),
 		},
 		"accessories": map[string]interface{}{
-			"brands":        getAllBrands(db, "accessories"),
+			"brands":        getAllBrands("accessories"),
 			"subcategories": getAllSubcategories(db, "accessories"),
 			"genders":       getAllGenders(db, "accessories"),
 			"products":      getProductsByIndexes(db, "accessories", "id, brand, product_name, images, product_link, gender, subcategory", []int{1, 2, 3, 4, 5, 6}),
@@ -257,57 +272,87 @@ func menuHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(b)
 }
 
+// rateLimiter allows each client address limit requests a minute.
+type rateLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	requests map[string][]time.Time
+}
+
 // Rate limiting middleware
 func rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return apiLimiter.middleware(next)
+}
+
+func (l *rateLimiter) middleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		clientIP := r.RemoteAddr
-		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-			clientIP = forwarded
-		}
+		ip := clientIP(r)
 
-		rateLimitMutex.Lock()
+		l.mu.Lock()
 		now := time.Now()
 		window := now.Add(-1 * time.Minute) // 1 minute window
 
 		// Clean old requests
-		if requests, exists := rateLimitMap[clientIP]; exists {
+		if requests, exists := l.requests[ip]; exists {
 			var validRequests []time.Time
 			for _, reqTime := range requests {
 				if reqTime.After(window) {
 					validRequests = append(validRequests, reqTime)
 				}
 			}
-			rateLimitMap[clientIP] = validRequests
+			l.requests[ip] = validRequests
 		}
 
-		// Check rate limit (100 requests per minute)
-		requests := rateLimitMap[clientIP]
-		if len(requests) >= 100 {
-			rateLimitMutex.Unlock()
+		// Check rate limit
+		if len(l.requests[ip]) >= l.limit {
+			l.mu.Unlock()
 			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 			return
 		}
 
 		// Add current request
-		rateLimitMap[clientIP] = append(rateLimitMap[clientIP], now)
-		rateLimitMutex.Unlock()
+		l.requests[ip] = append(l.requests[ip], now)
+		l.mu.Unlock()
 
 		next(w, r)
 	}
 }
 
+// withClientIP passes the client's address to resolvers that submit
+// enquiries.
+func withClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(enquiries.WithClientIP(r.Context(), clientIP(r))))
+	})
+}
+
 func corsHandlerFunc(h http.HandlerFunc) http.Handler {
 	return cors.New(cors.Options{
 		AllowedOrigins:   []string{os.Getenv("CORS_ORIGIN"), "http://localhost:3000", "https://localhost:3000", "https://houseofplutus.com", "https://www.houseofplutus.com", "http://houseofplutus.com", "http://www.houseofplutus.com", "https://houseofplutus.in", "https://www.houseofplutus.in", "http://houseofplutus.in", "http://www.houseofplutus.in"},
 		AllowCredentials: true,
 		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Requested-With"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Requested-With", "Idempotency-Key"},
 		MaxAge:           86400, // 24 hours
 	}).Handler(http.HandlerFunc(h))
 }
 
 var globalDB *sql.DB
 
+var (
+	productCatalog *catalog.Catalog
+	searchEngine   *search.Engine
+	suggester      *search.Suggester
+	brandResolver  *brands.Resolver
+	rates          *currency.Rates
+	alertEvaluator *alerts.Evaluator
+	enquiryOutbox  *enquiries.Outbox
+	enquiryService *enquiries.Service
+	sessions       *auth.Sessions
+	mailer         notify.Notifier
+	loginAccounts  *auth.Throttle
+	loginIPs       *auth.Throttle
+)
+
 func main() {
 	// Only load .env file in development
 	if os.Getenv("NODE_ENV") != "production" {
@@ -361,16 +406,79 @@ func main() {
 
 	defer db.Close()
 
-	// Create indexes for faster queries
-	createIndexes(db)
+	// The schema is versioned by package migrations and applied with
+	// `plutusctl migrate up`; don't run against one that's behind.
+	pending, err := migrations.Pending(context.Background(), db)
+	if err != nil {
+		log.Fatalf("❌ Failed to check migrations: %v", err)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("❌ %d pending migrations, starting with %s; run `plutusctl migrate up` first", len(pending), pending[0])
+	}
+	log.Printf("✅ Schema up to date")
+
+	// Register brands added since the last start and bring the search
+	// vocabulary up to date
+	if _, err := brands.Sync(context.Background(), db); err != nil {
+		log.Printf("⚠️ Warning: Failed to register brands: %v", err)
+	}
+	if err := search.RefreshTerms(context.Background(), db); err != nil {
+		log.Printf("⚠️ Warning: Failed to refresh search vocabulary: %v", err)
+	}
 
-	// Create auth tables
-	createAuthTables(db)
+	sessions = auth.NewSessions(db, jwtSecret)
+	loginAccounts = auth.NewAccountThrottle(db)
+	loginIPs = auth.NewIPThrottle(db)
+	go pruneLoginFailures()
+	go dummyPasswordHash()
 
 	globalDB = db // set global DB for menuHandler
 
-	resolver := &graph.Resolver{DB: db}
-	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: resolver}))
+	productCatalog = catalog.New(db)
+	searchEngine = search.New(productCatalog)
+	suggester = search.NewSuggester(db)
+	if err := suggester.Rebuild(context.Background()); err != nil {
+		log.Printf("⚠️ Warning: Failed to build suggestion index: %v", err)
+	}
+	brandResolver = brands.NewResolver(db)
+	if err := brandResolver.Load(context.Background()); err != nil {
+		log.Printf("⚠️ Warning: Failed to load brands: %v", err)
+	}
+	rates = currency.NewRates(db)
+	if err := rates.Load(context.Background()); err != nil {
+		log.Printf("⚠️ Warning: Failed to load exchange rates: %v", err)
+	}
+	for _, c := range catalog.Categories() {
+		if _, err := rates.Convert(1, c.PriceCurrency(), currency.Base); err != nil {
+			log.Printf("⚠️ Warning: %s prices can't be converted: %v", c.Key, err)
+		}
+	}
+	mailer, err = notify.FromEnv()
+	if err != nil {
+		log.Printf("⚠️ Warning: %v, logging notifications instead", err)
+		mailer = &notify.File{}
+	}
+	alertEvaluator = &alerts.Evaluator{Catalog: productCatalog, Rates: rates, Notifier: mailer, StorefrontURL: os.Getenv("STOREFRONT_URL")}
+	enquiryRoutes, err := enquiries.ParseRoutes(os.Getenv("ENQUIRY_ROUTES"))
+	if err != nil {
+		log.Printf("⚠️ Warning: %v, enquiries will only be acknowledged", err)
+	} else if len(enquiryRoutes.For("")) == 0 {
+		log.Printf("⚠️ Warning: ENQUIRY_ROUTES has no default (*) route; enquiries without a route of their own will only be acknowledged")
+	}
+	storefrontURL := os.Getenv("STOREFRONT_URL")
+	if storefrontURL == "" {
+		storefrontURL = alerts.DefaultStorefrontURL
+	}
+	enquiryOutbox = &enquiries.Outbox{DB: db, Mailer: mailer, Routes: enquiryRoutes, StorefrontURL: storefrontURL}
+	go enquiryOutbox.Run(context.Background())
+	enquiryService = enquiries.NewService(db, enquiryOutbox)
+	go watchCatalog(dbURL)
+
+	resolver := &graph.Resolver{DB: db, Catalog: productCatalog, Searcher: searchEngine, Suggester: suggester, BrandResolver: brandResolver, Rates: rates, Enquiries: enquiryService}
+	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
+		Resolvers:  resolver,
+		Directives: generated.DirectiveRoot{HasRole: graph.HasRole},
+	}))
 
 	// ✅ Add CORS here with multiple origins for deployment
 	corsOrigin := os.Getenv("CORS_ORIGIN")
@@ -405,16 +513,23 @@ func main() {
 		AllowedOrigins:   allowedOrigins,
 		AllowCredentials: true,
 		AllowedMethods:   []string{"GET", "POST", "OPTIONS", "PUT", "DELETE"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Requested-With", "Origin", "Accept"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Requested-With", "Origin", "Accept", currency.Header},
 		MaxAge:           86400, // 24 hours
-	}).Handler(srv)
-
-	http.Handle("/query", corsHandler)                                                           // ✅ CORS applied here
-	http.Handle("/api/menu", corsHandlerFunc(rateLimitMiddleware(menuHandler)))                  // CORS + Rate limit for menu
-	http.Handle("/api/search", corsHandlerFunc(rateLimitMiddleware(searchHandler)))              // CORS + Rate limit for search
-	http.Handle("/api/auth/register", corsHandlerFunc(rateLimitMiddleware(authRegisterHandler))) // Auth register
-	http.Handle("/api/auth/login", corsHandlerFunc(rateLimitMiddleware(authLoginHandler)))       // Auth login
-	http.Handle("/api/enquiry", corsHandlerFunc(rateLimitMiddleware(enquiryHandler)))            // Enquiry
+	}).Handler(sessions.Middleware(currency.Middleware(withClientIP(srv))))
+
+	http.Handle("/query", corsHandler)                                                                        // ✅ CORS applied here
+	http.Handle("/api/menu", corsHandlerFunc(rateLimitMiddleware(menuHandler)))                               // CORS + Rate limit for menu
+	http.Handle("/api/search", corsHandlerFunc(rateLimitMiddleware(searchHandler)))                           // CORS + Rate limit for search
+	http.Handle("/api/search/suggest", corsHandlerFunc(suggestLimiter.middleware(suggestHandler)))            // CORS + its own rate limit, called on every keystroke
+	http.Handle("/api/auth/register", corsHandlerFunc(rateLimitMiddleware(authRegisterHandler)))              // Auth register
+	http.Handle("/api/auth/login", corsHandlerFunc(rateLimitMiddleware(authLoginHandler)))                    // Auth login
+	http.Handle("/api/auth/refresh", corsHandlerFunc(rateLimitMiddleware(authRefreshHandler)))                // Rotate refresh token
+	http.Handle("/api/auth/logout", corsHandlerFunc(rateLimitMiddleware(authLogoutHandler)))                  // End session
+	http.Handle("/api/auth/oauth", corsHandlerFunc(rateLimitMiddleware(authOAuthHandler)))                    // Google/Apple sign-in via the storefront
+	http.Handle("/api/auth/forgot-password", corsHandlerFunc(rateLimitMiddleware(authForgotPasswordHandler))) // Email a reset link
+	http.Handle("/api/auth/reset-password", corsHandlerFunc(rateLimitMiddleware(authResetPasswordHandler)))   // Set a new password
+	http.Handle("/api/auth/verify-email", corsHandlerFunc(rateLimitMiddleware(authVerifyEmailHandler)))       // Confirm an email address
+	http.Handle("/api/enquiry", corsHandlerFunc(rateLimitMiddleware(enquiryHandler)))                         // Enquiry
 	http.Handle("/", playground.Handler("GraphQL Playground", "/query"))
 
 	log.Printf("🚀 Server running at http://localhost:%s/", port)
@@ -424,75 +539,40 @@ func main() {
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
 
-func createIndexes(db *sql.DB) {
-	indexes := []string{
-		"CREATE INDEX IF NOT EXISTS idx_sneakers_brand ON sneakers(LOWER(brand))",
-		"CREATE INDEX IF NOT EXISTS idx_sneakers_brand_product_name ON sneakers(LOWER(brand), LOWER(product_name))",
-		"CREATE INDEX IF NOT EXISTS idx_watches_brand ON watches(LOWER(brand))",
-		"CREATE INDEX IF NOT EXISTS idx_perfumes_brand ON perfumes(LOWER(brand))",
-		"CREATE INDEX IF NOT EXISTS idx_accessories_brand ON accessories(LOWER(brand))",
-		"CREATE INDEX IF NOT EXISTS idx_apparel_brand ON apparel(LOWER(brand))",
-	}
-
-	for _, index := range indexes {
-		_, err := db.Exec(index)
-		if err != nil {
-			log.Printf("⚠️ Warning: Failed to create index: %v", err)
-		} else {
-			log.Printf("✅ Created index for faster queries")
-		}
-	}
-}
-
-func createAuthTables(db *sql.DB) {
-	tables := []string{
-		`CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			user_id VARCHAR(50) UNIQUE NOT NULL,
-			full_name VARCHAR(255) NOT NULL,
-			email VARCHAR(255) UNIQUE NOT NULL,
-			phone VARCHAR(20),
-			password_hash VARCHAR(255) NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS enquiries (
-			id SERIAL PRIMARY KEY,
-			name VARCHAR(255) NOT NULL,
-			email VARCHAR(255) NOT NULL,
-			phone VARCHAR(20),
-			message TEXT NOT NULL,
-			product_id VARCHAR(100),
-			product_name VARCHAR(255),
-			product_category VARCHAR(100),
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-	}
-
-	indexes := []string{
-		"CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)",
-		"CREATE INDEX IF NOT EXISTS idx_users_user_id ON users(user_id)",
-		"CREATE INDEX IF NOT EXISTS idx_enquiries_email ON enquiries(email)",
-		"CREATE INDEX IF NOT EXISTS idx_enquiries_product_id ON enquiries(product_id)",
-		"CREATE INDEX IF NOT EXISTS idx_enquiries_created_at ON enquiries(created_at)",
-	}
-
-	for _, table := range tables {
-		_, err := db.Exec(table)
-		if err != nil {
-			log.Printf("⚠️ Warning: Failed to create table: %v", err)
-		} else {
-			log.Printf("✅ Created auth tables")
+// watchCatalog rebuilds everything derived from the product tables after
+// they change: new brands, the suggestion index, the typo vocabulary and the
+// search cache. Brand and exchange rate edits are announced the same way.
+// Then it sends the price and stock alerts the change set off.
+func watchCatalog(dbURL string) {
+	err := catalog.WatchChanges(context.Background(), dbURL, 2*time.Second, func() {
+		ctx := context.Background()
+		if _, err := brands.Sync(ctx, globalDB); err != nil {
+			log.Printf("⚠️ Warning: Failed to sync brands: %v", err)
 		}
-	}
-
-	for _, index := range indexes {
-		_, err := db.Exec(index)
+		if err := brandResolver.Load(ctx); err != nil {
+			log.Printf("⚠️ Warning: Failed to load brands: %v", err)
+		}
+		if err := rates.Load(ctx); err != nil {
+			log.Printf("⚠️ Warning: Failed to load exchange rates: %v", err)
+		}
+		if err := suggester.Rebuild(ctx); err != nil {
+			log.Printf("⚠️ Warning: Failed to rebuild suggestion index: %v", err)
+		}
+		if err := search.RefreshTerms(ctx, globalDB); err != nil {
+			log.Printf("⚠️ Warning: %v", err)
+		}
+		clearSearchCache()
+		log.Printf("🔄 Catalog changed, search indexes rebuilt")
+		sent, err := alertEvaluator.Run(ctx)
 		if err != nil {
-			log.Printf("⚠️ Warning: Failed to create auth index: %v", err)
-		} else {
-			log.Printf("✅ Created auth indexes")
+			log.Printf("⚠️ Warning: Failed to deliver alerts: %v", err)
+		}
+		if sent > 0 {
+			log.Printf("📧 Sent %d alerts", sent)
 		}
+	})
+	if err != nil {
+		log.Printf("⚠️ Warning: Not watching catalog changes: %v", err)
 	}
 }
 
@@ -541,31 +621,51 @@ func searchHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check cache first
-	cacheKey := query + ":" + category
+	cacheKey := r.URL.Query().Encode()
 	searchCacheMutex.RLock()
-	if time.Since(searchCacheTime) < searchCacheTTL && searchCache != nil {
-		if cached, exists := searchCache[cacheKey]; exists {
-			defer searchCacheMutex.RUnlock()
-			w.Header().Set("Content-Type", "application/json")
-			w.Write(cached)
+	cached, exists := searchCache[cacheKey]
+	searchCacheMutex.RUnlock()
+	if exists && time.Now().Before(cached.expires) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(cached.body)
+		return
+	}
+
+	params, err := searchParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := searchEngine.Search(r.Context(), params)
+	if err != nil {
+		if errors.Is(err, search.ErrUnknownCategory) || errors.Is(err, catalog.ErrInvalidCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		log.Printf("❌ Search failed: %v", err)
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
 	}
-	searchCacheMutex.RUnlock()
-
-	db := globalDB
-	var results []map[string]interface{}
 
-	if category != "" {
-		// Search within specific category
-		results = searchInCategory(db, category, query)
-	} else {
-		// Search across all categories
-		results = searchAllCategories(db, query)
+	categoryCounts := map[string]int{}
+	for _, c := range page.CategoryCounts {
+		categoryCounts[c.Category] = c.Count
+	}
+	products := page.Hits
+	if products == nil {
+		products = []search.Hit{}
 	}
-
 	response := map[string]interface{}{
-		"products": results,
+		"products":       products,
+		"total":          page.TotalCount,
+		"categoryCounts": categoryCounts,
+		"hasNextPage":    page.HasNextPage,
+	}
+	if n := len(products); n > 0 {
+		response["endCursor"] = products[n-1].Cursor
+	}
+	if page.DidYouMean != "" {
+		response["didYouMean"] = page.DidYouMean
 	}
 
 	b, err := json.Marshal(response)
@@ -574,95 +674,147 @@ func searchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Cache the results
-	searchCacheMutex.Lock()
-	if searchCache == nil {
-		searchCache = make(map[string][]byte)
-	}
-	searchCache[cacheKey] = b
-	searchCacheTime = time.Now()
-	searchCacheMutex.Unlock()
+	cacheSearch(cacheKey, b)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(b)
 }
 
-func searchInCategory(db *sql.DB, category, query string) []map[string]interface{} {
-	table := category
-	fields := "id, brand, product_name, images, product_link"
+type searchCacheEntry struct {
+	body    []byte
+	expires time.Time
+}
 
-	if category == "watches" {
-		fields = "id, brand, name, images, link"
-	} else if category == "perfumes" {
-		fields = "id, brand, title, images, url"
+// cacheSearch stores a search response, first dropping expired entries
+// and, if the cache is still full, the ones that would expire soonest.
+func cacheSearch(key string, body []byte) {
+	now := time.Now()
+	searchCacheMutex.Lock()
+	defer searchCacheMutex.Unlock()
+	for k, e := range searchCache {
+		if !now.Before(e.expires) {
+			delete(searchCache, k)
+		}
 	}
+	if _, ok := searchCache[key]; !ok && len(searchCache) >= searchCacheMax {
+		keys := slices.Collect(maps.Keys(searchCache))
+		slices.SortFunc(keys, func(a, b string) int { return searchCache[a].expires.Compare(searchCache[b].expires) })
+		for _, k := range keys[:len(keys)-searchCacheMax+1] {
+			delete(searchCache, k)
+		}
+	}
+	searchCache[key] = searchCacheEntry{body: body, expires: now.Add(searchCacheTTL)}
+}
 
-	// Use ILIKE for case-insensitive search
-	sqlQuery := "SELECT " + fields + " FROM " + table + " WHERE brand ILIKE $1 OR product_name ILIKE $1 OR name ILIKE $1 OR title ILIKE $1 LIMIT 50"
+func clearSearchCache() {
+	searchCacheMutex.Lock()
+	searchCache = make(map[string]searchCacheEntry)
+	searchCacheMutex.Unlock()
+}
 
-	rows, err := db.Query(sqlQuery, "%"+query+"%")
-	if err != nil {
-		return nil
+// suggestHandler serves search-as-you-type suggestions for ?q= from the
+// in-memory index.
+func suggestHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, fmt.Sprintf("invalid limit %q", v), http.StatusBadRequest)
+			return
+		}
+		limit = n
 	}
-	defer rows.Close()
-
-	var results []map[string]interface{}
-	for rows.Next() {
-		// Scan based on category
-		var result map[string]interface{}
-		// Implementation depends on the specific fields for each category
-		results = append(results, result)
+	b, err := json.Marshal(map[string]interface{}{
+		"suggestions": suggester.Suggest(r.URL.Query().Get("q"), limit),
+	})
+	if err != nil {
+		http.Error(w, "Failed to marshal suggestions", http.StatusInternalServerError)
+		return
 	}
-
-	return results
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
 }
 
-func searchAllCategories(db *sql.DB, query string) []map[string]interface{} {
-	// Search across all categories and combine results
-	allResults := []map[string]interface{}{}
-
-	categories := []string{"sneakers", "apparel", "accessories", "perfumes", "watches"}
-	for _, category := range categories {
-		results := searchInCategory(db, category, query)
-		allResults = append(allResults, results...)
+// searchParams reads /api/search's query string: q, category (one key or a
+// comma-separated list), limit, and either an after cursor or a page/offset
+// for clients that page by number.
+func searchParams(r *http.Request) (search.Params, error) {
+	q := r.URL.Query()
+	limit := 50
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return search.Params{}, fmt.Errorf("invalid limit %q", v)
+		}
+		limit = n
 	}
-
-	// Limit total results
-	if len(allResults) > 50 {
-		allResults = allResults[:50]
+	params := search.Params{Query: q.Get("q"), First: &limit, WithCounts: true}
+	if category := q.Get("category"); category != "" {
+		params.Categories = strings.Split(category, ",")
 	}
-
-	return allResults
+	if after := q.Get("after"); after != "" {
+		params.After = &after
+		return params, nil
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return search.Params{}, fmt.Errorf("invalid offset %q", v)
+		}
+		params.Offset = n
+	} else if v := q.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return search.Params{}, fmt.Errorf("invalid page %q", v)
+		}
+		params.Offset = (n - 1) * limit
+	}
+	return params, nil
 }
 
 func getProductCount(db *sql.DB, table string) int {
 	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count)
+	err := db.QueryRow("SELECT COUNT(*) FROM " + table + " WHERE " + catalog.Listed).Scan(&count)
 	if err != nil {
 		return 0
 	}
 	return count
 }
 
-// Generate random user ID
-func generateUserID() string {
-	bytes := make([]byte, 16)
-	rand.Read(bytes)
-	return "user_" + hex.EncodeToString(bytes)
-}
+// bcryptSlots bounds how many bcrypt hashes run at once. At cost 14 each
+// takes most of a second of CPU, so parallel sign-in attempts queue here
+// instead of starving the rest of the server.
+var bcryptSlots = make(chan struct{}, max(runtime.NumCPU()/2, 1))
 
 // Hash password
 func hashPassword(password string) (string, error) {
+	bcryptSlots <- struct{}{}
+	defer func() { <-bcryptSlots }()
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	return string(bytes), err
 }
 
+// minPasswordLength matches the storefront's sign-up form.
+const minPasswordLength = 6
+
 // Check password
 func checkPassword(password, hash string) bool {
+	bcryptSlots <- struct{}{}
+	defer func() { <-bcryptSlots }()
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
 
+// dummyPasswordHash is checked against when no account has the email being
+// signed in to, so that takes as long as a wrong password.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(auth.NewUserID()), 14)
+	if err != nil {
+		log.Printf("⚠️ Warning: Failed to hash dummy password: %v", err)
+	}
+	return string(hash)
+})
+
 // Auth Register Handler
 func authRegisterHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -687,6 +839,10 @@ func authRegisterHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Email and password are required", http.StatusBadRequest)
 		return
 	}
+	if len(req.Password) < minPasswordLength {
+		http.Error(w, fmt.Sprintf("Password must be at least %d characters", minPasswordLength), http.StatusBadRequest)
+		return
+	}
 
 	// Hash password
 	hashedPassword, err := hashPassword(req.Password)
@@ -710,11 +866,13 @@ func authRegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create new user
-	userID := generateUserID()
-	_, err = globalDB.Exec(`
+	userID := auth.NewUserID()
+	var id int
+	err = globalDB.QueryRow(`
 		INSERT INTO users (user_id, full_name, email, phone, password_hash)
 		VALUES ($1, $2, $3, $4, $5)
-	`, userID, req.FullName, req.Email, req.Phone, hashedPassword)
+		RETURNING id
+	`, userID, req.FullName, req.Email, req.Phone, hashedPassword).Scan(&id)
 
 	if err != nil {
 		log.Printf("Failed to create user: %v", err)
@@ -722,16 +880,29 @@ func authRegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sign the new user in
+	user := &auth.User{ID: id, UserID: userID, Email: req.Email, FullName: req.FullName, Role: auth.Customer}
+	tokens, err := sessions.Start(r.Context(), user, r.UserAgent(), clientIP(r))
+	if err != nil {
+		log.Printf("Failed to start session: %v", err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+	go sendVerificationEmail(user)
+
 	// Return success
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"message": "Account created successfully",
+		"tokens":  tokens,
 		"user": map[string]interface{}{
-			"id":       userID,
-			"fullName": req.FullName,
-			"email":    req.Email,
-			"phone":    req.Phone,
+			"id":            userID,
+			"fullName":      req.FullName,
+			"email":         req.Email,
+			"phone":         req.Phone,
+			"emailVerified": false,
+			"role":          user.Role,
 		},
 	})
 }
@@ -746,6 +917,9 @@ func authLoginHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		// Stash is the stash kept in the browser while signed out; it is
+		// merged into the account's.
+		Stash signedOutStash `json:"stash"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -759,15 +933,53 @@ func authLoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// One attempt per account at a time, so parallel guesses can't all get
+	// in before the first failure is counted. Accounts are keyed by the
+	// email as typed, so unknown emails queue and lock out the same way.
+	accountKey := strings.ToLower(strings.TrimSpace(req.Email))
+	unlock, err := signInLocks.lock(r.Context(), accountKey)
+	if err != nil {
+		// The client went away while waiting its turn
+		return
+	}
+	defer unlock()
+
+	// Refuse locked-out accounts and addresses before spending a bcrypt
+	// hash on them. This comes after waiting, since the attempt before may
+	// have set off the lockout.
+	ip := clientIP(r)
+	if wait := loginLockout(r.Context(), accountKey, ip); wait > 0 {
+		writeLoginLocked(w, wait)
+		return
+	}
+
 	// Find user and check password
+	var id int
 	var userID, fullName, email, phone, passwordHash string
-	err := globalDB.QueryRow(`
-		SELECT user_id, full_name, email, phone, password_hash 
+	var emailVerified bool
+	var role auth.Role
+	err = globalDB.QueryRow(`
+		SELECT id, user_id, full_name, email, COALESCE(phone, ''), password_hash, email_verified_at IS NOT NULL, role
 		FROM users WHERE email = $1
-	`, req.Email).Scan(&userID, &fullName, &email, &phone, &passwordHash)
+	`, req.Email).Scan(&id, &userID, &fullName, &email, &phone, &passwordHash, &emailVerified, &role)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Failed to look up user: %v", err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
 
-	if err != nil {
-		// User not found
+	// Check password; an unknown email, or an account that signs in with
+	// Google or Apple and has no password, is checked against a dummy hash
+	// so it takes as long as a wrong password
+	known := err == nil && passwordHash != ""
+	if !known {
+		passwordHash = dummyPasswordHash()
+	}
+	if !checkPassword(req.Password, passwordHash) || !known {
+		if wait := loginFailed(r.Context(), accountKey, ip); wait > 0 {
+			writeLoginLocked(w, wait)
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -776,32 +988,655 @@ func authLoginHandler(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if err := loginAccounts.Reset(r.Context(), accountKey); err != nil {
+		log.Printf("⚠️ Warning: Failed to reset login failures: %v", err)
+	}
+
+	user := &auth.User{ID: id, UserID: userID, Email: email, FullName: fullName, Role: role}
+	tokens, err := sessions.Start(r.Context(), user, r.UserAgent(), clientIP(r))
+	if err != nil {
+		log.Printf("Failed to start session: %v", err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+
+	// Merge the anonymous stash into the account's
+	stashMerged := req.Stash.merge(r.Context(), productCatalog, user)
+
+	// Return success
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"message":     "Successfully signed in",
+		"stashMerged": stashMerged,
+		"tokens":      tokens,
+		"user": map[string]interface{}{
+			"id":            userID,
+			"fullName":      fullName,
+			"email":         email,
+			"phone":         phone,
+			"emailVerified": emailVerified,
+			"role":          user.Role,
+		},
+	})
+}
+
+// signedOutStash is the storefront's stash kept in the browser while
+// signed out, sent along when signing in.
+type signedOutStash []struct {
+	ID          string `json:"id"`
+	Category    string `json:"category"`
+	ProductType string `json:"productType"`
+}
+
+// merge adds the stash to u's, checking the products against c, and returns
+// the number of products added. Failures are logged; the browser keeps its
+// stash.
+func (items signedOutStash) merge(ctx context.Context, c *catalog.Catalog, u *auth.User) int {
+	if len(items) == 0 {
+		return 0
+	}
+	refs := make([]stash.Ref, 0, len(items))
+	for _, item := range items {
+		category := item.Category
+		if _, ok := catalog.Find(category); !ok {
+			category = item.ProductType
+		}
+		refs = append(refs, stash.Ref{Category: category, ProductID: item.ID})
+	}
+	added, err := stash.Merge(ctx, c, u.ID, refs)
+	if err != nil {
+		log.Printf("⚠️ Warning: Failed to merge stash for %s: %v", u.UserID, err)
+	}
+	return added
+}
+
+// signInLocks serialises the sign-ins to each account.
+var signInLocks = &accountLocks{held: map[string]*accountLock{}}
+
+// accountLocks is a lock per account, kept only while someone holds or
+// waits for it.
+type accountLocks struct {
+	mu   sync.Mutex
+	held map[string]*accountLock
+}
+
+type accountLock struct {
+	turn  chan struct{}
+	users int
+}
+
+// lock waits until key is free, or ctx ends, and returns the function that
+// frees it.
+func (l *accountLocks) lock(ctx context.Context, key string) (func(), error) {
+	l.mu.Lock()
+	a := l.held[key]
+	if a == nil {
+		a = &accountLock{turn: make(chan struct{}, 1)}
+		l.held[key] = a
+	}
+	a.users++
+	l.mu.Unlock()
+
+	release := func() {
+		l.mu.Lock()
+		if a.users--; a.users == 0 {
+			delete(l.held, key)
+		}
+		l.mu.Unlock()
+	}
+	select {
+	case a.turn <- struct{}{}:
+		return func() {
+			<-a.turn
+			release()
+		}, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
+
+// loginLockout returns how much longer sign-ins to an account or from an
+// address are locked out, or 0. If the failures can't be read, sign-ins are
+// let through rather than locking everyone out.
+func loginLockout(ctx context.Context, accountKey, ip string) time.Duration {
+	var wait time.Duration
+	for _, lock := range []struct {
+		throttle *auth.Throttle
+		key      string
+	}{{loginAccounts, accountKey}, {loginIPs, ip}} {
+		d, err := lock.throttle.Locked(ctx, lock.key)
+		if err != nil {
+			log.Printf("⚠️ Warning: Failed to check login failures: %v", err)
+		}
+		wait = max(wait, d)
+	}
+	return wait
+}
+
+// loginFailed records a failed sign-in and returns the lockout it set off,
+// or 0.
+func loginFailed(ctx context.Context, accountKey, ip string) time.Duration {
+	account, err := loginAccounts.Fail(ctx, accountKey)
+	if err != nil {
+		log.Printf("⚠️ Warning: Failed to record login failure: %v", err)
+	}
+	address, err := loginIPs.Fail(ctx, ip)
+	if err != nil {
+		log.Printf("⚠️ Warning: Failed to record login failure: %v", err)
+	}
+	return max(account, address)
+}
+
+func writeLoginLocked(w http.ResponseWriter, wait time.Duration) {
+	seconds := max(int(wait.Round(time.Second).Seconds()), 1)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    false,
+		"message":    fmt.Sprintf("Too many failed sign-in attempts. Please try again in %s.", waitText(wait)),
+		"retryAfter": seconds,
+	})
+}
+
+func waitText(d time.Duration) string {
+	if d <= 5*time.Second {
+		return "a moment"
+	}
+	if d < time.Minute {
+		return fmt.Sprintf("%d seconds", int(d.Round(time.Second).Seconds()))
+	}
+	if minutes := int(d.Round(time.Minute).Minutes()); minutes < 60 {
+		if minutes == 1 {
+			return "a minute"
+		}
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+	return "an hour"
+}
+
+// pruneLoginFailures clears out old failed sign-in counts every hour.
+func pruneLoginFailures() {
+	for ; ; time.Sleep(time.Hour) {
+		for _, t := range []*auth.Throttle{loginAccounts, loginIPs} {
+			if err := t.Prune(context.Background()); err != nil {
+				log.Printf("⚠️ Warning: Failed to prune login failures: %v", err)
+			}
+		}
+	}
+}
+
+// Auth OAuth Handler signs in with a Google or Apple identity the
+// storefront's server has verified, creating or linking the account. The
+// request must be signed with OAUTH_BRIDGE_SECRET (see auth.VerifyBridge).
+func authOAuthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	secret := os.Getenv("OAUTH_BRIDGE_SECRET")
+	if secret == "" {
+		http.Error(w, "OAuth sign-in is not configured", http.StatusServiceUnavailable)
+		return
+	}
 
-	// Check password
-	if !checkPassword(req.Password, passwordHash) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := auth.VerifyBridge([]byte(secret), r.Header.Get("X-Plutus-Timestamp"), r.Header.Get("X-Plutus-Signature"), body, time.Now()); err != nil {
+		http.Error(w, "Invalid request signature", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Provider      string         `json:"provider"`
+		ProviderID    string         `json:"providerId"`
+		Email         string         `json:"email"`
+		EmailVerified bool           `json:"emailVerified"`
+		FullName      string         `json:"fullName"`
+		Stash         signedOutStash `json:"stash"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if !slices.Contains(auth.Providers, req.Provider) || req.ProviderID == "" || req.Email == "" {
+		http.Error(w, "provider, providerId and email are required", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.FullName) == "" {
+		req.FullName, _, _ = strings.Cut(req.Email, "@")
+	}
+
+	user, created, err := auth.SignIn(r.Context(), globalDB, auth.Identity{
+		Provider:      req.Provider,
+		Subject:       req.ProviderID,
+		Email:         req.Email,
+		EmailVerified: req.EmailVerified,
+		FullName:      strings.TrimSpace(req.FullName),
+	})
+	if errors.Is(err, auth.ErrUnverifiedEmail) {
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
+		w.WriteHeader(http.StatusConflict)
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
-			"message": "Invalid email or password",
+			"message": "An account with this email already exists. Please sign in with your password.",
 		})
 		return
 	}
+	if err != nil {
+		log.Printf("Failed to sign in with %s: %v", req.Provider, err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
 
-	// Return success
+	tokens, err := sessions.Start(r.Context(), user, r.UserAgent(), clientIP(r))
+	if err != nil {
+		log.Printf("Failed to start session: %v", err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+	stashMerged := req.Stash.merge(r.Context(), productCatalog, user)
+
+	var phone string
+	var emailVerified bool
+	if err := globalDB.QueryRowContext(r.Context(), "SELECT COALESCE(phone, ''), email_verified_at IS NOT NULL FROM users WHERE id = $1", user.ID).Scan(&phone, &emailVerified); err != nil {
+		log.Printf("⚠️ Warning: Failed to load user %s: %v", user.UserID, err)
+	}
+	message := "Successfully signed in"
+	if created {
+		message = "Account created successfully"
+	}
 	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"message":     message,
+		"created":     created,
+		"stashMerged": stashMerged,
+		"tokens":      tokens,
+		"user": map[string]interface{}{
+			"id":            user.UserID,
+			"fullName":      user.FullName,
+			"email":         user.Email,
+			"phone":         phone,
+			"emailVerified": emailVerified,
+			"role":          user.Role,
+		},
+	})
+}
+
+// Auth Refresh Handler exchanges a refresh token for a new access and
+// refresh token. The old refresh token stops working.
+func authRefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "refreshToken is required", http.StatusBadRequest)
+		return
+	}
+
+	tokens, user, err := sessions.Refresh(r.Context(), req.RefreshToken)
+	w.Header().Set("Content-Type", "application/json")
+	if errors.Is(err, auth.ErrInvalidToken) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Your session has expired. Please sign in again.",
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to refresh session: %v", err)
+		http.Error(w, "Failed to refresh session", http.StatusInternalServerError)
+		return
+	}
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"message": "Successfully signed in",
+		"tokens":  tokens,
 		"user": map[string]interface{}{
-			"id":       userID,
-			"fullName": fullName,
-			"email":    email,
-			"phone":    phone,
+			"id":       user.UserID,
+			"fullName": user.FullName,
+			"email":    user.Email,
+			"role":     user.Role,
 		},
 	})
 }
 
+// Auth Logout Handler ends the session of a refresh token. With "all" and a
+// valid access token it ends every session of the user.
+func authLogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+		All          bool   `json:"all"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.All {
+		token, ok := auth.BearerToken(r)
+		if !ok {
+			http.Error(w, "Signing out everywhere needs an access token", http.StatusUnauthorized)
+			return
+		}
+		user, err := sessions.Authenticate(token)
+		if err != nil {
+			http.Error(w, "Invalid or expired access token", http.StatusUnauthorized)
+			return
+		}
+		if err := sessions.RevokeAll(r.Context(), user.ID); err != nil {
+			log.Printf("Failed to end sessions: %v", err)
+			http.Error(w, "Failed to sign out", http.StatusInternalServerError)
+			return
+		}
+	} else if req.RefreshToken != "" {
+		if err := sessions.Revoke(r.Context(), req.RefreshToken); err != nil {
+			log.Printf("Failed to end session: %v", err)
+			http.Error(w, "Failed to sign out", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Signed out",
+	})
+}
+
+// Auth Forgot Password Handler emails a password reset link. It answers the
+// same whether or not the email belongs to an account, so it can't be used
+// to find out who has one.
+func authForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	// Look the account up and send the email in the background, so the
+	// response takes as long either way
+	go sendPasswordResetEmail(strings.TrimSpace(req.Email))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "If an account exists for that email, we've sent a link to reset its password.",
+	})
+}
+
+// Auth Reset Password Handler sets a new password with the token from a
+// reset email. The token works once, and every session of the account is
+// ended.
+func authResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" || req.Password == "" {
+		http.Error(w, "Token and password are required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Password) < minPasswordLength {
+		http.Error(w, fmt.Sprintf("Password must be at least %d characters", minPasswordLength), http.StatusBadRequest)
+		return
+	}
+
+	// Hash first: bcrypt can wait for a slot and then take a while, and the
+	// transaction holds the token's row lock until it commits
+	hashedPassword, err := hashPassword(req.Password)
+	if err != nil {
+		http.Error(w, "Failed to process password", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := globalDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Failed to reset password: %v", err)
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	id, err := auth.ConsumeToken(r.Context(), tx, req.Token, auth.PasswordReset)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "This reset link is invalid or has expired. Please request a new one.",
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to reset password: %v", err)
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	// The link arrived by email, so it also proves the address
+	if _, err := tx.ExecContext(r.Context(), "UPDATE users SET password_hash = $2, email_verified_at = COALESCE(email_verified_at, now()), updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		id, hashedPassword); err != nil {
+		log.Printf("Failed to reset password: %v", err)
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to reset password: %v", err)
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	if err := sessions.RevokeAll(r.Context(), id); err != nil {
+		log.Printf("⚠️ Warning: Failed to end sessions after a password reset: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Your password has been reset. Please sign in with your new password.",
+	})
+}
+
+// Auth Verify Email Handler confirms an email address with the token from a
+// verification email. Signed in and with "resend", it sends a new
+// verification email instead.
+func authVerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Token  string `json:"token"`
+		Resend bool   `json:"resend"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Resend {
+		token, ok := auth.BearerToken(r)
+		if !ok {
+			http.Error(w, "Resending the verification email needs an access token", http.StatusUnauthorized)
+			return
+		}
+		user, err := sessions.Authenticate(token)
+		if err != nil {
+			http.Error(w, "Invalid or expired access token", http.StatusUnauthorized)
+			return
+		}
+		go sendVerificationEmail(user)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "We've sent you a new verification email.",
+		})
+		return
+	}
+	if req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := globalDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Failed to verify email: %v", err)
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	id, err := auth.ConsumeToken(r.Context(), tx, req.Token, auth.EmailVerification)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "This verification link is invalid or has expired.",
+		})
+		return
+	}
+	if err == nil {
+		_, err = tx.ExecContext(r.Context(), "UPDATE users SET email_verified_at = COALESCE(email_verified_at, now()) WHERE id = $1", id)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Failed to verify email: %v", err)
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Your email address is verified.",
+	})
+}
+
+// sendVerificationEmail emails u a link confirming their address. Failures
+// are logged; the user can ask for another email.
+func sendVerificationEmail(u *auth.User) {
+	ctx := context.Background()
+	token, err := auth.IssueToken(ctx, globalDB, u.ID, auth.EmailVerification)
+	if err != nil {
+		log.Printf("⚠️ Warning: Failed to issue verification token for %s: %v", u.UserID, err)
+		return
+	}
+	err = mailer.Notify(ctx, notify.Message{
+		To:      u.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address for House of Plutus:\n%s\n\nThe link expires in %s. If you didn't create an account, you can ignore this email.\n",
+			greetingName(u.FullName), storefrontLink("/auth/verify-email", token), formatTTL(auth.EmailVerification.TTL())),
+	})
+	if err != nil {
+		log.Printf("⚠️ Warning: Failed to send verification email to %s: %v", u.UserID, err)
+	}
+}
+
+// sendPasswordResetEmail emails a reset link to the account registered
+// under email, if there is one.
+func sendPasswordResetEmail(email string) {
+	ctx := context.Background()
+	var id int
+	var userID, fullName string
+	err := globalDB.QueryRowContext(ctx, "SELECT id, user_id, full_name FROM users WHERE email = $1", email).Scan(&id, &userID, &fullName)
+	if err == sql.ErrNoRows {
+		return
+	}
+	if err != nil {
+		log.Printf("⚠️ Warning: Failed to look up account for a password reset: %v", err)
+		return
+	}
+	token, err := auth.IssueToken(ctx, globalDB, id, auth.PasswordReset)
+	if err != nil {
+		log.Printf("⚠️ Warning: Failed to issue reset token for %s: %v", userID, err)
+		return
+	}
+	err = mailer.Notify(ctx, notify.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your House of Plutus account. To choose a new one, open:\n%s\n\nThe link expires in %s and works once. If it wasn't you, you can ignore this email; your password hasn't changed.\n",
+			greetingName(fullName), storefrontLink("/auth/reset-password", token), formatTTL(auth.PasswordReset.TTL())),
+	})
+	if err != nil {
+		log.Printf("⚠️ Warning: Failed to send password reset email to %s: %v", userID, err)
+	}
+}
+
+// storefrontLink returns the storefront page at path carrying token.
+func storefrontLink(path, token string) string {
+	base := os.Getenv("STOREFRONT_URL")
+	if base == "" {
+		base = alerts.DefaultStorefrontURL
+	}
+	return strings.TrimRight(base, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+func greetingName(fullName string) string {
+	if first, _, _ := strings.Cut(strings.TrimSpace(fullName), " "); first != "" {
+		return first
+	}
+	return "there"
+}
+
+func formatTTL(d time.Duration) string {
+	if h := int(d.Hours()); h > 1 {
+		return fmt.Sprintf("%d hours", h)
+	}
+	return "1 hour"
+}
+
+// clientIP returns the address a request came from. Behind TRUSTED_PROXIES
+// proxies (default 1, the hosting platform's), that is the X-Forwarded-For
+// entry the outermost one appended; entries before it are whatever the
+// client sent and can't be trusted. With TRUSTED_PROXIES=0, or without the
+// header, it is the connection's remote host.
+func clientIP(r *http.Request) string {
+	hops := 1
+	if n, err := strconv.Atoi(os.Getenv("TRUSTED_PROXIES")); err == nil && n >= 0 {
+		hops = n
+	}
+	if forwarded := r.Header.Values("X-Forwarded-For"); hops > 0 && len(forwarded) > 0 {
+		entries := strings.Split(strings.Join(forwarded, ","), ",")
+		return strings.TrimSpace(entries[max(len(entries)-hops, 0)])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // Enquiry Handler
 func enquiryHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -817,28 +1652,57 @@ func enquiryHandler(w http.ResponseWriter, r *http.Request) {
 		ProductID       string `json:"productId"`
 		ProductName     string `json:"productName"`
 		ProductCategory string `json:"productCategory"`
+		Website         string `json:"website"`
+		IdempotencyKey  string `json:"idempotencyKey"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeEnquiryError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-
-	// Validate required fields
-	if req.Name == "" || req.Email == "" || req.Message == "" {
-		http.Error(w, "Name, email and message are required", http.StatusBadRequest)
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		req.IdempotencyKey = key
+	}
+
+	// Validate, screen and save the enquiry, queueing its notifications
+	_, err := enquiryService.Submit(r.Context(), enquiries.Submission{
+		Name:            req.Name,
+		Email:           req.Email,
+		Phone:           req.Phone,
+		Message:         req.Message,
+		ProductID:       req.ProductID,
+		ProductName:     req.ProductName,
+		ProductCategory: req.ProductCategory,
+		Website:         req.Website,
+		IdempotencyKey:  req.IdempotencyKey,
+		IP:              clientIP(r),
+	})
+	var invalid *enquiries.ValidationError
+	var throttled *enquiries.ThrottledError
+	switch {
+	case errors.As(err, &invalid):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": invalid.Error(),
+			"errors":  invalid.Fields,
+		})
 		return
-	}
-
-	// Save enquiry to database
-	_, err := globalDB.Exec(`
-		INSERT INTO enquiries (name, email, phone, message, product_id, product_name, product_category)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`, req.Name, req.Email, req.Phone, req.Message, req.ProductID, req.ProductName, req.ProductCategory)
-
-	if err != nil {
+	case errors.As(err, &throttled):
+		seconds := max(int(throttled.RetryAfter.Round(time.Second).Seconds()), 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":    false,
+			"message":    fmt.Sprintf("You've sent a lot of enquiries in a short time. Please try again in %s.", waitText(throttled.RetryAfter)),
+			"retryAfter": seconds,
+		})
+		return
+	case err != nil:
 		log.Printf("Failed to save enquiry: %v", err)
-		http.Error(w, "Failed to save enquiry", http.StatusInternalServerError)
+		writeEnquiryError(w, http.StatusInternalServerError, "Failed to save enquiry")
 		return
 	}
 
@@ -849,3 +1713,12 @@ func enquiryHandler(w http.ResponseWriter, r *http.Request) {
 		"message": "Enquiry submitted successfully",
 	})
 }
+
+func writeEnquiryError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"message": message,
+	})
+}
@@ -0,0 +1,213 @@
+// Package stash keeps each signed-in user's stash, the products they saved
+// for later, so it follows them across devices. Clients refer to products
+// by category and id; the stash stores the product's link instead, like
+// price history and alerts, so a re-seed renumbering the products doesn't
+// empty it.
+package stash
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"plutus-backend/catalog"
+	"plutus-backend/graph/model"
+)
+
+// MaxItems bounds one user's stash, and so the size of a merge.
+const MaxItems = 500
+
+// ErrNotFound is returned for a product that doesn't exist.
+var ErrNotFound = errors.New("product not found")
+
+// Ref names a product the way clients do.
+type Ref struct {
+	// Category is a category key or the storefront's product type, such
+	// as "sneakers" or "sneaker" (see catalog.Find).
+	Category  string
+	ProductID string
+}
+
+// Item is a stashed product. Product is nil while no product is listed
+// under ProductLink.
+type Item struct {
+	Category    string
+	ProductLink string
+	AddedAt     time.Time
+	Product     model.Product
+}
+
+// resolve finds the product ref names.
+func resolve(ctx context.Context, cat *catalog.Catalog, ref Ref) (*catalog.Category, model.Product, error) {
+	c, ok := catalog.Find(ref.Category)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown category %q", ref.Category)
+	}
+	if _, err := strconv.Atoi(ref.ProductID); err != nil {
+		return nil, nil, fmt.Errorf("%s %q: %w", c.Key, ref.ProductID, ErrNotFound)
+	}
+	found, err := cat.Products(ctx, c.Key, []string{ref.ProductID})
+	if err != nil {
+		return nil, nil, err
+	}
+	p := found[ref.ProductID]
+	if p == nil || catalog.ProductLink(p) == "" {
+		return nil, nil, fmt.Errorf("%s %q: %w", c.Key, ref.ProductID, ErrNotFound)
+	}
+	return c, p, nil
+}
+
+const insertItem = "INSERT INTO stash_items (user_id, category, product_link) SELECT $1, $2, $3" +
+	" WHERE (SELECT COUNT(*) FROM stash_items WHERE user_id = $1) < $4" +
+	" ON CONFLICT (user_id, category, product_link) DO NOTHING"
+
+// Add stashes a product for userID. Adding a stashed product again keeps
+// its original AddedAt.
+func Add(ctx context.Context, cat *catalog.Catalog, userID int, ref Ref) (*Item, error) {
+	c, p, err := resolve(ctx, cat, ref)
+	if err != nil {
+		return nil, err
+	}
+	link := catalog.ProductLink(p)
+	if _, err := cat.DB.ExecContext(ctx, insertItem, userID, c.Key, link, MaxItems); err != nil {
+		return nil, err
+	}
+	item := &Item{Category: c.Key, ProductLink: link, Product: p}
+	err = cat.DB.QueryRowContext(ctx, "SELECT added_at FROM stash_items WHERE user_id = $1 AND category = $2 AND product_link = $3",
+		userID, c.Key, link).Scan(&item.AddedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("a stash holds at most %d products", MaxItems)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Remove takes a product out of userID's stash. It reports whether the
+// product was stashed.
+func Remove(ctx context.Context, cat *catalog.Catalog, userID int, ref Ref) (bool, error) {
+	c, p, err := resolve(ctx, cat, ref)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	res, err := cat.DB.ExecContext(ctx, "DELETE FROM stash_items WHERE user_id = $1 AND category = $2 AND product_link = $3",
+		userID, c.Key, catalog.ProductLink(p))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Merge adds the products of a stash kept while signed out, such as the
+// storefront's local one, to userID's stash in one transaction. Unknown
+// categories and products that no longer exist are skipped. It returns the
+// number of products added.
+func Merge(ctx context.Context, cat *catalog.Catalog, userID int, refs []Ref) (int, error) {
+	if len(refs) > MaxItems {
+		refs = refs[:MaxItems]
+	}
+	ids := map[string][]string{}
+	for _, ref := range refs {
+		if c, ok := catalog.Find(ref.Category); ok {
+			if _, err := strconv.Atoi(ref.ProductID); err == nil {
+				ids[c.Key] = append(ids[c.Key], ref.ProductID)
+			}
+		}
+	}
+	products := map[string]map[string]model.Product{}
+	for category, categoryIDs := range ids {
+		found, err := cat.Products(ctx, category, categoryIDs)
+		if err != nil {
+			return 0, err
+		}
+		products[category] = found
+	}
+	type key struct{ category, link string }
+	var keys []key
+	seen := map[key]bool{}
+	for _, ref := range refs {
+		c, ok := catalog.Find(ref.Category)
+		if !ok {
+			continue
+		}
+		p := products[c.Key][ref.ProductID]
+		if p == nil || catalog.ProductLink(p) == "" {
+			continue
+		}
+		if k := (key{c.Key, catalog.ProductLink(p)}); !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+
+	tx, err := cat.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	added := 0
+	for _, k := range keys {
+		res, err := tx.ExecContext(ctx, insertItem, userID, k.category, k.link, MaxItems)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		added += int(n)
+	}
+	return added, tx.Commit()
+}
+
+// List returns userID's stash, most recently added first. Products that
+// are no longer listed are left out.
+func List(ctx context.Context, cat *catalog.Catalog, userID int) ([]*Item, error) {
+	rows, err := cat.DB.QueryContext(ctx, "SELECT category, product_link, added_at FROM stash_items"+
+		" WHERE user_id = $1 ORDER BY added_at DESC, category, product_link", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*Item
+	links := map[string][]string{}
+	for rows.Next() {
+		var item Item
+		if err := rows.Scan(&item.Category, &item.ProductLink, &item.AddedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+		links[item.Category] = append(links[item.Category], item.ProductLink)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	products := map[string]map[string]model.Product{}
+	for category, categoryLinks := range links {
+		if _, ok := catalog.Lookup(category); !ok {
+			continue
+		}
+		found, err := cat.ProductsByLink(ctx, category, categoryLinks)
+		if err != nil {
+			return nil, err
+		}
+		products[category] = found
+	}
+	out := []*Item{}
+	for _, item := range items {
+		if item.Product = products[item.Category][item.ProductLink]; item.Product != nil {
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
@@ -2,7 +2,7 @@ import React, { useEffect, useRef, useState } from 'react';
 import { useSession } from 'next-auth/react';
 import { useEnquiryPanel } from './EnquiryPanelContext';
 import styles from './LiveChat.module.css';
-import { sendEnquiry } from '../utils/sendEnquiry';
+import { newIdempotencyKey, sendEnquiry } from '../utils/sendEnquiry';
 
 interface ChatMessage {
   from: 'bot' | 'user' | 'typing';
@@ -24,6 +24,8 @@ const LiveChat: React.FC = () => {
   const [toast, setToast] = useState<string | null>(null);
   const chatEndRef = useRef<HTMLDivElement>(null);
   const contactInputRef = useRef<HTMLInputElement>(null);
+  // One enquiry per chat, however many times it is retried
+  const idempotencyKey = useRef(newIdempotencyKey());
 
   // Scroll to bottom on new message
   useEffect(() => {
@@ -114,7 +116,7 @@ const LiveChat: React.FC = () => {
         productId: product?.id,
         productName: product?.name,
         productCategory: product?.brand,
-      })
+      }, idempotencyKey.current)
         .then(() => {
           setLoading(false);
           setMessages(prev => [
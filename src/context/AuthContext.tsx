@@ -49,17 +49,57 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
 
     if (status === 'authenticated' && session?.user) {
       // User is authenticated via NextAuth (Google OAuth)
+      const savedUser = localStorage.getItem('plutus_user');
+      if (savedUser && localStorage.getItem('plutus_tokens')) {
+        // Already signed in to the backend for this session
+        try {
+          const userData = JSON.parse(savedUser);
+          setUser(userData);
+          setIsAuthenticated(true);
+          syncStashWithUser(userData.id);
+          setIsLoading(false);
+          return;
+        } catch {
+          localStorage.removeItem('plutus_user');
+        }
+      }
+
       const nextAuthUser: User = {
         id: session.user.email || 'nextauth-user',
         fullName: session.user.name || session.user.email?.split('@')[0] || 'User',
         email: session.user.email || '',
         createdAt: new Date().toISOString()
       };
-      
       setUser(nextAuthUser);
       setIsAuthenticated(true);
-      syncStashWithUser(nextAuthUser.id);
       setIsLoading(false);
+
+      // Sign in to the backend as the same person, so the account gets a
+      // server-side stash, alerts and enquiries like password accounts
+      let stash = [];
+      try {
+        stash = JSON.parse(localStorage.getItem('stashedProducts') || '[]');
+      } catch {
+        stash = [];
+      }
+      fetch('/api/auth/oauth-user', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ stash }),
+      })
+        .then((response) => response.json())
+        .then((data) => {
+          if (data.success) {
+            setUser(data.user);
+            localStorage.setItem('plutus_user', JSON.stringify(data.user));
+            localStorage.setItem('plutus_tokens', JSON.stringify(data.tokens));
+            syncStashWithUser(data.user.id);
+          } else {
+            console.error('Backend sign-in failed:', data.message);
+            syncStashWithUser(nextAuthUser.id);
+          }
+        })
+        .catch(() => syncStashWithUser(nextAuthUser.id));
     } else if (status === 'unauthenticated') {
       // Check for custom authentication
       const savedUser = localStorage.getItem('plutus_user');
@@ -85,12 +125,19 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
 
   const login = async (email: string, password: string): Promise<{ success: boolean; message: string }> => {
     try {
+      // Send the signed-out stash along so the backend merges it into the account
+      let stash = [];
+      try {
+        stash = JSON.parse(localStorage.getItem('stashedProducts') || '[]');
+      } catch {
+        stash = [];
+      }
       const response = await fetch('/api/auth/login', {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
         },
-        body: JSON.stringify({ email, password }),
+        body: JSON.stringify({ email, password, stash }),
       });
 
       const data = await response.json();
@@ -99,6 +146,9 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
         setUser(data.user);
         setIsAuthenticated(true);
         localStorage.setItem('plutus_user', JSON.stringify(data.user));
+        if (data.tokens) {
+          localStorage.setItem('plutus_tokens', JSON.stringify(data.tokens));
+        }
         syncStashWithUser(data.user.id);
         return { success: true, message: data.message };
       } else {
@@ -125,6 +175,9 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
         setUser(data.user);
         setIsAuthenticated(true);
         localStorage.setItem('plutus_user', JSON.stringify(data.user));
+        if (data.tokens) {
+          localStorage.setItem('plutus_tokens', JSON.stringify(data.tokens));
+        }
         syncStashWithUser(data.user.id);
         return { success: true, message: data.message };
       } else {
@@ -139,6 +192,22 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
     setUser(null);
     setIsAuthenticated(false);
     localStorage.removeItem('plutus_user');
+
+    // End the backend session
+    const tokens = localStorage.getItem('plutus_tokens');
+    localStorage.removeItem('plutus_tokens');
+    if (tokens) {
+      try {
+        const { refreshToken } = JSON.parse(tokens);
+        fetch('/api/auth/logout', {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ refreshToken }),
+        }).catch(() => {});
+      } catch {
+        // Ignore unreadable tokens
+      }
+    }
     
     // Clear user-specific stash and revert to anonymous stash
     if (user) {
@@ -1,5 +1,6 @@
 import { ApolloClient, InMemoryCache, HttpLink, NormalizedCacheObject, from } from '@apollo/client';
 import { onError } from '@apollo/client/link/error';
+import { setContext } from '@apollo/client/link/context';
 import { useMemo } from 'react';
 import { RetryLink } from '@apollo/client/link/retry';
 
@@ -30,6 +31,45 @@ const errorLink = onError(({ graphQLErrors, networkError, operation }) => {
   }
 });
 
+let refreshing: Promise<any> | null = null;
+
+async function refreshTokens(refreshToken: string) {
+  const response = await fetch('/api/auth/refresh', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ refreshToken }),
+  });
+  const data = await response.json();
+  if (data.success) {
+    localStorage.setItem('plutus_tokens', JSON.stringify(data.tokens));
+    return data.tokens;
+  }
+  localStorage.removeItem('plutus_tokens');
+  return null;
+}
+
+// Sends the signed-in user's access token, stored by AuthContext, and
+// exchanges the refresh token for a new one shortly before it expires
+const authLink = setContext(async (_, { headers }) => {
+  if (typeof window === 'undefined') return { headers };
+  try {
+    let tokens = JSON.parse(localStorage.getItem('plutus_tokens') || 'null');
+    if (tokens?.refreshToken && new Date(tokens.accessExpiresAt).getTime() - Date.now() < 30000) {
+      // A refresh token works once, so concurrent requests share one refresh
+      refreshing = refreshing ?? refreshTokens(tokens.refreshToken).finally(() => {
+        refreshing = null;
+      });
+      tokens = await refreshing;
+    }
+    if (tokens?.accessToken) {
+      return { headers: { ...headers, Authorization: `Bearer ${tokens.accessToken}` } };
+    }
+  } catch {
+    // Go out signed out if the tokens can't be read or refreshed
+  }
+  return { headers };
+});
+
 function createApolloClient() {
   const httpLink = new HttpLink({
     uri: process.env.NEXT_PUBLIC_GRAPHQL_ENDPOINT || 'http://localhost:8090/query',
@@ -65,7 +105,7 @@ function createApolloClient() {
 
   return new ApolloClient({
     ssrMode: typeof window === 'undefined',
-    link: from([errorLink, retryLink, httpLink]),
+    link: from([errorLink, retryLink, authLink, httpLink]),
     cache: new InMemoryCache({
       typePolicies: {
         Query: {
@@ -48,7 +48,7 @@ export const authOptions: NextAuthOptions = {
     }),
   ],
   callbacks: {
-    async jwt({ token, user, account }: any) {
+    async jwt({ token, user, account, profile }: any) {
       console.log('JWT Callback:', { token: !!token, user: !!user, account: !!account });
       
       if (account && user) {
@@ -66,6 +66,12 @@ export const authOptions: NextAuthOptions = {
             name: user.name,
             image: user.image,
           };
+
+          // Kept in the token only, for /api/auth/oauth-user to link the
+          // Google account to a backend user
+          token.provider = account.provider;
+          token.providerId = account.providerAccountId;
+          token.emailVerified = profile?.email_verified === true;
           
           console.log('User token created:', token.user);
         }
@@ -6,7 +6,7 @@ export default async function handler(req: NextApiRequest, res: NextApiResponse)
   }
 
   try {
-    const { email, password } = req.body;
+    const { email, password, stash } = req.body;
 
     // Validate required fields
     if (!email || !password) {
@@ -31,14 +31,22 @@ export default async function handler(req: NextApiRequest, res: NextApiResponse)
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        // Failed sign-ins are throttled per address, so pass on the client's
+        // (set TRUSTED_PROXIES=2 on the backend to trust it)
+        'X-Forwarded-For': String(req.headers['x-forwarded-for'] || req.socket.remoteAddress || ''),
       },
       body: JSON.stringify({
         email,
-        password
+        password,
+        stash
       }),
     });
 
     const data = await response.json();
+    const retryAfter = response.headers.get('Retry-After');
+    if (retryAfter) {
+      res.setHeader('Retry-After', retryAfter);
+    }
     return res.status(response.status).json(data);
 
   } catch (error) {
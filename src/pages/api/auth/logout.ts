@@ -0,0 +1,30 @@
+import { NextApiRequest, NextApiResponse } from 'next';
+
+export default async function handler(req: NextApiRequest, res: NextApiResponse) {
+  if (req.method !== 'POST') {
+    return res.status(405).json({ message: 'Method not allowed' });
+  }
+
+  try {
+    // Forward request to Go backend
+    const backendUrl = process.env.BACKEND_URL || 'https://finalised-a77d.onrender.com';
+    const response = await fetch(`${backendUrl}/api/auth/logout`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        ...(req.headers.authorization ? { Authorization: req.headers.authorization } : {}),
+      },
+      body: JSON.stringify(req.body ?? {}),
+    });
+
+    const data = await response.json();
+    return res.status(response.status).json(data);
+
+  } catch (error) {
+    console.error('Logout error:', error);
+    return res.status(500).json({ 
+      success: false, 
+      message: 'An unexpected error occurred. Please try again.' 
+    });
+  }
+}
@@ -1,54 +1,68 @@
 import type { NextApiRequest, NextApiResponse } from 'next';
+import { createHmac } from 'crypto';
+import { getToken } from 'next-auth/jwt';
 
+// Signs the user of the current NextAuth (Google) session in to the Go
+// backend, creating or linking their account there. The identity is read
+// from the NextAuth token, never from the request body, and the backend
+// only accepts it signed with OAUTH_BRIDGE_SECRET.
 export default async function handler(req: NextApiRequest, res: NextApiResponse) {
   if (req.method !== 'POST') {
     return res.status(405).json({ success: false, message: 'Method not allowed' });
   }
 
   try {
-    const { email, fullName, profileImage, provider, providerId } = req.body;
-
-    // Validate required fields
-    if (!email || !fullName || !provider) {
-      return res.status(400).json({ 
-        success: false, 
-        message: 'Missing required fields' 
+    const token = await getToken({ req, secret: process.env.NEXTAUTH_SECRET });
+    if (!token?.provider || !token?.providerId || !token?.email) {
+      return res.status(401).json({
+        success: false,
+        message: 'You need to sign in with Google first'
       });
     }
 
-    // Here you would typically:
-    // 1. Check if user already exists by email
-    // 2. If exists, update their OAuth provider info
-    // 3. If not exists, create new user
-    // 4. Return user data
-
-    // For now, we'll simulate a successful user creation
-    const user = {
-      id: `oauth_${Date.now()}`,
-      email,
-      fullName,
-      profileImage: profileImage || null,
-      provider,
-      providerId,
-      createdAt: new Date().toISOString(),
-    };
-
-    // Store user in localStorage for demo (in production, use your database)
-    if (typeof window !== 'undefined') {
-      localStorage.setItem('plutus_user', JSON.stringify(user));
+    const secret = process.env.OAUTH_BRIDGE_SECRET;
+    if (!secret) {
+      console.error('OAuth user error: OAUTH_BRIDGE_SECRET is not set');
+      return res.status(503).json({
+        success: false,
+        message: 'Google sign-in is not available right now'
+      });
     }
 
-    return res.status(200).json({
-      success: true,
-      message: 'User created successfully',
-      user
+    const body = JSON.stringify({
+      provider: token.provider,
+      providerId: token.providerId,
+      email: token.email,
+      emailVerified: token.emailVerified === true,
+      fullName: token.name || '',
+      stash: req.body?.stash,
     });
+    const timestamp = Math.floor(Date.now() / 1000).toString();
+    const signature = createHmac('sha256', secret).update(`${timestamp}.${body}`).digest('hex');
+
+    // Forward request to Go backend
+    const backendUrl = process.env.BACKEND_URL || 'https://finalised-a77d.onrender.com';
+    const response = await fetch(`${backendUrl}/api/auth/oauth`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        'X-Plutus-Timestamp': timestamp,
+        'X-Plutus-Signature': signature,
+      },
+      body,
+    });
+
+    const data = await response.json().catch(() => ({
+      success: false,
+      message: 'Failed to sign in. Please try again.'
+    }));
+    return res.status(response.status).json(data);
 
   } catch (error) {
-    console.error('OAuth user creation error:', error);
+    console.error('OAuth user error:', error);
     return res.status(500).json({
       success: false,
       message: 'Internal server error'
     });
   }
-} 
\ No newline at end of file
+}
@@ -6,7 +6,7 @@ export default async function handler(req: NextApiRequest, res: NextApiResponse)
   }
 
   try {
-    const { name, email, message } = req.body;
+    const { name, email, message, website, idempotencyKey } = req.body;
 
     // Validate required fields
     if (!name || !email || !message) {
@@ -31,6 +31,10 @@ export default async function handler(req: NextApiRequest, res: NextApiResponse)
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        // Enquiries are throttled per address, so pass on the client's
+        'X-Forwarded-For': String(req.headers['x-forwarded-for'] || req.socket.remoteAddress || ''),
+        // Lets the backend recognise a retried submission
+        'Idempotency-Key': String(req.headers['idempotency-key'] || idempotencyKey || ''),
       },
       body: JSON.stringify({
         name,
@@ -40,11 +44,17 @@ export default async function handler(req: NextApiRequest, res: NextApiResponse)
         phone: '',
         productId: '',
         productName: '',
-        productCategory: 'contact_form'
+        productCategory: 'contact_form',
+        // The honeypot, which only bots fill in
+        website
       }),
     });
 
     const data = await response.json();
+    const retryAfter = response.headers.get('Retry-After');
+    if (retryAfter) {
+      res.setHeader('Retry-After', retryAfter);
+    }
     
     if (response.ok) {
       return res.status(200).json({
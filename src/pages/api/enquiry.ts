@@ -6,7 +6,7 @@ export default async function handler(req: NextApiRequest, res: NextApiResponse)
   }
 
   try {
-    const { name, email, phone, message, productId, productName, productCategory } = req.body;
+    const { name, email, phone, message, productId, productName, productCategory, website, idempotencyKey } = req.body;
 
     // Validate required fields
     if (!name || !email || !message) {
@@ -22,6 +22,10 @@ export default async function handler(req: NextApiRequest, res: NextApiResponse)
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        // Enquiries are throttled per address, so pass on the client's
+        'X-Forwarded-For': String(req.headers['x-forwarded-for'] || req.socket.remoteAddress || ''),
+        // Lets the backend recognise a retried submission
+        'Idempotency-Key': String(req.headers['idempotency-key'] || idempotencyKey || ''),
       },
       body: JSON.stringify({
         name,
@@ -30,11 +34,16 @@ export default async function handler(req: NextApiRequest, res: NextApiResponse)
         message,
         productId,
         productName,
-        productCategory
+        productCategory,
+        website
       }),
     });
 
     const data = await response.json();
+    const retryAfter = response.headers.get('Retry-After');
+    if (retryAfter) {
+      res.setHeader('Retry-After', retryAfter);
+    }
     return res.status(response.status).json(data);
 
   } catch (error) {
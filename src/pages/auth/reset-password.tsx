@@ -0,0 +1,154 @@
+import React, { useState } from 'react';
+import Image from 'next/image';
+import { useRouter } from 'next/router';
+import styles from './AuthPage.module.css';
+
+// Without a token this page asks for the account's email and sends a reset
+// link; the link brings the user back here with ?token= to choose a new
+// password.
+const ResetPasswordPage = () => {
+  const router = useRouter();
+  const token = typeof router.query.token === 'string' ? router.query.token : '';
+  const [email, setEmail] = useState('');
+  const [password, setPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const [isLoading, setIsLoading] = useState(false);
+  const [done, setDone] = useState(false);
+  const [message, setMessage] = useState('');
+  const [messageType, setMessageType] = useState<'success' | 'error'>('success');
+
+  const post = async (path: string, body: object) => {
+    setIsLoading(true);
+    setMessage('');
+    try {
+      const response = await fetch(path, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(body),
+      });
+      const data = await response.json().catch(() => ({}));
+      setMessageType(response.ok && data.success ? 'success' : 'error');
+      setMessage(data.message || 'An unexpected error occurred. Please try again.');
+      setDone(response.ok && data.success);
+    } catch (error) {
+      setMessageType('error');
+      setMessage('An unexpected error occurred. Please try again.');
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!token) {
+      await post('/api/auth/forgot-password', { email });
+      return;
+    }
+    if (password.length < 6) {
+      setMessageType('error');
+      setMessage('Password must be at least 6 characters long.');
+      return;
+    }
+    if (password !== confirmPassword) {
+      setMessageType('error');
+      setMessage('Passwords do not match.');
+      return;
+    }
+    await post('/api/auth/reset-password', { token, password });
+  };
+
+  return (
+    <div className={styles.authPage} data-auth-page="true">
+      <div className={styles.backgroundImage}>
+        <Image
+          src="/auth-background-optimized.webp"
+          alt="Background"
+          fill
+          style={{ objectFit: 'cover', objectPosition: 'center' }}
+          priority
+          sizes="100vw"
+          quality={60}
+        />
+      </div>
+
+      <div className={styles.authContainer}>
+        <div className={styles.logoContainer}>
+          <Image src="/LOGO.svg" alt="House of Plutus" width={160} height={48} priority />
+          <button className={styles.closeButton} onClick={() => router.push('/')} aria-label="Close and go back">
+            <svg xmlns="http://www.w3.org/2000/svg" fill="none" viewBox="0 0 24 24" strokeWidth="1.5" stroke="currentColor" className={styles.closeIcon}>
+              <path strokeLinecap="round" strokeLinejoin="round" d="M6 18 18 6M6 6l12 12" />
+            </svg>
+          </button>
+        </div>
+
+        <form onSubmit={handleSubmit} className={styles.form}>
+          {!token ? (
+            <div className={styles.inputGroup}>
+              <label className={styles.inputLabel}>Email Address</label>
+              <input
+                type="email"
+                placeholder="kashishexample@gmail.com"
+                value={email}
+                onChange={(e) => setEmail(e.target.value)}
+                className={styles.input}
+                required
+              />
+            </div>
+          ) : (
+            <>
+              <div className={styles.inputGroup}>
+                <label className={styles.inputLabel}>New Password</label>
+                <input
+                  type="password"
+                  placeholder="********"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  className={styles.input}
+                  required
+                />
+              </div>
+              <div className={styles.inputGroup}>
+                <label className={styles.inputLabel}>Confirm Password</label>
+                <input
+                  type="password"
+                  placeholder="********"
+                  value={confirmPassword}
+                  onChange={(e) => setConfirmPassword(e.target.value)}
+                  className={styles.input}
+                  required
+                />
+              </div>
+            </>
+          )}
+
+          {message && (
+            <div className={`${styles.message} ${styles[messageType]}`}>
+              {message}
+            </div>
+          )}
+
+          {done && token ? (
+            <button type="button" className={styles.submitButton} onClick={() => router.push('/auth/signin')}>
+              Log In
+            </button>
+          ) : (
+            <button
+              type="submit"
+              disabled={isLoading || done}
+              className={`${styles.submitButton} ${isLoading ? styles.loading : ''}`}
+            >
+              {isLoading ? <div className={styles.spinner} /> : token ? 'Reset Password' : 'Send Reset Link'}
+            </button>
+          )}
+        </form>
+      </div>
+    </div>
+  );
+};
+
+// Custom layout to remove navbar
+ResetPasswordPage.getLayout = (page: React.ReactElement) => {
+  return <>{page}</>;
+};
+
+export default ResetPasswordPage;
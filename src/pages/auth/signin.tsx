@@ -185,7 +185,12 @@ const SigninPage = () => {
             </div>
           </div>
 
-
+          {/* Forgot password */}
+          <p className={styles.forgotPassword}>
+            <a href="#" onClick={(e) => { e.preventDefault(); router.push('/auth/reset-password'); }}>
+              Forgot password?
+            </a>
+          </p>
 
           {/* Message */}
           {message && (
@@ -0,0 +1,80 @@
+import React, { useEffect, useState } from 'react';
+import Image from 'next/image';
+import { useRouter } from 'next/router';
+import styles from './AuthPage.module.css';
+
+// The link in the verification email lands here with ?token=, which is
+// confirmed with the backend as soon as the page loads.
+const VerifyEmailPage = () => {
+  const router = useRouter();
+  const [message, setMessage] = useState('Verifying your email address...');
+  const [messageType, setMessageType] = useState<'success' | 'error'>('success');
+
+  useEffect(() => {
+    if (!router.isReady) return;
+    const token = typeof router.query.token === 'string' ? router.query.token : '';
+    if (!token) {
+      setMessageType('error');
+      setMessage('This verification link is invalid or has expired.');
+      return;
+    }
+    fetch('/api/auth/verify-email', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ token }),
+    })
+      .then(async (response) => {
+        const data = await response.json().catch(() => ({}));
+        setMessageType(response.ok && data.success ? 'success' : 'error');
+        setMessage(data.message || 'An unexpected error occurred. Please try again.');
+        if (response.ok && data.success) {
+          const savedUser = localStorage.getItem('plutus_user');
+          if (savedUser) {
+            localStorage.setItem('plutus_user', JSON.stringify({ ...JSON.parse(savedUser), emailVerified: true }));
+          }
+        }
+      })
+      .catch(() => {
+        setMessageType('error');
+        setMessage('An unexpected error occurred. Please try again.');
+      });
+  }, [router.isReady, router.query.token]);
+
+  return (
+    <div className={styles.authPage} data-auth-page="true">
+      <div className={styles.backgroundImage}>
+        <Image
+          src="/auth-background-optimized.webp"
+          alt="Background"
+          fill
+          style={{ objectFit: 'cover', objectPosition: 'center' }}
+          priority
+          sizes="100vw"
+          quality={60}
+        />
+      </div>
+
+      <div className={styles.authContainer}>
+        <div className={styles.logoContainer}>
+          <Image src="/LOGO.svg" alt="House of Plutus" width={160} height={48} priority />
+        </div>
+
+        <div className={styles.form}>
+          <div className={`${styles.message} ${styles[messageType]}`}>
+            {message}
+          </div>
+          <button type="button" className={styles.submitButton} onClick={() => router.push('/')}>
+            Continue Shopping
+          </button>
+        </div>
+      </div>
+    </div>
+  );
+};
+
+// Custom layout to remove navbar
+VerifyEmailPage.getLayout = (page: React.ReactElement) => {
+  return <>{page}</>;
+};
+
+export default VerifyEmailPage;
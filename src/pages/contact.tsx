@@ -1,15 +1,20 @@
-import React, { useState } from 'react';
+import React, { useRef, useState } from 'react';
 import Head from 'next/head';
 import Image from 'next/image';
 import Navbar from '@/components/nav/Navbar';
 import styles from './contact.module.css';
+import { newIdempotencyKey } from '@/utils/sendEnquiry';
 
 const ContactUs = () => {
   const [formData, setFormData] = useState({
     name: '',
     email: '',
-    message: ''
+    message: '',
+    // Honeypot: hidden from people, so only bots fill it in
+    website: ''
   });
+  // Identifies this submission to the backend, so a retry isn't stored twice
+  const idempotencyKey = useRef(newIdempotencyKey());
   const [isSubmitting, setIsSubmitting] = useState(false);
   const [showSuccessPopup, setShowSuccessPopup] = useState(false);
   const [errorMessage, setErrorMessage] = useState('');
@@ -43,6 +48,7 @@ const ContactUs = () => {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
+          'Idempotency-Key': idempotencyKey.current,
         },
         body: JSON.stringify(formData),
       });
@@ -53,7 +59,8 @@ const ContactUs = () => {
         // Show success popup
         setShowSuccessPopup(true);
         // Reset form
-        setFormData({ name: '', email: '', message: '' });
+        setFormData({ name: '', email: '', message: '', website: '' });
+        idempotencyKey.current = newIdempotencyKey();
         // Hide popup after 5 seconds
         setTimeout(() => {
           setShowSuccessPopup(false);
@@ -142,6 +149,21 @@ const ContactUs = () => {
                     />
                   </div>
                   
+                  {/* Honeypot */}
+                  <div
+                    aria-hidden="true"
+                    style={{ position: 'absolute', left: '-10000px', width: 1, height: 1, overflow: 'hidden' }}
+                  >
+                    <input
+                      type="text"
+                      name="website"
+                      value={formData.website}
+                      onChange={handleInputChange}
+                      tabIndex={-1}
+                      autoComplete="off"
+                    />
+                  </div>
+
                   {/* Error Message */}
                   {errorMessage && (
                     <div className={styles.errorMessage}>
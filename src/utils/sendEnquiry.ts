@@ -8,13 +8,25 @@ export interface EnquiryPayload {
   productCategory?: string;
 }
 
-export async function sendEnquiry(payload: EnquiryPayload): Promise<{ success: boolean }> {
+// newIdempotencyKey returns a key identifying one enquiry, so the backend
+// stores it once however many times it is retried.
+export function newIdempotencyKey(): string {
+  if (typeof crypto !== 'undefined' && 'randomUUID' in crypto) {
+    return crypto.randomUUID();
+  }
+  return `${Date.now()}-${Math.random().toString(36).slice(2)}`;
+}
+
+export async function sendEnquiry(payload: EnquiryPayload, idempotencyKey?: string): Promise<{ success: boolean }> {
   try {
     console.log('Sending enquiry with payload:', payload);
     
     const res = await fetch('https://finalised-a77d.onrender.com/api/enquiry', {
       method: 'POST',
-      headers: { 'Content-Type': 'application/json' },
+      headers: {
+        'Content-Type': 'application/json',
+        ...(idempotencyKey ? { 'Idempotency-Key': idempotencyKey } : {}),
+      },
       body: JSON.stringify(payload),
     });
     